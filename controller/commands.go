@@ -0,0 +1,1000 @@
+// Package controller provides functions to edit the schedule or view (ie. command line)
+// commands.go provides non-interactive subcommands so the schedule can be scripted from a shell
+package controller
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hlin91/CS3560_Scheduler_Backup/model"
+)
+
+const (
+	// Exit codes returned by RunCommand
+	EXIT_OK      = 0 // Command succeeded
+	EXIT_FAILURE = 1 // The schedule rejected the operation (eg. conflict, bad type)
+	EXIT_USAGE   = 2 // Unknown subcommand or bad flags
+	EXIT_FILE    = 3 // The schedule file could not be read or written
+	// Name of the program used in usage and error messages
+	PROGRAM_NAME = "pss"
+	// File formats accepted by import and export
+	FORMAT_JSON = "json"
+	FORMAT_ICS  = "ics"
+	FORMAT_CSV  = "csv"
+	// Environment variable naming the json file of task types schedules accept, see model/types.go
+	TYPES_ENV = "PSS_TYPES"
+)
+
+// commandError pairs an error with the exit code it should produce
+type commandError struct {
+	code int
+	err  error
+}
+
+func (c commandError) Error() string {
+	return c.err.Error()
+}
+
+// usageError wraps an error caused by bad command line input
+func usageError(format string, a ...interface{}) error {
+	return commandError{EXIT_USAGE, fmt.Errorf(format, a...)}
+}
+
+// fileError wraps an error caused by reading or writing the schedule file
+func fileError(err error) error {
+	return commandError{EXIT_FILE, err}
+}
+
+// command is a non-interactive subcommand
+type command struct {
+	usage string
+	run   func(args []string) error
+}
+
+// commands returns the table of available subcommands
+func commands() map[string]command {
+	return map[string]command{
+		"add": {
+			usage: "add transient|anti|recurring [flags]",
+			run:   runAdd,
+		},
+		"delete": {
+			usage: "delete --name NAME|ID --file FILE",
+			run:   runDelete,
+		},
+		"override": {
+			usage: "override --name NAME|ID --on YYYY-MM-DD --file FILE [--date YYYY-MM-DD] [--start HH:MM] [--duration H:MM] [--zone ZONE] | --restore",
+			run:   runOverride,
+		},
+		"split": {
+			usage: "split --name NAME|ID --from YYYY-MM-DD --file FILE [--new-name NAME] [--date YYYY-MM-DD] [--start HH:MM] [--duration H:MM] [--zone ZONE]",
+			run:   runSplit,
+		},
+		"blackout": {
+			usage: "blackout --file FILE [--name NAME --from YYYY-MM-DD --to YYYY-MM-DD [--from-time HH:MM] [--to-time HH:MM] [--zone ZONE] [--skip] | --name NAME --delete]",
+			run:   runBlackout,
+		},
+		"types": {
+			usage: "types",
+			run:   runTypes,
+		},
+		"holidays": {
+			usage: "holidays --file FILE [--from FILE --types TYPE,... | --remove]",
+			run:   runHolidays,
+		},
+		"list": {
+			usage: "list --file FILE [--month M | --week YYYY-MM-DD | --date YYYY-MM-DD] [--zone ZONE]",
+			run:   runList,
+		},
+		"import": {
+			usage: "import --from FILE --file FILE [--format json|ics|csv] [--category CATEGORY=TYPE ...]",
+			run:   runImport,
+		},
+		"export": {
+			usage: "export --file FILE --out FILE [--format json|ics|csv] [--month M | --week YYYY-MM-DD | --date YYYY-MM-DD] [--zone ZONE]",
+			run:   runExport,
+		},
+		"serve": {
+			usage: "serve [--addr HOST:PORT] [--file FILE]",
+			run:   runServe,
+		},
+	}
+}
+
+// RunCommand runs the subcommand described by args and returns the exit code for the process
+func RunCommand(args []string) int {
+	return runCommand(args, os.Stdout, os.Stderr)
+}
+
+func runCommand(args []string, stdout, stderr io.Writer) int {
+	cmds := commands()
+	if len(args) == 0 {
+		printUsage(stderr, cmds)
+		return EXIT_USAGE
+	}
+	if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		printUsage(stdout, cmds)
+		return EXIT_OK
+	}
+	cmd, ok := cmds[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "%s: unknown command %q\n", PROGRAM_NAME, args[0])
+		printUsage(stderr, cmds)
+		return EXIT_USAGE
+	}
+	commandOutput = stdout
+	err := cmd.run(args[1:])
+	if err == nil {
+		return EXIT_OK
+	}
+	if errors.Is(err, flag.ErrHelp) {
+		return EXIT_OK
+	}
+	fmt.Fprintf(stderr, "%s: %v\n", PROGRAM_NAME, err)
+	var ce commandError
+	if errors.As(err, &ce) {
+		if ce.code == EXIT_USAGE {
+			fmt.Fprintf(stderr, "usage: %s %s\n", PROGRAM_NAME, cmd.usage)
+		}
+		return ce.code
+	}
+	return EXIT_FAILURE
+}
+
+// commandOutput is where subcommands print their results
+var commandOutput io.Writer = os.Stdout
+
+func printUsage(w io.Writer, cmds map[string]command) {
+	names := []string{}
+	for n := range cmds {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	fmt.Fprintf(w, "usage: %s <command> [flags]\n", PROGRAM_NAME)
+	fmt.Fprintf(w, "Run %s without arguments to start the interactive menu\n\nCommands:\n", PROGRAM_NAME)
+	for _, n := range names {
+		fmt.Fprintf(w, "\t%s %s\n", PROGRAM_NAME, cmds[n].usage)
+	}
+}
+
+// newFlagSet creates a flag set that reports errors instead of exiting
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(PROGRAM_NAME+" "+name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return fs
+}
+
+// parseFlags parses args into fs and rejects stray positional arguments
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			fs.SetOutput(commandOutput)
+			fs.PrintDefaults()
+			return err
+		}
+		return usageError("%v", err)
+	}
+	if fs.NArg() != 0 {
+		return usageError("unexpected argument %q", fs.Arg(0))
+	}
+	return nil
+}
+
+// openSchedule loads the schedule stored at path
+// If create is true, a missing file is treated as an empty schedule
+func openSchedule(path string, create bool) (*model.Schedule, error) {
+	if path == "" {
+		return nil, usageError("--file is required")
+	}
+	s, err := NewSchedule()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(path); err != nil {
+		if create && errors.Is(err, os.ErrNotExist) {
+			return s, nil
+		}
+		return nil, fileError(fmt.Errorf("cannot open schedule: %v", err))
+	}
+	if err := s.LoadFile(path); err != nil {
+		return nil, fileError(err)
+	}
+	return s, nil
+}
+
+// NewSchedule creates a schedule accepting the task types of the file named by the PSS_TYPES environment variable,
+// or the built in types if it is not set
+func NewSchedule() (*model.Schedule, error) {
+	s := model.NewSchedule()
+	path := os.Getenv(TYPES_ENV)
+	if path == "" {
+		return s, nil
+	}
+	types, err := model.ReadTypes(path)
+	if err != nil {
+		return nil, fileError(err)
+	}
+	if err := s.SetTypes(types); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// saveSchedule writes the schedule back to path
+func saveSchedule(s *model.Schedule, path string) error {
+	if err := s.WriteTasks(path); err != nil {
+		return fileError(err)
+	}
+	return nil
+}
+
+// runAdd implements "add transient|anti|recurring"
+func runAdd(args []string) error {
+	if len(args) == 0 {
+		return usageError("missing task kind (transient, anti or recurring)")
+	}
+	kind := args[0]
+	fs := newFlagSet("add " + kind)
+	file := fs.String("file", "", "schedule file to modify")
+	name := fs.String("name", "", "task name")
+	taskType := fs.String("type", "", "task type")
+	date := fs.String("date", "", "date of the task (eg. 2020-04-28)")
+	start := fs.String("start", "", "start time (eg. 17:30)")
+	duration := fs.String("duration", "", "duration (eg. 2:30 or 2.5)")
+	zone := fs.String("zone", "", "time zone of the date and start time (eg. America/Los_Angeles, default UTC)")
+	snap := fs.Int("snap", 15, "minutes the start time and duration are rounded to (1, 5 or 15)")
+	var endDate, weekdays, series *string
+	var frequency, monthDay, monthWeek, count *int
+	switch kind {
+	case "transient":
+	case "anti":
+		*taskType = model.CANCEL
+		endDate = fs.String("end", "", "last date of a range to cancel every occurrence in, or of the occurrences --frequency cancels (eg. 2020-04-26)")
+		series = fs.String("series", "", "name or ID of the recurring task the range cancels the occurrences of (default every recurring task)")
+		frequency = fs.Int("frequency", 0, "occurrences between cancellations, to cancel every few occurrences until --end (eg. 2 for every other)")
+	case "recurring":
+		endDate = fs.String("end", "", "end date of the recurring task (eg. 2020-05-28), if it ends on a date")
+		count = fs.Int("count", 0, "number of occurrences, if the recurring task ends after a count")
+		frequency = fs.Int("frequency", 0, "days, weeks or months between occurrences (default 1 for --weekdays and --month-day)")
+		weekdays = fs.String("weekdays", "", "days of the week the task repeats on (eg. Mon,Wed,Fri)")
+		monthDay = fs.Int("month-day", 0, "day of the month the task repeats on (1-31)")
+		monthWeek = fs.Int("month-week", 0, "week of the month the task repeats in, with one day in --weekdays (1-5, or -1 for the last)")
+	default:
+		return usageError("unknown task kind %q", kind)
+	}
+	if err := parseFlags(fs, args[1:]); err != nil {
+		return err
+	}
+	if *name == "" {
+		return usageError("--name is required")
+	}
+	dateInt, err := stringToDateInt(*date)
+	if err != nil {
+		return usageError("bad --date %q", *date)
+	}
+	if kind == "anti" && (*endDate != "" || *series != "") && *frequency == 0 {
+		// A range anti task has no start time or duration
+		a := model.AntiTask{Task: model.Task{Name: *name, Type: *taskType, Date: dateInt}, Series: *series}
+		if a.EndDate, err = stringToDateInt(*endDate); err != nil {
+			return usageError("bad --end %q", *endDate)
+		}
+		s, err := openSchedule(*file, true)
+		if err != nil {
+			return err
+		}
+		if err := s.AddAnti(a); err != nil {
+			return err
+		}
+		return saveSchedule(s, *file)
+	}
+	startTime, err := stringToTime(*start)
+	if err != nil {
+		return usageError("bad --start %q", *start)
+	}
+	durationTime, err := stringToDuration(*duration)
+	if err != nil {
+		return usageError("bad --duration %q", *duration)
+	}
+	if _, err := time.LoadLocation(*zone); err != nil {
+		return usageError("bad --zone %q", *zone)
+	}
+	s, err := openSchedule(*file, true)
+	if err != nil {
+		return err
+	}
+	if err := s.SetSnap(time.Duration(*snap) * time.Minute); err != nil {
+		return usageError("bad --snap %d", *snap)
+	}
+	task := model.Task{Name: *name, Type: *taskType, Date: dateInt, StartTime: startTime, Duration: durationTime, TimeZone: *zone}
+	switch kind {
+	case "transient":
+		err = s.AddTask(task)
+	case "anti":
+		if *frequency == 0 {
+			err = s.AddTask(task)
+			break
+		}
+		// A recurring anti task starts on the occurrence it lines up with
+		a := model.AntiTask{Task: task, Frequency: *frequency}
+		if a.EndDate, err = stringToDateInt(*endDate); err != nil {
+			return usageError("bad --end %q", *endDate)
+		}
+		err = s.AddAnti(a)
+	case "recurring":
+		// Without --end or --count the task repeats forever
+		endInt := 0
+		if *endDate != "" {
+			var e error
+			if endInt, e = stringToDateInt(*endDate); e != nil {
+				return usageError("bad --end %q", *endDate)
+			}
+		}
+		days, e := model.ParseWeekdays(*weekdays)
+		if *weekdays == "" {
+			days, e = 0, nil
+		}
+		if e != nil {
+			return usageError("bad --weekdays %q", *weekdays)
+		}
+		r := model.RecurringTask{Task: task, EndDate: endInt, Count: *count, Frequency: *frequency, Weekdays: days, MonthDay: *monthDay, MonthWeek: *monthWeek}
+		if r.Frequency == 0 && (days != 0 || r.MonthDay != 0) {
+			r.Frequency = 1
+		}
+		err = s.AddRecurring(r)
+	}
+	if err != nil {
+		return err
+	}
+	return saveSchedule(s, *file)
+}
+
+// runDelete implements "delete"
+func runDelete(args []string) error {
+	fs := newFlagSet("delete")
+	file := fs.String("file", "", "schedule file to modify")
+	name := fs.String("name", "", "name or ID of the task to delete")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *name == "" {
+		return usageError("--name is required")
+	}
+	s, err := openSchedule(*file, false)
+	if err != nil {
+		return err
+	}
+	if err := s.DeleteTask(*name); err != nil {
+		return err
+	}
+	return saveSchedule(s, *file)
+}
+
+// runOverride implements "override"
+// Details that are not given keep the values of the occurrence being moved
+func runOverride(args []string) error {
+	fs := newFlagSet("override")
+	file := fs.String("file", "", "schedule file to modify")
+	name := fs.String("name", "", "name or ID of the recurring task")
+	on := fs.String("on", "", "date of the occurrence to move or change (eg. 2020-04-30)")
+	date := fs.String("date", "", "new date of the occurrence")
+	start := fs.String("start", "", "new start time of the occurrence (eg. 18:00)")
+	duration := fs.String("duration", "", "new duration of the occurrence (eg. 2:30 or 2.5)")
+	zone := fs.String("zone", "", "time zone of the new date and start time (default the zone of the recurring task)")
+	restore := fs.Bool("restore", false, "put the occurrence back in its place instead")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *name == "" {
+		return usageError("--name is required")
+	}
+	original, err := stringToDateInt(*on)
+	if err != nil {
+		return usageError("bad --on %q", *on)
+	}
+	s, err := openSchedule(*file, false)
+	if err != nil {
+		return err
+	}
+	if *restore {
+		if err := s.DeleteOverride(*name, original); err != nil {
+			return err
+		}
+		return saveSchedule(s, *file)
+	}
+	r, err := recurringTask(s, *name)
+	if err != nil {
+		return err
+	}
+	o := model.Override{Task: r.Task, Original: original}
+	o.Date = original
+	if prev, ok := s.Override(*name, original); ok {
+		o = prev
+	}
+	if *date != "" {
+		if o.Date, err = stringToDateInt(*date); err != nil {
+			return usageError("bad --date %q", *date)
+		}
+	}
+	if *start != "" {
+		if o.StartTime, err = stringToTime(*start); err != nil {
+			return usageError("bad --start %q", *start)
+		}
+	}
+	if *duration != "" {
+		if o.Duration, err = stringToDuration(*duration); err != nil {
+			return usageError("bad --duration %q", *duration)
+		}
+	}
+	if *zone != "" {
+		if _, err := time.LoadLocation(*zone); err != nil {
+			return usageError("bad --zone %q", *zone)
+		}
+		o.TimeZone = *zone
+	}
+	if err := s.AddOverride(o); err != nil {
+		return err
+	}
+	return saveSchedule(s, *file)
+}
+
+// runSplit implements "split"
+// The new task keeps the type, rule and end of the old one, starting on its first occurrence from --from unless
+// --date is given, and a task ending after a count keeps the occurrences it has left
+func runSplit(args []string) error {
+	fs := newFlagSet("split")
+	file := fs.String("file", "", "schedule file to modify")
+	name := fs.String("name", "", "name or ID of the recurring task")
+	from := fs.String("from", "", "date the change takes effect (eg. 2020-04-20)")
+	newName := fs.String("new-name", "", "name of the recurring task from --from onward (default the name of the old task)")
+	date := fs.String("date", "", "start date of the new task (default the first occurrence from --from)")
+	start := fs.String("start", "", "new start time (eg. 18:00)")
+	duration := fs.String("duration", "", "new duration (eg. 2:30 or 2.5)")
+	zone := fs.String("zone", "", "new time zone of the start time")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *name == "" {
+		return usageError("--name is required")
+	}
+	fromInt, err := stringToDateInt(*from)
+	if err != nil {
+		return usageError("bad --from %q", *from)
+	}
+	s, err := openSchedule(*file, false)
+	if err != nil {
+		return err
+	}
+	r, err := recurringTask(s, *name)
+	if err != nil {
+		return err
+	}
+	// The new task is given an ID of its own when it is added
+	newTask := r
+	newTask.ID = ""
+	if *newName != "" {
+		newTask.Name = *newName
+	}
+	fromTime := time.Date(fromInt/10000, time.Month(fromInt/100%100), fromInt%100, 0, 0, 0, 0, r.Location())
+	if *date == "" {
+		// The search runs for a year for each unit of the frequency and a week more, longer than the gap between
+		// two occurrences of any rule, so finding nothing means the task ends before --from
+		later, _ := r.GetSubtasksInRange(fromTime, fromTime.AddDate(r.Frequency, 0, 7))
+		newTask.Date = 0
+		for _, t := range later {
+			if t.Date >= fromInt {
+				newTask.Date = t.Date
+				break
+			}
+		}
+		if newTask.Date == 0 {
+			return fmt.Errorf("no occurrence of %q on or after %s", r.Name, *from)
+		}
+	}
+	if r.Count > 0 {
+		first, _ := r.GetStartDate()
+		before, _ := r.GetSubtasksInRange(first, fromTime)
+		for _, t := range before {
+			if t.Date < fromInt {
+				newTask.Count--
+			}
+		}
+	}
+	if *date != "" {
+		if newTask.Date, err = stringToDateInt(*date); err != nil {
+			return usageError("bad --date %q", *date)
+		}
+	}
+	if *start != "" {
+		if newTask.StartTime, err = stringToTime(*start); err != nil {
+			return usageError("bad --start %q", *start)
+		}
+	}
+	if *duration != "" {
+		if newTask.Duration, err = stringToDuration(*duration); err != nil {
+			return usageError("bad --duration %q", *duration)
+		}
+	}
+	if *zone != "" {
+		if _, err := time.LoadLocation(*zone); err != nil {
+			return usageError("bad --zone %q", *zone)
+		}
+		newTask.TimeZone = *zone
+	}
+	if err := s.SplitRecurring(*name, fromInt, newTask); err != nil {
+		return err
+	}
+	return saveSchedule(s, *file)
+}
+
+// recurringTask gets the recurring task a --name flag gives by name or ID
+func recurringTask(s *model.Schedule, ref string) (model.RecurringTask, error) {
+	id, err := s.TaskID(ref)
+	if err != nil {
+		return model.RecurringTask{}, err
+	}
+	r, ok := s.RecurringTask(id)
+	if !ok {
+		return r, fmt.Errorf("%q is not a recurring task", ref)
+	}
+	return r, nil
+}
+
+// runBlackout implements "blackout"
+// A blackout period covers whole days from --from to --to unless times are given, and is listed without --name
+func runBlackout(args []string) error {
+	fs := newFlagSet("blackout")
+	file := fs.String("file", "", "schedule file to modify")
+	name := fs.String("name", "", "name of the blackout period")
+	from := fs.String("from", "", "first date of the period (eg. 2020-11-25)")
+	fromTime := fs.String("from-time", "", "time the period begins on --from (default the start of the day)")
+	to := fs.String("to", "", "last date of the period (eg. 2020-11-29)")
+	toTime := fs.String("to-time", "", "time the period ends on --to (default the end of the day)")
+	zone := fs.String("zone", "", "time zone of the dates and times (eg. America/Los_Angeles, default UTC)")
+	skip := fs.Bool("skip", false, "skip the occurrences of recurring tasks in the period instead of rejecting them")
+	remove := fs.Bool("delete", false, "delete the blackout period named --name instead")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *name == "" {
+		s, err := openSchedule(*file, false)
+		if err != nil {
+			return err
+		}
+		blackouts := s.Blackouts()
+		if len(blackouts) == 0 {
+			fmt.Fprintln(commandOutput, "No blackout periods found")
+			return nil
+		}
+		fmt.Fprintln(commandOutput, SEP_STRING)
+		for _, b := range blackouts {
+			fmt.Fprintln(commandOutput, b)
+			fmt.Fprintln(commandOutput, SEP_STRING)
+		}
+		return nil
+	}
+	if *remove {
+		s, err := openSchedule(*file, false)
+		if err != nil {
+			return err
+		}
+		if err := s.DeleteBlackout(*name); err != nil {
+			return err
+		}
+		return saveSchedule(s, *file)
+	}
+	b := model.Blackout{Name: *name, TimeZone: *zone, Policy: model.BLACKOUT_REJECT}
+	if *skip {
+		b.Policy = model.BLACKOUT_SKIP
+	}
+	var err error
+	if b.Date, err = stringToDateInt(*from); err != nil {
+		return usageError("bad --from %q", *from)
+	}
+	if b.EndDate, err = stringToDateInt(*to); err != nil {
+		return usageError("bad --to %q", *to)
+	}
+	if *fromTime != "" {
+		if b.StartTime, err = stringToTime(*fromTime); err != nil {
+			return usageError("bad --from-time %q", *fromTime)
+		}
+	}
+	if *toTime != "" {
+		if b.EndTime, err = stringToTime(*toTime); err != nil {
+			return usageError("bad --to-time %q", *toTime)
+		}
+	} else {
+		b.EndDate = dayAfter(b.EndDate)
+	}
+	if _, err := time.LoadLocation(*zone); err != nil {
+		return usageError("bad --zone %q", *zone)
+	}
+	s, err := openSchedule(*file, true)
+	if err != nil {
+		return err
+	}
+	if err := s.AddBlackout(b); err != nil {
+		return err
+	}
+	return saveSchedule(s, *file)
+}
+
+// runTypes implements "types", listing the task types schedules accept by category
+func runTypes(args []string) error {
+	fs := newFlagSet("types")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	s, err := NewSchedule()
+	if err != nil {
+		return err
+	}
+	for _, category := range []string{model.TRANSIENT, model.RECURRING, model.ANTI} {
+		fmt.Fprintf(commandOutput, "%s types:\n", category)
+		for _, t := range s.Types().Types(category) {
+			fmt.Fprintf(commandOutput, "\t%s\n", typeString(t))
+		}
+	}
+	return nil
+}
+
+// runHolidays implements "holidays"
+// The cancellations generated for holidays are replaced with ones for the calendar in --from, deleted with
+// --remove, or listed without either
+func runHolidays(args []string) error {
+	fs := newFlagSet("holidays")
+	file := fs.String("file", "", "schedule file to modify")
+	from := fs.String("from", "", "holiday calendar to cancel occurrences on (json, or ics for a .ics file)")
+	types := fs.String("types", "", "types of recurring tasks to cancel on holidays (eg. Class,Work)")
+	remove := fs.Bool("remove", false, "delete every cancellation generated for holidays instead")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *from != "" && *remove {
+		return usageError("--from and --remove cannot be used together")
+	}
+	if *from != "" && *types == "" {
+		return usageError("--types is required with --from")
+	}
+	s, err := openSchedule(*file, false)
+	if err != nil {
+		return err
+	}
+	switch {
+	case *remove:
+		err = s.RemoveHolidayCancellations()
+	case *from != "":
+		holidays, e := model.ReadHolidays(*from)
+		if e != nil {
+			return e
+		}
+		typeList := []string{}
+		for _, t := range strings.Split(*types, ",") {
+			typeList = append(typeList, strings.TrimSpace(t))
+		}
+		err = s.CancelHolidays(holidays, typeList)
+	default:
+		cancellations := s.HolidayCancellations()
+		if len(cancellations) == 0 {
+			fmt.Fprintln(commandOutput, "No holiday cancellations found")
+			return nil
+		}
+		fmt.Fprintln(commandOutput, SEP_STRING)
+		for _, a := range cancellations {
+			fmt.Fprintln(commandOutput, a)
+			fmt.Fprintln(commandOutput, SEP_STRING)
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return saveSchedule(s, *file)
+}
+
+// rangeFlags holds the flags that select a month, week or day of tasks
+type rangeFlags struct {
+	month     *string
+	week      *string
+	date      *string
+	weekStart *string
+	zone      *string
+}
+
+func addRangeFlags(fs *flag.FlagSet) rangeFlags {
+	return rangeFlags{
+		month:     fs.String("month", "", "only tasks in this month (eg. 2020-04), or in this month of every year (1-12)"),
+		week:      fs.String("week", "", "only tasks in the week of this date (eg. 2020-04-28)"),
+		date:      fs.String("date", "", "only tasks on this date (eg. 2020-04-28)"),
+		weekStart: fs.String("week-start", "monday", "first day of the week for --week (sunday or monday)"),
+		zone:      fs.String("zone", "UTC", "time zone of --month, --week and --date and of the tasks shown for them"),
+	}
+}
+
+// selected reports whether any range flag was given
+func (r rangeFlags) selected() bool {
+	return *r.month != "" || *r.week != "" || *r.date != ""
+}
+
+// tasks fetches the tasks selected by the range flags
+func (r rangeFlags) tasks(s *model.Schedule) ([]model.Task, error) {
+	count := 0
+	for _, set := range []bool{*r.month != "", *r.week != "", *r.date != ""} {
+		if set {
+			count++
+		}
+	}
+	if count > 1 {
+		return nil, usageError("only one of --month, --week and --date may be given")
+	}
+	loc, err := time.LoadLocation(*r.zone)
+	if err != nil {
+		return nil, usageError("bad --zone %q", *r.zone)
+	}
+	var tasks []model.Task
+	switch {
+	case *r.month != "":
+		if month, err := strconv.Atoi(*r.month); err == nil {
+			// A month of every year
+			tasks, err = s.GetTasksByMonth(month)
+			if err != nil {
+				return nil, err
+			}
+			sort.Slice(tasks, func(i, j int) bool {
+				return tasks[i].Before(tasks[j])
+			})
+			break
+		}
+		start, e := time.ParseInLocation(MONTH_LAYOUT, *r.month, loc)
+		if e != nil {
+			return nil, usageError("bad --month %q", *r.month)
+		}
+		tasks, err = s.GetTasksInRange(start, start.AddDate(0, 1, 0))
+	case *r.week != "":
+		date, e := time.ParseInLocation(DATE_LAYOUT, *r.week, loc)
+		if e != nil {
+			return nil, usageError("bad --week %q", *r.week)
+		}
+		day, e := parseWeekday(*r.weekStart)
+		if e != nil {
+			return nil, usageError("bad --week-start: %v", e)
+		}
+		s.SetWeekStart(day)
+		tasks, err = s.GetTasksInWeek(date)
+	default:
+		date, e := time.ParseInLocation(DATE_LAYOUT, *r.date, loc)
+		if e != nil {
+			return nil, usageError("bad --date %q", *r.date)
+		}
+		tasks, err = s.GetTasksInRange(date, date.AddDate(0, 0, 1))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return model.TasksIn(tasks, loc), nil
+}
+
+// runList implements "list"
+func runList(args []string) error {
+	fs := newFlagSet("list")
+	file := fs.String("file", "", "schedule file to read")
+	rf := addRangeFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	s, err := openSchedule(*file, false)
+	if err != nil {
+		return err
+	}
+	var entries []fmt.Stringer
+	if rf.selected() {
+		tasks, err := rf.tasks(s)
+		if err != nil {
+			return err
+		}
+		for _, t := range tasks {
+			entries = append(entries, t)
+		}
+	} else {
+		entries = allTasks(s)
+	}
+	if len(entries) == 0 {
+		fmt.Fprintln(commandOutput, "No tasks found")
+		return nil
+	}
+	fmt.Fprintln(commandOutput, SEP_STRING)
+	for _, e := range entries {
+		fmt.Fprintln(commandOutput, e)
+		fmt.Fprintln(commandOutput, SEP_STRING)
+	}
+	return nil
+}
+
+// allTasks returns every task in the schedule sorted by name, and tasks sharing a name by ID
+func allTasks(s *model.Schedule) []fmt.Stringer {
+	snap := s.Snapshot()
+	names := []string{}
+	byName := map[string]fmt.Stringer{}
+	key := func(t model.Task) string {
+		return t.Name + "\x00" + t.ID
+	}
+	for _, t := range snap.TransientTasks() {
+		names = append(names, key(t))
+		byName[key(t)] = t
+	}
+	for _, t := range snap.AntiTasks() {
+		names = append(names, key(t.Task))
+		byName[key(t.Task)] = t
+	}
+	for _, t := range snap.RecurringTasks() {
+		names = append(names, key(t.Task))
+		byName[key(t.Task)] = t
+	}
+	// Overrides follow the recurring task they belong to
+	for _, o := range snap.Overrides() {
+		key := fmt.Sprintf("%s (%d)", key(o.Task), o.Original)
+		names = append(names, key)
+		byName[key] = o
+	}
+	sort.Strings(names)
+	result := []fmt.Stringer{}
+	for _, n := range names {
+		result = append(result, byName[n])
+	}
+	return result
+}
+
+// runImport implements "import"
+func runImport(args []string) error {
+	fs := newFlagSet("import")
+	file := fs.String("file", "", "schedule file to modify")
+	from := fs.String("from", "", "json task list, iCalendar or csv file to import")
+	format := fs.String("format", "", "json, ics or csv (default: from the --from extension)")
+	categories := categoryFlag{}
+	fs.Var(categories, "category", "map an iCalendar category onto a task type (eg. Lecture=Class), may be repeated")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *from == "" {
+		return usageError("--from is required")
+	}
+	inFormat, err := fileFormat(*from, *format)
+	if err != nil {
+		return err
+	}
+	s, err := openSchedule(*file, true)
+	if err != nil {
+		return err
+	}
+	switch inFormat {
+	case FORMAT_ICS:
+		err = s.LoadICal(*from, categories)
+	case FORMAT_CSV:
+		err = s.LoadCSV(*from)
+	default:
+		err = s.LoadFile(*from)
+	}
+	if err != nil {
+		return err
+	}
+	return saveSchedule(s, *file)
+}
+
+// categoryFlag collects repeated CATEGORY=TYPE flags
+type categoryFlag map[string]string
+
+func (c categoryFlag) String() string {
+	pairs := []string{}
+	for category, taskType := range c {
+		pairs = append(pairs, category+"="+taskType)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (c categoryFlag) Set(v string) error {
+	mappings, err := parseCategories(v)
+	if err != nil {
+		return err
+	}
+	for category, taskType := range mappings {
+		c[category] = taskType
+	}
+	return nil
+}
+
+// runExport implements "export"
+func runExport(args []string) error {
+	fs := newFlagSet("export")
+	file := fs.String("file", "", "schedule file to read")
+	out := fs.String("out", "", "file to write the tasks to")
+	format := fs.String("format", "", "json, ics or csv (default: from the --out extension)")
+	rf := addRangeFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *out == "" {
+		return usageError("--out is required")
+	}
+	outFormat, err := fileFormat(*out, *format)
+	if err != nil {
+		return err
+	}
+	s, err := openSchedule(*file, false)
+	if err != nil {
+		return err
+	}
+	switch {
+	case outFormat == FORMAT_ICS:
+		if rf.selected() {
+			return usageError("--month, --week and --date are not supported for ics")
+		}
+		err = s.WriteICal(*out)
+	case rf.selected():
+		tasks, e := rf.tasks(s)
+		if e != nil {
+			return e
+		}
+		if outFormat == FORMAT_CSV {
+			err = s.WriteTaskListCSV(*out, tasks)
+		} else {
+			err = s.WriteTaskList(*out, tasks)
+		}
+	case outFormat == FORMAT_CSV:
+		err = s.WriteCSV(*out)
+	default:
+		err = s.WriteTasks(*out)
+	}
+	if err != nil {
+		return fileError(err)
+	}
+	return nil
+}
+
+// fileFormat picks the format of a file from the --format flag or, if that is empty, the file extension
+func fileFormat(path, flagValue string) (string, error) {
+	format := strings.ToLower(flagValue)
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	}
+	switch format {
+	case FORMAT_JSON, FORMAT_ICS, FORMAT_CSV:
+		return format, nil
+	case "ical", "ifb", "icalendar":
+		return FORMAT_ICS, nil
+	case "":
+		return FORMAT_JSON, nil
+	}
+	return "", usageError("unsupported format %q", format)
+}
+
+// runServe implements "serve"
+func runServe(args []string) error {
+	fs := newFlagSet("serve")
+	addr := fs.String("addr", DEFAULT_ADDR, "address to listen on")
+	file := fs.String("file", "", "schedule file to load and save changes to (optional)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	s, err := NewSchedule()
+	if err != nil {
+		return err
+	}
+	if *file != "" {
+		if s, err = openSchedule(*file, true); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(commandOutput, "Serving schedule on http://%s\n", *addr)
+	return http.ListenAndServe(*addr, NewServer(s, *file))
+}
+
+//!--
@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/hlin91/CS3560_Scheduler_Backup/model"
 )
@@ -24,27 +26,59 @@ func MakeMenu(s *model.Schedule) Menu {
 		 * View by month
 		 * View by week
 		 * View by day
+		 * Set first day of the week
+		 * Set time zone
+		 * Set time rounding
+		 * Load task types from file
 		 * Delete a task
+		 * Move or change one occurrence of a recurring task
+		 * Restore a moved occurrence
+		 * Cancel occurrences on holidays
+		 * Add a blackout period
+		 * Delete a blackout period
+		 * Remove holiday cancellations
+		 * Undo the last change
+		 * Redo the last undone change
 		 * Read schedule from file
+		 * Read schedule from iCalendar file
+		 * Read schedule from CSV file
 		 * Write schedule to file
 		 * Write by day
 		 * Write by week
 		 * Write by month
+		 * Write to iCalendar file
+		 * Write to CSV file
 	     **********************************************/
 	options := []ScheduleMenuItem{}
 	// Add create task option
 	options = append(options, NewScheduleMenuItem("Create a task", s, createTask))
 	options = append(options, NewScheduleMenuItem("Delete a task", s, deleteTask))
 	options = append(options, NewScheduleMenuItem("Edit a task", s, editTask))
+	options = append(options, NewScheduleMenuItem("Move one occurrence", s, overrideOccurrence))
+	options = append(options, NewScheduleMenuItem("Restore a moved occurrence", s, restoreOccurrence))
+	options = append(options, NewScheduleMenuItem("Cancel occurrences on holidays", s, cancelHolidays))
+	options = append(options, NewScheduleMenuItem("Remove holiday cancellations", s, removeHolidays))
+	options = append(options, NewScheduleMenuItem("Add a blackout period", s, addBlackout))
+	options = append(options, NewScheduleMenuItem("Delete a blackout period", s, deleteBlackout))
+	options = append(options, NewScheduleMenuItem("Undo", s, undo))
+	options = append(options, NewScheduleMenuItem("Redo", s, redo))
 	options = append(options, NewScheduleMenuItem("View a task", s, viewTask))
 	options = append(options, NewScheduleMenuItem("View by month", s, viewTaskByMonth))
 	options = append(options, NewScheduleMenuItem("View by week", s, viewTaskByWeek))
 	options = append(options, NewScheduleMenuItem("View by day", s, viewTaskByDay))
+	options = append(options, NewScheduleMenuItem("Set first day of the week", s, setWeekStart))
+	options = append(options, NewScheduleMenuItem("Set time zone", s, setTimeZone))
+	options = append(options, NewScheduleMenuItem("Set time rounding", s, setSnap))
+	options = append(options, NewScheduleMenuItem("Load task types from file", s, loadTypes))
 	options = append(options, NewScheduleMenuItem("Load from file", s, loadFile))
+	options = append(options, NewScheduleMenuItem("Load from iCalendar file", s, loadICal))
+	options = append(options, NewScheduleMenuItem("Load from CSV file", s, loadCSV))
 	options = append(options, NewScheduleMenuItem("Write tasks to file", s, writeTasks))
 	options = append(options, NewScheduleMenuItem("Write tasks by month", s, writeTasksByMonth))
 	options = append(options, NewScheduleMenuItem("Write tasks by week", s, writeTasksByWeek))
 	options = append(options, NewScheduleMenuItem("Write tasks by day", s, writeTasksByDay))
+	options = append(options, NewScheduleMenuItem("Write tasks to iCalendar file", s, writeICal))
+	options = append(options, NewScheduleMenuItem("Write tasks to CSV file", s, writeCSV))
 	m := []Menuer{}
 	for _, o := range options {
 		temp := o
@@ -63,30 +97,72 @@ func createTask(s *model.Schedule) error {
 	fmt.Println("1. Transient task")
 	fmt.Println("2. Anti task")
 	fmt.Println("3. Recurring task")
+	fmt.Println("4. Cancel a range of dates")
+	fmt.Println("5. Cancel every few occurrences")
 	fmt.Print("Enter an option: ")
 	for !valid {
 		switch input.Scan(); input.Text() {
 		case "1":
 			valid = true
-			name, taskType, date, startTime, duration, err := requestTaskInfo()
+			name, taskType, date, startTime, duration, err := requestTaskInfo(s)
 			if err != nil {
 				return err
 			}
-			return s.AddTransientTask(name, taskType, date, startTime, duration)
+			zone, err := requestTimeZone()
+			if err != nil {
+				return err
+			}
+			return s.AddTask(model.Task{Name: name, Type: taskType, Date: date, StartTime: startTime, Duration: duration, TimeZone: zone})
 		case "2":
 			valid = true
 			name, date, startTime, duration, err := requestAntiInfo()
 			if err != nil {
 				return err
 			}
-			return s.AddAntiTask(name, model.CANCEL, date, float32(startTime), float32(duration))
+			zone, err := requestTimeZone()
+			if err != nil {
+				return err
+			}
+			return s.AddTask(model.Task{Name: name, Type: model.CANCEL, Date: date, StartTime: startTime, Duration: duration, TimeZone: zone})
 		case "3":
 			valid = true
-			name, taskType, date, startTime, duration, endDate, frequency, err := requestRecurringInfo()
+			name, taskType, date, startTime, duration, endDate, count, err := requestRecurringInfo(s)
 			if err != nil {
 				return err
 			}
-			return s.AddRecurringTask(name, taskType, date, startTime, duration, endDate, frequency)
+			r := model.RecurringTask{
+				Task:    model.Task{Name: name, Type: taskType, Date: date, StartTime: startTime, Duration: duration},
+				EndDate: endDate,
+				Count:   count,
+			}
+			if err := requestRule(&r); err != nil {
+				return err
+			}
+			if r.TimeZone, err = requestTimeZone(); err != nil {
+				return err
+			}
+			return s.AddRecurring(r)
+		case "4":
+			valid = true
+			a, err := requestAntiRange()
+			if err != nil {
+				return err
+			}
+			return s.AddAnti(a)
+		case "5":
+			valid = true
+			name, date, startTime, duration, err := requestAntiInfo()
+			if err != nil {
+				return err
+			}
+			a := model.AntiTask{Task: model.Task{Name: name, Type: model.CANCEL, Date: date, StartTime: startTime, Duration: duration}}
+			if err := requestAntiRecurrence(&a); err != nil {
+				return err
+			}
+			if a.TimeZone, err = requestTimeZone(); err != nil {
+				return err
+			}
+			return s.AddAnti(a)
 		default:
 			fmt.Print("Invalid option. Try again: ")
 		}
@@ -94,161 +170,331 @@ func createTask(s *model.Schedule) error {
 	return nil
 }
 
-// viewTask allows the user to view the details of a task by name
+// viewTask allows the user to view the details of a task by name or ID
 func viewTask(s *model.Schedule) error {
 	input := bufio.NewScanner(os.Stdin)
-	fmt.Print("Enter a task name: ")
+	fmt.Print("Enter a task name or ID: ")
 	input.Scan()
-	if t, ok := s.TransientTasks[input.Text()]; ok {
-		fmt.Println(SEP_STRING)
-		fmt.Println(t)
-		fmt.Println(SEP_STRING)
-		return nil
+	id, err := s.TaskID(strings.TrimSpace(input.Text()))
+	if err != nil {
+		return err
 	}
-	if t, ok := s.RecurringTasks[input.Text()]; ok {
+	if t, ok := s.TransientTask(id); ok {
 		fmt.Println(SEP_STRING)
 		fmt.Println(t)
 		fmt.Println(SEP_STRING)
 		return nil
 	}
-	if t, ok := s.AntiTasks[input.Text()]; ok {
+	if t, ok := s.RecurringTask(id); ok {
 		fmt.Println(SEP_STRING)
 		fmt.Println(t)
 		fmt.Println(SEP_STRING)
+		for _, o := range s.Overrides() {
+			if o.ID == t.ID {
+				fmt.Println(o)
+				fmt.Println(SEP_STRING)
+			}
+		}
 		return nil
 	}
-	return fmt.Errorf("task name does not exist in schedule")
+	t, _ := s.AntiTask(id)
+	fmt.Println(SEP_STRING)
+	fmt.Println(t)
+	fmt.Println(SEP_STRING)
+	return nil
 }
 
 // viewTaskByMonth allows the user to view all tasks for a specified month
 func viewTaskByMonth(s *model.Schedule) error {
 	input := bufio.NewScanner(os.Stdin)
-	fmt.Print("Enter a month (1-12): ")
-	input.Scan()
-	month, err := strconv.Atoi(input.Text())
+	start, end, err := requestMonth(input, s.Location())
 	if err != nil {
-		return fmt.Errorf("bad month entered")
+		return err
 	}
-	tasks, err := s.GetTasksByMonth(month)
+	tasks, err := s.GetTasksInRange(start, end)
 	if err != nil {
 		return err
 	}
-	if len(tasks) == 0 {
-		fmt.Println("No tasks found")
-		return nil
-	}
-	fmt.Println(SEP_STRING)
-	for _, t := range tasks {
-		fmt.Println(t)
-		fmt.Println(SEP_STRING)
-	}
+	displayTasks(model.TasksIn(tasks, s.Location()))
 	return nil
 }
 
 // viewTaskByWeek allows the user to view all tasks for the week of a specified day
 func viewTaskByWeek(s *model.Schedule) error {
 	input := bufio.NewScanner(os.Stdin)
-	fmt.Print("Enter a month (1-12): ")
-	input.Scan()
-	month, err := strconv.Atoi(input.Text())
+	date, err := requestDate(input, s.Location())
 	if err != nil {
-		return fmt.Errorf("bad month entered")
+		return err
 	}
-	fmt.Print("Enter a day (1-31): ")
-	input.Scan()
-	day, err := strconv.Atoi(input.Text())
+	tasks, err := s.GetTasksInWeek(date)
 	if err != nil {
-		return fmt.Errorf("bad day entered")
+		return err
 	}
-	tasks, err := s.GetTasksByWeek(month, day)
+	displayTasks(model.TasksIn(tasks, s.Location()))
+	return nil
+}
+
+// viewTaskByDay allows the user to view all tasks for a specified day
+func viewTaskByDay(s *model.Schedule) error {
+	input := bufio.NewScanner(os.Stdin)
+	date, err := requestDate(input, s.Location())
 	if err != nil {
 		return err
 	}
-	if len(tasks) == 0 {
-		fmt.Println("No tasks found")
-		return nil
-	}
-	fmt.Println(SEP_STRING)
-	for _, t := range tasks {
-		fmt.Println(t)
-		fmt.Println(SEP_STRING)
+	tasks, err := s.GetTasksInRange(date, date.AddDate(0, 0, 1))
+	if err != nil {
+		return err
 	}
+	displayTasks(model.TasksIn(tasks, s.Location()))
 	return nil
 }
 
-// viewTaskByDay allows the user to view all tasks for a specified day
-func viewTaskByDay(s *model.Schedule) error {
+// setWeekStart allows the user to choose whether weeks begin on Sunday or Monday
+func setWeekStart(s *model.Schedule) error {
 	input := bufio.NewScanner(os.Stdin)
-	fmt.Print("Enter a month (1-12): ")
+	fmt.Printf("Weeks currently begin on %v\n", s.WeekStart())
+	fmt.Print("Enter the first day of the week (Sunday or Monday): ")
 	input.Scan()
-	month, err := strconv.Atoi(input.Text())
+	day, err := parseWeekday(input.Text())
 	if err != nil {
-		return fmt.Errorf("bad month entered")
+		return err
 	}
-	fmt.Print("Enter a day (1-31): ")
+	return s.SetWeekStart(day)
+}
+
+// setTimeZone allows the user to choose the time zone of new tasks and of the views
+func setTimeZone(s *model.Schedule) error {
+	input := bufio.NewScanner(os.Stdin)
+	fmt.Printf("The time zone is currently %v\n", s.Location())
+	fmt.Print("Enter a time zone (eg. America/Los_Angeles or UTC): ")
 	input.Scan()
-	day, err := strconv.Atoi(input.Text())
+	loc, err := time.LoadLocation(strings.TrimSpace(input.Text()))
 	if err != nil {
-		return fmt.Errorf("bad day entered")
+		return fmt.Errorf("unknown time zone %q", strings.TrimSpace(input.Text()))
 	}
-	tasks, err := s.GetTasksByDay(month, day)
+	return s.SetLocation(loc)
+}
+
+// setSnap allows the user to choose the minutes that start times and durations are rounded to
+func setSnap(s *model.Schedule) error {
+	input := bufio.NewScanner(os.Stdin)
+	fmt.Printf("Times are currently rounded to %v\n", s.Snap())
+	fmt.Print("Enter the minutes to round times to (1, 5 or 15): ")
+	input.Scan()
+	minutes, err := strconv.Atoi(strings.TrimSpace(input.Text()))
 	if err != nil {
-		return err
+		return fmt.Errorf("bad number of minutes entered")
 	}
-	if len(tasks) == 0 {
-		fmt.Println("No tasks found")
-		return nil
+	return s.SetSnap(time.Duration(minutes) * time.Minute)
+}
+
+// loadTypes allows the user to replace the task types the schedule accepts with those of a json file
+func loadTypes(s *model.Schedule) error {
+	input := bufio.NewScanner(os.Stdin)
+	fmt.Print("Enter the path of the task types file: ")
+	input.Scan()
+	types, err := model.ReadTypes(strings.TrimSpace(input.Text()))
+	if err != nil {
+		return err
 	}
-	fmt.Println(SEP_STRING)
-	for _, t := range tasks {
-		fmt.Println(t)
-		fmt.Println(SEP_STRING)
+	if err := s.SetTypes(types); err != nil {
+		return err
 	}
+	fmt.Printf("%d task types loaded\n", len(types.Types("")))
 	return nil
 }
 
-// deleteTask allows the user to delete a task by name
+// deleteTask allows the user to delete a task by name or ID
 func deleteTask(s *model.Schedule) error {
 	input := bufio.NewScanner(os.Stdin)
-	fmt.Print("Enter task name: ")
+	fmt.Print("Enter task name or ID: ")
 	input.Scan()
-	return s.DeleteTask(input.Text())
+	return s.DeleteTask(strings.TrimSpace(input.Text()))
 }
 
-// editTask allows the user to edit the details of a task by name
+// editTask allows the user to edit the details of a task by name or ID
 func editTask(s *model.Schedule) error {
 	input := bufio.NewScanner(os.Stdin)
-	fmt.Print("Enter the name of the task to edit: ")
+	fmt.Print("Enter the name or ID of the task to edit: ")
 	input.Scan()
-	taskName := input.Text()
-	if _, ok := s.TransientTasks[taskName]; ok {
+	id, err := s.TaskID(strings.TrimSpace(input.Text()))
+	if err != nil {
+		return err
+	}
+	if _, ok := s.TransientTask(id); ok {
 		// Edit a transient task
-		newName, newType, newDate, newStartTime, newDuration, err := requestTaskInfo()
+		newName, newType, newDate, newStartTime, newDuration, err := requestTaskInfo(s)
+		if err != nil {
+			return err
+		}
+		newZone, err := requestTimeZone()
 		if err != nil {
 			return err
 		}
-		err = s.EditTransientTask(taskName, newName, newType, newDate, newStartTime, newDuration)
+		err = s.EditTask(id, model.Task{Name: newName, Type: newType, Date: newDate, StartTime: newStartTime, Duration: newDuration, TimeZone: newZone})
 		return err
 	}
-	if _, ok := s.AntiTasks[taskName]; ok {
+	if _, ok := s.AntiTask(id); ok {
 		// Edit an anti task
 		newName, newDate, newStartTime, newDuration, err := requestAntiInfo()
 		if err != nil {
 			return err
 		}
-		err = s.EditAntiTask(taskName, newName, newDate, newStartTime, newDuration)
+		newZone, err := requestTimeZone()
+		if err != nil {
+			return err
+		}
+		err = s.EditTask(id, model.Task{Name: newName, Date: newDate, StartTime: newStartTime, Duration: newDuration, TimeZone: newZone})
 		return err
 	}
-	if _, ok := s.RecurringTasks[taskName]; ok {
-		// Edit a recurring task
-		newName, newType, newDate, newStartTime, newDuration, newEndDate, newFrequency, err := requestRecurringInfo()
+	if _, ok := s.RecurringTask(id); ok {
+		// Edit a recurring task, either every occurrence or from a date onward
+		fmt.Print("Enter the date to change from (eg. 2020-11-14), or leave blank to change every occurrence: ")
+		input.Scan()
+		from := 0
+		if text := strings.TrimSpace(input.Text()); text != "" {
+			var err error
+			if from, err = stringToDateInt(text); err != nil {
+				return fmt.Errorf("bad date entered")
+			}
+			fmt.Println("Enter the details of the task from that date")
+		}
+		newName, newType, newDate, newStartTime, newDuration, newEndDate, newCount, err := requestRecurringInfo(s)
 		if err != nil {
 			return err
 		}
-		err = s.EditRecurringTask(taskName, newName, newType, newDate, newStartTime, newDuration, newEndDate, newFrequency)
+		r := model.RecurringTask{
+			Task:    model.Task{Name: newName, Type: newType, Date: newDate, StartTime: newStartTime, Duration: newDuration},
+			EndDate: newEndDate,
+			Count:   newCount,
+		}
+		if err := requestRule(&r); err != nil {
+			return err
+		}
+		if r.TimeZone, err = requestTimeZone(); err != nil {
+			return err
+		}
+		if from != 0 {
+			return s.SplitRecurring(id, from, r)
+		}
+		return s.EditRecurring(id, r)
+	}
+	return model.ErrNotFound
+}
+
+// overrideOccurrence allows the user to move or change a single occurrence of a recurring task
+func overrideOccurrence(s *model.Schedule) error {
+	name, original, err := requestOccurrence()
+	if err != nil {
 		return err
 	}
-	return fmt.Errorf("could not find task with name %q", taskName)
+	r, _ := s.RecurringTask(name)
+	fmt.Println("Enter the new details of the occurrence")
+	fmt.Print("Enter date (eg. 2020-11-14): ")
+	input := bufio.NewScanner(os.Stdin)
+	input.Scan()
+	date, err := stringToDateInt(strings.TrimSpace(input.Text()))
+	if err != nil {
+		return fmt.Errorf("bad date entered")
+	}
+	fmt.Print("Enter start time (eg. 15:30): ")
+	input.Scan()
+	startTime, err := stringToTime(strings.TrimSpace(input.Text()))
+	if err != nil {
+		return fmt.Errorf("bad start time entered")
+	}
+	fmt.Print("Enter duration (eg. '8:30' or '8.5' for 8 hours 30 min): ")
+	input.Scan()
+	duration, err := stringToDuration(strings.TrimSpace(input.Text()))
+	if err != nil {
+		return fmt.Errorf("bad duration entered")
+	}
+	return s.AddOverride(model.Override{
+		Task:     model.Task{Name: name, Date: date, StartTime: startTime, Duration: duration, TimeZone: r.TimeZone},
+		Original: original,
+	})
+}
+
+// restoreOccurrence allows the user to put a moved occurrence of a recurring task back in its place
+func restoreOccurrence(s *model.Schedule) error {
+	name, original, err := requestOccurrence()
+	if err != nil {
+		return err
+	}
+	return s.DeleteOverride(name, original)
+}
+
+// cancelHolidays allows the user to cancel the occurrences of recurring tasks that fall on the holidays of a
+// calendar file, replacing the cancellations made for holidays before
+func cancelHolidays(s *model.Schedule) error {
+	input := bufio.NewScanner(os.Stdin)
+	fmt.Print("Enter the path of the holiday calendar (json or .ics): ")
+	input.Scan()
+	holidays, err := model.ReadHolidays(strings.TrimSpace(input.Text()))
+	if err != nil {
+		return err
+	}
+	displayTypes(s, model.RECURRING)
+	fmt.Print("Enter the types to cancel on holidays (eg. Class, Work): ")
+	input.Scan()
+	types := []string{}
+	for _, t := range strings.Split(input.Text(), ",") {
+		types = append(types, strings.TrimSpace(t))
+	}
+	if err := s.CancelHolidays(holidays, types); err != nil {
+		return err
+	}
+	fmt.Printf("%d occurrences cancelled for holidays\n", len(s.HolidayCancellations()))
+	return nil
+}
+
+// removeHolidays deletes every cancellation made for holidays
+func removeHolidays(s *model.Schedule) error {
+	return s.RemoveHolidayCancellations()
+}
+
+// addBlackout allows the user to add a period in which nothing may be scheduled
+func addBlackout(s *model.Schedule) error {
+	b, err := requestBlackout()
+	if err != nil {
+		return err
+	}
+	if b.TimeZone, err = requestTimeZone(); err != nil {
+		return err
+	}
+	return s.AddBlackout(b)
+}
+
+// deleteBlackout allows the user to delete a blackout period by name
+func deleteBlackout(s *model.Schedule) error {
+	for _, b := range s.Blackouts() {
+		fmt.Println(b)
+		fmt.Println(SEP_STRING)
+	}
+	input := bufio.NewScanner(os.Stdin)
+	fmt.Print("Enter the name of the blackout period to delete: ")
+	input.Scan()
+	return s.DeleteBlackout(strings.TrimSpace(input.Text()))
+}
+
+// undo reverts the most recent change to the schedule
+func undo(s *model.Schedule) error {
+	desc, err := s.Undo()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Undid %s\n", desc)
+	return nil
+}
+
+// redo reapplies the most recently undone change to the schedule
+func redo(s *model.Schedule) error {
+	desc, err := s.Redo()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Redid %s\n", desc)
+	return nil
 }
 
 // loadFile allows the user to load the tasks from a specified json file
@@ -260,6 +506,31 @@ func loadFile(s *model.Schedule) error {
 	return s.LoadFile(filePath)
 }
 
+// loadICal allows the user to load the events from a specified iCalendar (.ics) file
+func loadICal(s *model.Schedule) error {
+	input := bufio.NewScanner(os.Stdin)
+	fmt.Print("Enter the path of the file to load: ")
+	input.Scan()
+	filePath := input.Text()
+	fmt.Println("Event categories that are not task types must be mapped onto one")
+	fmt.Print("Enter category mappings (eg. Lecture=Class, Meeting=Appointment) or leave blank: ")
+	input.Scan()
+	categories, err := parseCategories(input.Text())
+	if err != nil {
+		return err
+	}
+	return s.LoadICal(filePath, categories)
+}
+
+// loadCSV allows the user to load the tasks from a specified CSV file
+func loadCSV(s *model.Schedule) error {
+	input := bufio.NewScanner(os.Stdin)
+	fmt.Print("Enter the path of the file to load: ")
+	input.Scan()
+	filePath := input.Text()
+	return s.LoadCSV(filePath)
+}
+
 // writeTasks allows the user to write all tasks to a specified json file
 func writeTasks(s *model.Schedule) error {
 	input := bufio.NewScanner(os.Stdin)
@@ -275,13 +546,11 @@ func writeTasksByMonth(s *model.Schedule) error {
 	fmt.Print("Enter the path of the file to write to: ")
 	input.Scan()
 	filePath := input.Text()
-	fmt.Print("Enter a month (1-12): ")
-	input.Scan()
-	month, err := strconv.Atoi(input.Text())
+	start, end, err := requestMonth(input, s.Location())
 	if err != nil {
-		return fmt.Errorf("bad month entered")
+		return err
 	}
-	tasks, err := s.GetTasksByMonth(month)
+	tasks, err := s.GetTasksInRange(start, end)
 	if err != nil {
 		return fmt.Errorf("error fetching tasks: %v", err)
 	}
@@ -294,19 +563,11 @@ func writeTasksByWeek(s *model.Schedule) error {
 	fmt.Print("Enter the path of the file to write to: ")
 	input.Scan()
 	filePath := input.Text()
-	fmt.Print("Enter a month (1-12): ")
-	input.Scan()
-	month, err := strconv.Atoi(input.Text())
-	if err != nil {
-		return fmt.Errorf("bad month entered")
-	}
-	fmt.Print("Enter a day (1-31): ")
-	input.Scan()
-	day, err := strconv.Atoi(input.Text())
+	date, err := requestDate(input, s.Location())
 	if err != nil {
-		return fmt.Errorf("bad day entered")
+		return err
 	}
-	tasks, err := s.GetTasksByWeek(month, day)
+	tasks, err := s.GetTasksInWeek(date)
 	if err != nil {
 		return fmt.Errorf("error fetching tasks: %v", err)
 	}
@@ -319,23 +580,33 @@ func writeTasksByDay(s *model.Schedule) error {
 	fmt.Print("Enter the path of the file to write to: ")
 	input.Scan()
 	filePath := input.Text()
-	fmt.Print("Enter a month (1-12): ")
-	input.Scan()
-	month, err := strconv.Atoi(input.Text())
-	if err != nil {
-		return fmt.Errorf("bad month entered")
-	}
-	fmt.Print("Enter a day (1-31): ")
-	input.Scan()
-	day, err := strconv.Atoi(input.Text())
+	date, err := requestDate(input, s.Location())
 	if err != nil {
-		return fmt.Errorf("bad day entered")
+		return err
 	}
-	tasks, err := s.GetTasksByDay(month, day)
+	tasks, err := s.GetTasksInRange(date, date.AddDate(0, 0, 1))
 	if err != nil {
 		return fmt.Errorf("error fetching tasks: %v", err)
 	}
 	return s.WriteTaskList(filePath, tasks)
 }
 
+// writeICal allows the user to write all tasks to a specified iCalendar (.ics) file
+func writeICal(s *model.Schedule) error {
+	input := bufio.NewScanner(os.Stdin)
+	fmt.Print("Enter the path of the file to write to (eg. schedule.ics): ")
+	input.Scan()
+	filePath := input.Text()
+	return s.WriteICal(filePath)
+}
+
+// writeCSV allows the user to write all tasks to a specified CSV file
+func writeCSV(s *model.Schedule) error {
+	input := bufio.NewScanner(os.Stdin)
+	fmt.Print("Enter the path of the file to write to (eg. schedule.csv): ")
+	input.Scan()
+	filePath := input.Text()
+	return s.WriteCSV(filePath)
+}
+
 //!--
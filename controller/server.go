@@ -0,0 +1,687 @@
+// Package controller provides functions to edit the schedule or view (ie. command line)
+// server.go provides a JSON HTTP API for driving the schedule from other programs
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hlin91/CS3560_Scheduler_Backup/model"
+)
+
+const (
+	DEFAULT_ADDR = "localhost:8080"
+	// Largest request body the server will read
+	MAX_BODY_BYTES = 10 << 20
+)
+
+/**********************************************
+ * Endpoints are...
+ * GET    /tasks                     List all tasks
+ * POST   /tasks/transient           Create a transient task
+ * POST   /tasks/anti                Create an anti task
+ * POST   /tasks/recurring           Create a recurring task
+ * GET    /tasks/{ref}               View a task
+ * PUT    /tasks/{ref}               Edit a task
+ * DELETE /tasks/{ref}               Delete a task
+ * POST   /tasks/{ref}/split         Change a recurring task from the date From onward
+ * GET    /tasks/{ref}/overrides/{date}  View the override of the occurrence of a recurring task on a date
+ * PUT    /tasks/{ref}/overrides/{date}  Move or change the occurrence on a date
+ * DELETE /tasks/{ref}/overrides/{date}  Put the occurrence on a date back in its place
+ * A task is given by {ref}, its ID or a name no other task has, and a name shared by several tasks is a conflict
+ * GET    /blackouts                 List the blackout periods
+ * POST   /blackouts                 Create a blackout period
+ * GET    /blackouts/{name}          View a blackout period
+ * DELETE /blackouts/{name}          Delete a blackout period
+ * GET    /types                     List the task types the schedule accepts
+ * GET    /holidays                  List the cancellations generated for holidays
+ * PUT    /holidays                  Replace the cancellations generated for holidays
+ * DELETE /holidays                  Delete the cancellations generated for holidays
+ * GET    /schedule/month?month=M    Tasks in a month
+ * GET    /schedule/week?month=M&day=D  Tasks in the week of a day
+ * GET    /schedule/day?month=M&day=D   Tasks on a day
+ * GET    /schedule/range?start=S&end=E Tasks from date S up to date E
+ * The /schedule queries take an optional zone=ZONE parameter (eg. America/Los_Angeles) giving the
+ * time zone of the dates and of the tasks returned, which is UTC by default
+ * POST   /undo                      Undo the last change
+ * POST   /redo                      Redo the last undone change
+ * POST   /import                    Load a json task list
+ * GET    /export                    Download the json task list
+ **********************************************/
+
+// Server serves a schedule over HTTP
+// Queries are served concurrently while changes are made one at a time and, if a path is given,
+// each change is saved to it before the next is made
+type Server struct {
+	mu       sync.Mutex // Held while a change is made and saved
+	schedule *model.Schedule
+	path     string
+}
+
+// NewServer creates a server for the schedule
+// If path is not empty, the schedule is written to it after every successful change
+func NewServer(s *model.Schedule, path string) *Server {
+	return &Server{
+		schedule: s,
+		path:     path,
+	}
+}
+
+// jsonHours is a start time or duration in a request body, written as "17:30" like the responses or
+// as float hours like older clients
+type jsonHours time.Duration
+
+func (h *jsonHours) UnmarshalJSON(data []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	switch v := v.(type) {
+	case float64:
+		*h = jsonHours(math.Round(v*60)) * jsonHours(time.Minute)
+		return nil
+	case string:
+		d, err := stringToDuration(v)
+		if err != nil {
+			return fmt.Errorf("bad time %q", v)
+		}
+		*h = jsonHours(d)
+		return nil
+	}
+	return fmt.Errorf("bad time %s", data)
+}
+
+// taskRequest is the request body for creating or editing a transient or anti task
+// EndDate and Series are only used by range anti tasks, and EndDate and Frequency by recurring anti tasks
+type taskRequest struct {
+	Name      string
+	Type      string
+	Date      int
+	StartTime jsonHours
+	Duration  jsonHours
+	TimeZone  string
+	EndDate   int
+	Series    string
+	Frequency int
+}
+
+// task converts the request to a task
+func (t taskRequest) task() model.Task {
+	return model.Task{Name: t.Name, Type: t.Type, Date: t.Date, StartTime: time.Duration(t.StartTime), Duration: time.Duration(t.Duration), TimeZone: t.TimeZone}
+}
+
+// anti converts the request to an anti task
+func (t taskRequest) anti() model.AntiTask {
+	return model.AntiTask{Task: t.task(), EndDate: t.EndDate, Series: t.Series, Frequency: t.Frequency}
+}
+
+// recurRequest is the request body for creating or editing a recurring task
+type recurRequest struct {
+	Name      string
+	Type      string
+	StartDate int
+	StartTime jsonHours
+	Duration  jsonHours
+	EndDate   int
+	Count     int
+	Frequency int
+	Weekdays  model.Weekdays
+	MonthDay  int
+	MonthWeek int
+	TimeZone  string
+}
+
+// recurring converts the request to a recurring task
+func (t recurRequest) recurring() model.RecurringTask {
+	return model.RecurringTask{
+		Task:      model.Task{Name: t.Name, Type: t.Type, Date: t.StartDate, StartTime: time.Duration(t.StartTime), Duration: time.Duration(t.Duration), TimeZone: t.TimeZone},
+		EndDate:   t.EndDate,
+		Count:     t.Count,
+		Frequency: t.Frequency,
+		Weekdays:  t.Weekdays,
+		MonthDay:  t.MonthDay,
+		MonthWeek: t.MonthWeek,
+	}
+}
+
+// splitRequest is the request body for changing a recurring task from a date onward, with the details of
+// the new task it is split into
+type splitRequest struct {
+	From int
+	recurRequest
+}
+
+// overrideRequest is the request body for moving or changing an occurrence of a recurring task
+type overrideRequest struct {
+	Date      int
+	StartTime jsonHours
+	Duration  jsonHours
+	TimeZone  string
+}
+
+// blackoutRequest is the request body for creating a blackout period
+type blackoutRequest struct {
+	Name      string
+	Date      int
+	StartTime jsonHours
+	EndDate   int
+	EndTime   jsonHours
+	TimeZone  string
+	Policy    string
+}
+
+// holidaysRequest is the request body for cancelling the occurrences of recurring tasks of the given types that
+// fall on holidays
+type holidaysRequest struct {
+	Holidays []model.Holiday
+	Types    []string
+}
+
+// httpError is an error with the status code it should be reported with
+type httpError struct {
+	status int
+	err    error
+}
+
+func (h httpError) Error() string {
+	return h.err.Error()
+}
+
+func badRequest(format string, a ...interface{}) error {
+	return httpError{http.StatusBadRequest, fmt.Errorf(format, a...)}
+}
+
+// statusFor maps an error from the schedule to an HTTP status code
+func statusFor(err error) int {
+	var h httpError
+	switch {
+	case errors.As(err, &h):
+		return h.status
+	case errors.Is(err, model.ErrConflict), errors.Is(err, model.ErrNameTaken), errors.Is(err, model.ErrIDTaken), errors.Is(err, model.ErrAmbiguous):
+		return http.StatusConflict
+	case errors.Is(err, model.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, model.ErrInvalid):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// ServeHTTP routes a request to the matching endpoint
+func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		srv.mu.Lock()
+		defer srv.mu.Unlock()
+	}
+	status, body, err := srv.route(r)
+	if err != nil {
+		writeJSON(w, statusFor(err), map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, status, body)
+}
+
+// route dispatches the request and returns the status and body of a successful response
+func (srv *Server) route(r *http.Request) (int, interface{}, error) {
+	path := strings.Trim(r.URL.Path, "/")
+	parts := strings.SplitN(path, "/", 2)
+	switch {
+	case path == "tasks":
+		if r.Method != http.MethodGet {
+			return 0, nil, methodNotAllowed(r)
+		}
+		return srv.exportTasks()
+	case parts[0] == "tasks" && len(parts) == 2 && strings.HasSuffix(parts[1], "/split"):
+		if r.Method != http.MethodPost {
+			return 0, nil, methodNotAllowed(r)
+		}
+		return srv.splitTask(strings.TrimSuffix(parts[1], "/split"), r)
+	case parts[0] == "tasks" && len(parts) == 2 && strings.Contains(parts[1], "/overrides/"):
+		i := strings.LastIndex(parts[1], "/overrides/")
+		date, err := strconv.Atoi(parts[1][i+len("/overrides/"):])
+		if err != nil {
+			return 0, nil, badRequest("bad date %q", parts[1][i+len("/overrides/"):])
+		}
+		return srv.override(parts[1][:i], date, r)
+	case parts[0] == "tasks" && len(parts) == 2:
+		switch r.Method {
+		case http.MethodPost:
+			return srv.createTask(parts[1], r)
+		case http.MethodGet:
+			return srv.viewTask(parts[1])
+		case http.MethodPut:
+			return srv.editTask(parts[1], r)
+		case http.MethodDelete:
+			return srv.deleteTask(parts[1])
+		}
+		return 0, nil, methodNotAllowed(r)
+	case path == "types":
+		if r.Method != http.MethodGet {
+			return 0, nil, methodNotAllowed(r)
+		}
+		return http.StatusOK, srv.schedule.Types().Types(""), nil
+	case path == "holidays":
+		return srv.holidays(r)
+	case parts[0] == "blackouts":
+		name := ""
+		if len(parts) == 2 {
+			name = parts[1]
+		}
+		return srv.blackouts(name, r)
+	case parts[0] == "schedule" && len(parts) == 2:
+		if r.Method != http.MethodGet {
+			return 0, nil, methodNotAllowed(r)
+		}
+		return srv.queryTasks(parts[1], r)
+	case path == "undo" || path == "redo":
+		if r.Method != http.MethodPost {
+			return 0, nil, methodNotAllowed(r)
+		}
+		return srv.undoRedo(path)
+	case path == "import":
+		if r.Method != http.MethodPost {
+			return 0, nil, methodNotAllowed(r)
+		}
+		return srv.importTasks(r)
+	case path == "export":
+		if r.Method != http.MethodGet {
+			return 0, nil, methodNotAllowed(r)
+		}
+		return srv.exportTasks()
+	}
+	return 0, nil, httpError{http.StatusNotFound, fmt.Errorf("no endpoint at %q", r.URL.Path)}
+}
+
+func methodNotAllowed(r *http.Request) error {
+	return httpError{http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed on %q", r.Method, r.URL.Path)}
+}
+
+// createTask handles POST /tasks/{transient,anti,recurring}
+// The task is given its ID here so that it can be looked up once added
+func (srv *Server) createTask(kind string, r *http.Request) (int, interface{}, error) {
+	s := srv.schedule
+	id := model.NewID()
+	switch kind {
+	case "transient":
+		var t taskRequest
+		if err := decodeBody(r, &t); err != nil {
+			return 0, nil, err
+		}
+		task := t.task()
+		task.ID = id
+		if err := s.AddTask(task); err != nil {
+			return 0, nil, err
+		}
+		task, _ = s.TransientTask(id)
+		return srv.saved(http.StatusCreated, task)
+	case "anti":
+		var t taskRequest
+		if err := decodeBody(r, &t); err != nil {
+			return 0, nil, err
+		}
+		if t.Type == "" {
+			t.Type = model.CANCEL
+		}
+		a := t.anti()
+		a.ID = id
+		if err := s.AddAnti(a); err != nil {
+			return 0, nil, err
+		}
+		a, _ = s.AntiTask(id)
+		return srv.saved(http.StatusCreated, a)
+	case "recurring":
+		var t recurRequest
+		if err := decodeBody(r, &t); err != nil {
+			return 0, nil, err
+		}
+		task := t.recurring()
+		task.ID = id
+		if err := s.AddRecurring(task); err != nil {
+			return 0, nil, err
+		}
+		task, _ = s.RecurringTask(id)
+		return srv.saved(http.StatusCreated, task)
+	}
+	return 0, nil, httpError{http.StatusNotFound, fmt.Errorf("unknown task kind %q", kind)}
+}
+
+// viewTask handles GET /tasks/{ref}
+func (srv *Server) viewTask(ref string) (int, interface{}, error) {
+	s := srv.schedule
+	id, err := s.TaskID(ref)
+	if err != nil {
+		return 0, nil, err
+	}
+	if t, ok := s.TransientTask(id); ok {
+		return http.StatusOK, t, nil
+	}
+	if t, ok := s.AntiTask(id); ok {
+		return http.StatusOK, t, nil
+	}
+	t, _ := s.RecurringTask(id)
+	return http.StatusOK, t, nil
+}
+
+// editTask handles PUT /tasks/{ref}
+// The body holds the complete new details of the task, which keeps its ID
+func (srv *Server) editTask(ref string, r *http.Request) (int, interface{}, error) {
+	s := srv.schedule
+	id, err := s.TaskID(ref)
+	if err != nil {
+		return 0, nil, err
+	}
+	if _, ok := s.TransientTask(id); ok {
+		var t taskRequest
+		if err := decodeBody(r, &t); err != nil {
+			return 0, nil, err
+		}
+		if err := s.EditTask(id, t.task()); err != nil {
+			return 0, nil, err
+		}
+		task, _ := s.TransientTask(id)
+		return srv.saved(http.StatusOK, task)
+	}
+	if old, ok := s.AntiTask(id); ok {
+		var t taskRequest
+		if err := decodeBody(r, &t); err != nil {
+			return 0, nil, err
+		}
+		// An anti task generated for a holiday stays generated so it is regenerated or removed with the others
+		a := t.anti()
+		a.Holiday = old.Holiday
+		if err := s.EditAnti(id, a); err != nil {
+			return 0, nil, err
+		}
+		task, _ := s.AntiTask(id)
+		return srv.saved(http.StatusOK, task)
+	}
+	var t recurRequest
+	if err := decodeBody(r, &t); err != nil {
+		return 0, nil, err
+	}
+	if err := s.EditRecurring(id, t.recurring()); err != nil {
+		return 0, nil, err
+	}
+	task, _ := s.RecurringTask(id)
+	return srv.saved(http.StatusOK, task)
+}
+
+// deleteTask handles DELETE /tasks/{ref}
+func (srv *Server) deleteTask(ref string) (int, interface{}, error) {
+	if err := srv.schedule.DeleteTask(ref); err != nil {
+		return 0, nil, err
+	}
+	return srv.saved(http.StatusOK, map[string]string{"deleted": ref})
+}
+
+// splitTask handles POST /tasks/{ref}/split
+func (srv *Server) splitTask(ref string, r *http.Request) (int, interface{}, error) {
+	s := srv.schedule
+	var t splitRequest
+	if err := decodeBody(r, &t); err != nil {
+		return 0, nil, err
+	}
+	newTask := t.recurring()
+	newTask.ID = model.NewID()
+	if err := s.SplitRecurring(ref, t.From, newTask); err != nil {
+		return 0, nil, err
+	}
+	task, _ := s.RecurringTask(newTask.ID)
+	return srv.saved(http.StatusCreated, task)
+}
+
+// override handles GET, PUT and DELETE /tasks/{ref}/overrides/{date}
+func (srv *Server) override(ref string, date int, r *http.Request) (int, interface{}, error) {
+	s := srv.schedule
+	switch r.Method {
+	case http.MethodGet:
+		if o, ok := s.Override(ref, date); ok {
+			return http.StatusOK, o, nil
+		}
+		return 0, nil, model.ErrNotFound
+	case http.MethodPut:
+		var t overrideRequest
+		if err := decodeBody(r, &t); err != nil {
+			return 0, nil, err
+		}
+		o := model.Override{
+			Task:     model.Task{ID: ref, Date: t.Date, StartTime: time.Duration(t.StartTime), Duration: time.Duration(t.Duration), TimeZone: t.TimeZone},
+			Original: date,
+		}
+		if err := s.AddOverride(o); err != nil {
+			return 0, nil, err
+		}
+		o, _ = s.Override(ref, date)
+		return srv.saved(http.StatusOK, o)
+	case http.MethodDelete:
+		if err := s.DeleteOverride(ref, date); err != nil {
+			return 0, nil, err
+		}
+		return srv.saved(http.StatusOK, map[string]string{"restored": fmt.Sprintf("%s on %d", ref, date)})
+	}
+	return 0, nil, methodNotAllowed(r)
+}
+
+// blackouts handles GET and POST /blackouts and GET and DELETE /blackouts/{name}
+func (srv *Server) blackouts(name string, r *http.Request) (int, interface{}, error) {
+	s := srv.schedule
+	switch {
+	case name == "" && r.Method == http.MethodGet:
+		return http.StatusOK, s.Blackouts(), nil
+	case name == "" && r.Method == http.MethodPost:
+		var t blackoutRequest
+		if err := decodeBody(r, &t); err != nil {
+			return 0, nil, err
+		}
+		b := model.Blackout{Name: t.Name, Date: t.Date, StartTime: time.Duration(t.StartTime), EndDate: t.EndDate, EndTime: time.Duration(t.EndTime), TimeZone: t.TimeZone, Policy: t.Policy}
+		if err := s.AddBlackout(b); err != nil {
+			return 0, nil, err
+		}
+		b, _ = s.Blackout(t.Name)
+		return srv.saved(http.StatusCreated, b)
+	case name != "" && r.Method == http.MethodGet:
+		if b, ok := s.Blackout(name); ok {
+			return http.StatusOK, b, nil
+		}
+		return 0, nil, model.ErrNotFound
+	case name != "" && r.Method == http.MethodDelete:
+		if err := s.DeleteBlackout(name); err != nil {
+			return 0, nil, err
+		}
+		return srv.saved(http.StatusOK, map[string]string{"deleted": name})
+	}
+	return 0, nil, methodNotAllowed(r)
+}
+
+// holidays handles GET, PUT and DELETE /holidays
+func (srv *Server) holidays(r *http.Request) (int, interface{}, error) {
+	s := srv.schedule
+	switch r.Method {
+	case http.MethodGet:
+		return http.StatusOK, s.HolidayCancellations(), nil
+	case http.MethodPut:
+		var t holidaysRequest
+		if err := decodeBody(r, &t); err != nil {
+			return 0, nil, err
+		}
+		if err := s.CancelHolidays(t.Holidays, t.Types); err != nil {
+			return 0, nil, err
+		}
+		return srv.saved(http.StatusOK, s.HolidayCancellations())
+	case http.MethodDelete:
+		if err := s.RemoveHolidayCancellations(); err != nil {
+			return 0, nil, err
+		}
+		return srv.saved(http.StatusOK, s.HolidayCancellations())
+	}
+	return 0, nil, methodNotAllowed(r)
+}
+
+// queryTasks handles GET /schedule/{month,week,day,range}
+func (srv *Server) queryTasks(view string, r *http.Request) (int, interface{}, error) {
+	s := srv.schedule
+	loc, err := queryZone(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if view == "range" {
+		start, err := queryDate(r, "start", loc)
+		if err != nil {
+			return 0, nil, err
+		}
+		end, err := queryDate(r, "end", loc)
+		if err != nil {
+			return 0, nil, err
+		}
+		tasks, err := s.GetTasksInRange(start, end)
+		if err != nil {
+			return 0, nil, err
+		}
+		return http.StatusOK, model.TasksIn(tasks, loc), nil
+	}
+	month, err := queryInt(r, "month")
+	if err != nil {
+		return 0, nil, err
+	}
+	var tasks []model.Task
+	switch view {
+	case "month":
+		tasks, err = s.GetTasksByMonth(month)
+	case "week", "day":
+		day, e := queryInt(r, "day")
+		if e != nil {
+			return 0, nil, e
+		}
+		if view == "week" {
+			tasks, err = s.GetTasksByWeek(month, day)
+		} else {
+			tasks, err = s.GetTasksByDay(month, day)
+		}
+	default:
+		return 0, nil, httpError{http.StatusNotFound, fmt.Errorf("unknown view %q", view)}
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].Before(tasks[j])
+	})
+	return http.StatusOK, model.TasksIn(tasks, loc), nil
+}
+
+// undoRedo handles POST /undo and POST /redo
+func (srv *Server) undoRedo(action string) (int, interface{}, error) {
+	undo := srv.schedule.Undo
+	if action == "redo" {
+		undo = srv.schedule.Redo
+	}
+	desc, err := undo()
+	if errors.Is(err, model.ErrNothingToUndo) || errors.Is(err, model.ErrNothingToRedo) {
+		return 0, nil, httpError{http.StatusConflict, err}
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	return srv.saved(http.StatusOK, map[string]string{action: desc})
+}
+
+// importTasks handles POST /import
+// The body is a json task list in the same format read by LoadFile
+func (srv *Server) importTasks(r *http.Request) (int, interface{}, error) {
+	content, err := io.ReadAll(io.LimitReader(r.Body, MAX_BODY_BYTES))
+	if err != nil {
+		return 0, nil, badRequest("error reading body: %v", err)
+	}
+	if err := srv.schedule.LoadJSON(content); err != nil {
+		return 0, nil, err
+	}
+	return srv.exportTasks()
+}
+
+// exportTasks handles GET /export and GET /tasks
+func (srv *Server) exportTasks() (int, interface{}, error) {
+	content, err := srv.schedule.MarshalTasks()
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, json.RawMessage(content), nil
+}
+
+// saved writes the schedule to the server's file, if any, and returns the response for a change
+func (srv *Server) saved(status int, body interface{}) (int, interface{}, error) {
+	if srv.path != "" {
+		if err := srv.schedule.WriteTasks(srv.path); err != nil {
+			return 0, nil, err
+		}
+	}
+	return status, body, nil
+}
+
+// decodeBody decodes the json request body into v
+func decodeBody(r *http.Request, v interface{}) error {
+	dec := json.NewDecoder(io.LimitReader(r.Body, MAX_BODY_BYTES))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return badRequest("bad request body: %v", err)
+	}
+	return nil
+}
+
+// queryInt reads a required integer query parameter
+func queryInt(r *http.Request, key string) (int, error) {
+	v := r.URL.Query().Get(key)
+	if v == "" {
+		return 0, badRequest("missing %q parameter", key)
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, badRequest("bad %q parameter %q", key, v)
+	}
+	return i, nil
+}
+
+// queryDate reads a required date query parameter (eg. 2020-04-28) as the start of that day in a time zone
+func queryDate(r *http.Request, key string, loc *time.Location) (time.Time, error) {
+	v := r.URL.Query().Get(key)
+	if v == "" {
+		return time.Time{}, badRequest("missing %q parameter", key)
+	}
+	date, err := time.ParseInLocation(DATE_LAYOUT, v, loc)
+	if err != nil {
+		return time.Time{}, badRequest("bad %q parameter %q", key, v)
+	}
+	return date, nil
+}
+
+// queryZone reads the optional zone query parameter, which is UTC if not given
+func queryZone(r *http.Request) (*time.Location, error) {
+	v := r.URL.Query().Get("zone")
+	loc, err := time.LoadLocation(v)
+	if err != nil {
+		return nil, badRequest("bad %q parameter %q", "zone", v)
+	}
+	return loc, nil
+}
+
+// writeJSON writes v as the json response body
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	content, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(content)
+}
+
+//!--
@@ -5,10 +5,12 @@ package controller
 import (
 	"bufio"
 	"fmt"
+	"math"
 	"os"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/hlin91/CS3560_Scheduler_Backup/model"
 )
@@ -16,6 +18,9 @@ import (
 const (
 	TIME_FORMAT = `^\pN{1,2}:\pN{2}$`
 	SEP_STRING  = "--------------------------------"
+	// Layouts of the months and dates entered by the user
+	MONTH_LAYOUT = "2006-01"
+	DATE_LAYOUT  = "2006-01-02"
 )
 
 func displayHeader() {
@@ -41,13 +46,13 @@ func stringToDateInt(s string) (int, error) {
 	return date, nil
 }
 
-// requestTaskInfo asks the user to enter transient task information
-func requestTaskInfo() (string, string, int, float32, float32, error) {
+// requestTaskInfo asks the user to enter transient task information, listing the transient types of the schedule
+func requestTaskInfo(s *model.Schedule) (string, string, int, time.Duration, time.Duration, error) {
 	input := bufio.NewScanner(os.Stdin)
 	fmt.Print("Enter task name: ")
 	input.Scan()
 	name := strings.TrimSpace(input.Text())
-	displayTransientTypes()
+	displayTypes(s, model.TRANSIENT)
 	fmt.Print("Enter task type: ")
 	input.Scan()
 	taskType := strings.TrimSpace(input.Text())
@@ -63,17 +68,17 @@ func requestTaskInfo() (string, string, int, float32, float32, error) {
 	if err != nil {
 		return "", "", 0, 0, 0, fmt.Errorf("bad start time entered")
 	}
-	fmt.Print("Enter duration (eg. '8.5' for 8 hours 30 min): ")
+	fmt.Print("Enter duration (eg. '8:30' or '8.5' for 8 hours 30 min): ")
 	input.Scan()
-	duration, err := strconv.ParseFloat(strings.TrimSpace(input.Text()), 32)
+	duration, err := stringToDuration(strings.TrimSpace(input.Text()))
 	if err != nil {
 		return "", "", 0, 0, 0, fmt.Errorf("bad duration entered")
 	}
-	return name, taskType, date, float32(startTime), float32(duration), nil
+	return name, taskType, date, startTime, duration, nil
 }
 
 // requestAntiInfo asks the user to enter anti task information
-func requestAntiInfo() (string, int, float32, float32, error) {
+func requestAntiInfo() (string, int, time.Duration, time.Duration, error) {
 	input := bufio.NewScanner(os.Stdin)
 	fmt.Print("Enter task name: ")
 	input.Scan()
@@ -90,22 +95,23 @@ func requestAntiInfo() (string, int, float32, float32, error) {
 	if err != nil {
 		return "", 0, 0, 0, fmt.Errorf("bad start time entered")
 	}
-	fmt.Print("Enter duration (eg. '8.5' for 8 hours 30 min): ")
+	fmt.Print("Enter duration (eg. '8:30' or '8.5' for 8 hours 30 min): ")
 	input.Scan()
-	duration, err := strconv.ParseFloat(strings.TrimSpace(input.Text()), 32)
+	duration, err := stringToDuration(strings.TrimSpace(input.Text()))
 	if err != nil {
 		return "", 0, 0, 0, fmt.Errorf("bad duration entered")
 	}
-	return name, date, float32(startTime), float32(duration), nil
+	return name, date, startTime, duration, nil
 }
 
-// requestRecurringInfo asks the user to enter recurring task information
-func requestRecurringInfo() (string, string, int, float32, float32, int, int, error) {
+// requestRecurringInfo asks the user to enter recurring task information, listing the recurring types of the schedule
+// The task ends on an end date, after a count or never, and the rule it repeats by is asked for by requestRule
+func requestRecurringInfo(s *model.Schedule) (string, string, int, time.Duration, time.Duration, int, int, error) {
 	input := bufio.NewScanner(os.Stdin)
 	fmt.Print("Enter task name: ")
 	input.Scan()
 	name := strings.TrimSpace(input.Text())
-	displayRecurringTypes()
+	displayTypes(s, model.RECURRING)
 	fmt.Print("Enter task type: ")
 	input.Scan()
 	taskType := strings.TrimSpace(input.Text())
@@ -121,46 +127,204 @@ func requestRecurringInfo() (string, string, int, float32, float32, int, int, er
 	if err != nil {
 		return "", "", 0, 0, 0, 0, 0, fmt.Errorf("bad start time entered")
 	}
-	fmt.Print("Enter duration (eg. '8.5' for 8 hours 30 min): ")
+	fmt.Print("Enter duration (eg. '8:30' or '8.5' for 8 hours 30 min): ")
 	input.Scan()
-	duration, err := strconv.ParseFloat(strings.TrimSpace(input.Text()), 32)
+	duration, err := stringToDuration(strings.TrimSpace(input.Text()))
 	if err != nil {
 		return "", "", 0, 0, 0, 0, 0, fmt.Errorf("bad duration entered")
 	}
-	fmt.Print("Enter end date (eg. 2020-11-14): ")
+	fmt.Print("Enter end date (eg. 2020-11-14), number of times (eg. 10) or nothing to repeat forever: ")
 	input.Scan()
-	endDate, err := stringToDateInt(strings.TrimSpace(input.Text()))
-	if err != nil {
-		return "", "", 0, 0, 0, 0, 0, fmt.Errorf("bad date entered")
+	end := strings.TrimSpace(input.Text())
+	endDate, count := 0, 0
+	switch {
+	case end == "":
+	case strings.Contains(end, "-"):
+		if endDate, err = stringToDateInt(end); err != nil {
+			return "", "", 0, 0, 0, 0, 0, fmt.Errorf("bad date entered")
+		}
+	default:
+		if count, err = strconv.Atoi(end); err != nil {
+			return "", "", 0, 0, 0, 0, 0, fmt.Errorf("bad number of times entered")
+		}
+	}
+	return name, taskType, date, startTime, duration, endDate, count, nil
+}
+
+// requestRule asks the user to enter the rule a recurring task repeats by
+func requestRule(r *model.RecurringTask) error {
+	input := bufio.NewScanner(os.Stdin)
+	requestInt := func(prompt string) (int, error) {
+		fmt.Print(prompt)
+		input.Scan()
+		n, err := strconv.Atoi(strings.TrimSpace(input.Text()))
+		if err != nil {
+			return 0, fmt.Errorf("bad number entered")
+		}
+		return n, nil
 	}
-	fmt.Print("Enter frequency (1-7): ")
+	fmt.Println("1. Every few days")
+	fmt.Println("2. On days of the week (eg. Mon, Wed and Fri)")
+	fmt.Println("3. On a day of the month (eg. the 15th)")
+	fmt.Println("4. On a day of a week of the month (eg. the 2nd Tuesday)")
+	fmt.Print("Choose how the task repeats: ")
+	input.Scan()
+	var err error
+	switch strings.TrimSpace(input.Text()) {
+	case "1":
+		r.Frequency, err = requestInt("Enter frequency in days (1-7): ")
+		return err
+	case "2":
+		fmt.Print("Enter days of the week (eg. Mon,Wed,Fri): ")
+		input.Scan()
+		if r.Weekdays, err = model.ParseWeekdays(input.Text()); err != nil {
+			return fmt.Errorf("bad days entered")
+		}
+		r.Frequency, err = requestInt("Enter weeks between (eg. 1 for every week): ")
+		return err
+	case "3":
+		if r.MonthDay, err = requestInt("Enter day of the month (1-31): "); err != nil {
+			return err
+		}
+		r.Frequency, err = requestInt("Enter months between (eg. 1 for every month): ")
+		return err
+	case "4":
+		if r.MonthWeek, err = requestInt("Enter week of the month (1-5, or -1 for the last): "); err != nil {
+			return err
+		}
+		fmt.Print("Enter day of the week (eg. Tue): ")
+		input.Scan()
+		if r.Weekdays, err = model.ParseWeekdays(input.Text()); err != nil {
+			return fmt.Errorf("bad day entered")
+		}
+		r.Frequency, err = requestInt("Enter months between (eg. 1 for every month): ")
+		return err
+	}
+	return fmt.Errorf("bad option entered")
+}
+
+// requestAntiRange prompts the user for the information needed to create a range anti task
+func requestAntiRange() (model.AntiTask, error) {
+	input := bufio.NewScanner(os.Stdin)
+	fmt.Print("Enter task name: ")
+	input.Scan()
+	a := model.AntiTask{Task: model.Task{Name: strings.TrimSpace(input.Text()), Type: model.CANCEL}}
+	fmt.Print("Enter first date (eg. 2020-04-20): ")
+	input.Scan()
+	var err error
+	if a.Date, err = stringToDateInt(strings.TrimSpace(input.Text())); err != nil {
+		return a, fmt.Errorf("bad date entered")
+	}
+	fmt.Print("Enter last date (eg. 2020-04-26): ")
+	input.Scan()
+	if a.EndDate, err = stringToDateInt(strings.TrimSpace(input.Text())); err != nil {
+		return a, fmt.Errorf("bad date entered")
+	}
+	fmt.Print("Enter the name or ID of the recurring task to cancel, or leave blank for every recurring task: ")
+	input.Scan()
+	a.Series = strings.TrimSpace(input.Text())
+	return a, nil
+}
+
+// requestAntiRecurrence asks the user to enter the end date and frequency of a recurring anti task
+func requestAntiRecurrence(a *model.AntiTask) error {
+	input := bufio.NewScanner(os.Stdin)
+	fmt.Print("Enter last date (eg. 2020-05-26): ")
 	input.Scan()
-	frequency, err := strconv.Atoi(input.Text())
+	var err error
+	if a.EndDate, err = stringToDateInt(strings.TrimSpace(input.Text())); err != nil {
+		return fmt.Errorf("bad date entered")
+	}
+	fmt.Print("Enter occurrences between cancellations (eg. 2 for every other occurrence): ")
+	input.Scan()
+	if a.Frequency, err = strconv.Atoi(strings.TrimSpace(input.Text())); err != nil {
+		return fmt.Errorf("bad number entered")
+	}
+	return nil
+}
+
+// dayAfter returns the date after a date in YYYYMMDD form
+func dayAfter(date int) int {
+	next := time.Date(date/10000, time.Month(date/100%100), date%100+1, 0, 0, 0, 0, time.UTC)
+	return next.Year()*10000 + int(next.Month())*100 + next.Day()
+}
+
+// requestBlackout prompts the user for the information needed to create a blackout period
+// The period covers whole days unless times are entered
+func requestBlackout() (model.Blackout, error) {
+	input := bufio.NewScanner(os.Stdin)
+	fmt.Print("Enter blackout name: ")
+	input.Scan()
+	b := model.Blackout{Name: strings.TrimSpace(input.Text())}
+	var err error
+	fmt.Print("Enter first date (eg. 2020-11-25): ")
+	input.Scan()
+	if b.Date, err = stringToDateInt(strings.TrimSpace(input.Text())); err != nil {
+		return b, fmt.Errorf("bad date entered")
+	}
+	fmt.Print("Enter start time (eg. 17:30), or leave blank for the start of the day: ")
+	input.Scan()
+	if s := strings.TrimSpace(input.Text()); s != "" {
+		if b.StartTime, err = stringToTime(s); err != nil {
+			return b, fmt.Errorf("bad time entered")
+		}
+	}
+	fmt.Print("Enter last date (eg. 2020-11-29): ")
+	input.Scan()
+	if b.EndDate, err = stringToDateInt(strings.TrimSpace(input.Text())); err != nil {
+		return b, fmt.Errorf("bad date entered")
+	}
+	fmt.Print("Enter end time (eg. 12:00), or leave blank for the end of the day: ")
+	input.Scan()
+	if s := strings.TrimSpace(input.Text()); s != "" {
+		if b.EndTime, err = stringToTime(s); err != nil {
+			return b, fmt.Errorf("bad time entered")
+		}
+	} else {
+		b.EndDate = dayAfter(b.EndDate)
+	}
+	fmt.Print("Skip the occurrences of recurring tasks in the period instead of rejecting them? (y/n): ")
+	input.Scan()
+	b.Policy = model.BLACKOUT_REJECT
+	if strings.HasPrefix(strings.ToLower(strings.TrimSpace(input.Text())), "y") {
+		b.Policy = model.BLACKOUT_SKIP
+	}
+	return b, nil
+}
+
+// requestOccurrence prompts the user for a recurring task and the date of one of its occurrences
+func requestOccurrence() (string, int, error) {
+	input := bufio.NewScanner(os.Stdin)
+	fmt.Print("Enter the name or ID of the recurring task: ")
+	input.Scan()
+	name := strings.TrimSpace(input.Text())
+	fmt.Print("Enter the date of the occurrence (eg. 2020-11-14): ")
+	input.Scan()
+	date, err := stringToDateInt(strings.TrimSpace(input.Text()))
 	if err != nil {
-		return "", "", 0, 0, 0, 0, 0, fmt.Errorf("bad frequency entered")
+		return "", 0, fmt.Errorf("bad date entered")
 	}
-	return name, taskType, date, float32(startTime), float32(duration), endDate, frequency, nil
+	return name, date, nil
 }
 
-func displayTransientTypes() {
+// displayTypes prints the task types of a category that the schedule accepts
+func displayTypes(s *model.Schedule, category string) {
 	fmt.Println("Available types...")
-	fmt.Println(model.VISIT)
-	fmt.Println(model.SHOPPING)
-	fmt.Println(model.APPOINTMENT)
+	for _, t := range s.Types().Types(category) {
+		fmt.Println(typeString(t))
+	}
 }
 
-func displayRecurringTypes() {
-	fmt.Println("Available types...")
-	fmt.Println(model.CLASS)
-	fmt.Println(model.STUDY)
-	fmt.Println(model.SLEEP)
-	fmt.Println(model.EXERCISE)
-	fmt.Println(model.WORK)
-	fmt.Println(model.MEAL)
+// typeString describes a task type along with its aliases
+func typeString(t model.TaskType) string {
+	if len(t.Aliases) == 0 {
+		return t.Name
+	}
+	return fmt.Sprintf("%s (or %s)", t.Name, strings.Join(t.Aliases, ", "))
 }
 
-// Convert a time string of format TIME_FORMAT to a float time in hours
-func stringToTime(s string) (float32, error) {
+// Convert a time string of format TIME_FORMAT to a time since midnight
+func stringToTime(s string) (time.Duration, error) {
 	re := regexp.MustCompile(TIME_FORMAT)
 	if !re.Match([]byte(s)) {
 		return 0, fmt.Errorf("invalid time string")
@@ -174,7 +338,102 @@ func stringToTime(s string) (float32, error) {
 	if err != nil || (min < 0 || min > 59) {
 		return 0, fmt.Errorf("invalid minutes")
 	}
-	return float32(hour) + (float32(min) / 60.0), nil
+	return time.Duration(hour)*time.Hour + time.Duration(min)*time.Minute, nil
+}
+
+// Convert a duration string of the form 8:30 or 8.5 to a duration
+func stringToDuration(s string) (time.Duration, error) {
+	tok := strings.Split(s, ":")
+	if len(tok) == 2 {
+		hour, err := strconv.Atoi(tok[0])
+		if err != nil || hour < 0 {
+			return 0, fmt.Errorf("invalid hours")
+		}
+		min, err := strconv.Atoi(tok[1])
+		if err != nil || (min < 0 || min > 59) {
+			return 0, fmt.Errorf("invalid minutes")
+		}
+		return time.Duration(hour)*time.Hour + time.Duration(min)*time.Minute, nil
+	}
+	hours, err := strconv.ParseFloat(s, 64)
+	if err != nil || hours < 0 {
+		return 0, fmt.Errorf("invalid duration string")
+	}
+	return time.Duration(math.Round(hours*60)) * time.Minute, nil
+}
+
+// requestTimeZone asks the user to enter the time zone of a task, which is empty if left blank
+func requestTimeZone() (string, error) {
+	input := bufio.NewScanner(os.Stdin)
+	fmt.Print("Enter time zone (eg. America/Los_Angeles) or leave blank for the default: ")
+	input.Scan()
+	zone := strings.TrimSpace(input.Text())
+	if _, err := time.LoadLocation(zone); err != nil {
+		return "", fmt.Errorf("unknown time zone %q", zone)
+	}
+	return zone, nil
+}
+
+// requestMonth asks the user to enter a month and returns the span of time it covers in a time zone
+func requestMonth(input *bufio.Scanner, loc *time.Location) (time.Time, time.Time, error) {
+	fmt.Print("Enter a month (eg. 2020-11): ")
+	input.Scan()
+	start, err := time.ParseInLocation(MONTH_LAYOUT, strings.TrimSpace(input.Text()), loc)
+	if err != nil {
+		return start, start, fmt.Errorf("bad month entered")
+	}
+	return start, start.AddDate(0, 1, 0), nil
+}
+
+// requestDate asks the user to enter a date and returns the start of that day in a time zone
+func requestDate(input *bufio.Scanner, loc *time.Location) (time.Time, error) {
+	fmt.Print("Enter a date (eg. 2020-11-14): ")
+	input.Scan()
+	date, err := time.ParseInLocation(DATE_LAYOUT, strings.TrimSpace(input.Text()), loc)
+	if err != nil {
+		return date, fmt.Errorf("bad date entered")
+	}
+	return date, nil
+}
+
+// parseWeekday converts the name of a day the week may begin on (eg. "Sunday" or "mon") to a weekday
+func parseWeekday(s string) (time.Weekday, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "sunday", "sun":
+		return time.Sunday, nil
+	case "monday", "mon":
+		return time.Monday, nil
+	}
+	return time.Sunday, fmt.Errorf("weeks can only begin on Sunday or Monday, not %q", s)
+}
+
+// displayTasks prints a list of tasks
+func displayTasks(tasks []model.Task) {
+	if len(tasks) == 0 {
+		fmt.Println("No tasks found")
+		return
+	}
+	fmt.Println(SEP_STRING)
+	for _, t := range tasks {
+		fmt.Println(t)
+		fmt.Println(SEP_STRING)
+	}
+}
+
+// parseCategories parses a comma separated list of CATEGORY=TYPE pairs (eg. "Lecture=Class, Meeting=Appointment")
+func parseCategories(s string) (map[string]string, error) {
+	result := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		tok := strings.SplitN(pair, "=", 2)
+		if len(tok) != 2 || strings.TrimSpace(tok[0]) == "" || strings.TrimSpace(tok[1]) == "" {
+			return nil, fmt.Errorf("expected CATEGORY=TYPE, got %q", strings.TrimSpace(pair))
+		}
+		result[strings.TrimSpace(tok[0])] = strings.TrimSpace(tok[1])
+	}
+	return result, nil
 }
 
 //!--
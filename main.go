@@ -3,12 +3,22 @@
 package main
 
 import (
+	"fmt"
+	"os"
+
 	"github.com/hlin91/CS3560_Scheduler_Backup/controller"
-	"github.com/hlin91/CS3560_Scheduler_Backup/model"
 )
 
 func main() {
-	s := model.NewSchedule()       // Create the schedule "Model"
+	if len(os.Args) > 1 {
+		// Run a single non-interactive subcommand (eg. "pss add transient ...")
+		os.Exit(controller.RunCommand(os.Args[1:]))
+	}
+	s, err := controller.NewSchedule() // Create the schedule "Model"
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", controller.PROGRAM_NAME, err)
+		os.Exit(controller.EXIT_FILE)
+	}
 	menu := controller.MakeMenu(s) // Create the menu "Controller"
 	menu.Run()
 }
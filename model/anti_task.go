@@ -2,53 +2,231 @@
 // anti_task.go provides an implementation for anti tasks
 package model
 
-import "math"
+import (
+	"fmt"
+	"time"
+)
 
 // AntiTask implements an anti task in the schedule
+// An anti task cancels the one subtask with its start time and duration, unless it has an EndDate, in which
+// case it cancels every subtask starting on the dates from Date to EndDate of the recurring task with the ID
+// Series, or of every recurring task if Series is empty, and its start time and duration are not used
+// A recurring anti task has a Frequency as well and cancels the subtask it lines up with, then every
+// Frequency-th subtask of the same recurring task after it up to EndDate
 type AntiTask struct {
 	Task
+	EndDate   int    // Last date of the range of a range or recurring anti task
+	Series    string // ID of the recurring task a range anti task cancels the subtasks of
+	Frequency int    // Number of subtasks from one cancelled subtask to the next of a recurring anti task
+	Holiday   string // Holiday an anti task was generated for, empty for anti tasks made by hand
 }
 
-func NewAntiTask(name, taskType string, date int, startTime, duration float32) (AntiTask, error) {
+func NewAntiTask(name, taskType string, date int, startTime, duration time.Duration) (AntiTask, error) {
 	t, err := NewTask(name, taskType, date, startTime, duration)
 	if err != nil {
 		return AntiTask{}, err
 	}
-	return AntiTask{t}, nil
+	return AntiTask{Task: t}, nil
+}
+
+func (a AntiTask) String() string {
+	if a.Holiday != "" {
+		return fmt.Sprintf("%v\nHoliday: %v", a.Task.String(), a.Holiday)
+	}
+	if a.IsRecurring() {
+		return fmt.Sprintf("%v\nCancels: %v until %v", a.Task.String(), everyNth(a.Frequency), dateIntToString(a.EndDate))
+	}
+	if !a.IsRange() {
+		return a.Task.String()
+	}
+	series := "every recurring task"
+	if a.Series != "" {
+		series = fmt.Sprintf("the recurring task with ID %v", a.Series)
+	}
+	return fmt.Sprintf("ID: %v\nName: %v\nType: %v\nCancels: %v from %v to %v", a.ID, a.Name, a.Type, series, dateIntToString(a.Date), dateIntToString(a.EndDate))
+}
+
+// everyNth describes which subtasks a recurring anti task cancels
+func everyNth(frequency int) string {
+	switch frequency {
+	case 1:
+		return "every occurrence"
+	case 2:
+		return "every other occurrence"
+	}
+	return fmt.Sprintf("every %d occurrences", frequency)
+}
+
+// IsRange checks if the anti task cancels a range of dates rather than a single subtask
+func (a AntiTask) IsRange() bool {
+	return a.EndDate != 0 && a.Frequency == 0
+}
+
+// IsRecurring checks if the anti task cancels a regular subset of the subtasks of a recurring task
+func (a AntiTask) IsRecurring() bool {
+	return a.Frequency != 0
+}
+
+// withDetails returns the anti task with the details of a task, keeping its end date, recurring task, frequency
+// and holiday
+func (a AntiTask) withDetails(t Task) AntiTask {
+	a.Task = t
+	return a
+}
+
+// checkRange checks the range of a range anti task and clears the start time and duration it does not use
+func (a AntiTask) checkRange() (AntiTask, error) {
+	if err := a.checkEndDate(); err != nil {
+		return a, err
+	}
+	a.StartTime, a.Duration = 0, 0
+	return a, nil
+}
+
+// checkRecurrence checks the end date and frequency of a recurring anti task
+func (a AntiTask) checkRecurrence() error {
+	if a.Frequency < 1 {
+		return invalidf("frequency must be at least 1")
+	}
+	if a.Series != "" {
+		return invalidf("a recurring anti task cancels the recurring task it lines up with and cannot name one")
+	}
+	return a.checkEndDate()
+}
+
+// checkEndDate checks that the end date of a range or recurring anti task is a date on or after its start date
+func (a AntiTask) checkEndDate() error {
+	start, err := intToDate(a.Date)
+	if err != nil {
+		return invalidf("bad date")
+	}
+	end, err := intToDate(a.EndDate)
+	if err != nil || a.EndDate > MAX_DATE {
+		return invalidf("bad end date")
+	}
+	if end.Before(start) {
+		return invalidf("end date before start date")
+	}
+	return nil
+}
+
+// days returns the first and last day, counted from the Unix epoch, on which the anti task may cancel a subtask
+// A range or recurring anti task covers a day either side of its dates as subtasks are dated in the time zone
+// of their recurring task
+func (a AntiTask) days() (int64, int64) {
+	if !a.IsRange() && !a.IsRecurring() {
+		return taskDays(a.Task)
+	}
+	start, _ := intToDate(a.Date)
+	end, _ := intToDate(a.EndDate)
+	return epochDay(start) - 1, epochDay(end) + 1
 }
 
 // Cancels determines if this anti task cancels out another task
+// Recurring anti tasks need the recurring task of the other task, see CancelsSubtask
 func (a AntiTask) Cancels(t Task) bool {
+	if a.IsRecurring() {
+		return false
+	}
+	if a.IsRange() {
+		return (a.Series == "" || a.Series == t.ID) && t.Date >= a.Date && t.Date <= a.EndDate
+	}
 	date1, _ := a.GetStartDate()
 	date2, _ := t.GetStartDate()
 	if date2.Before(date1) {
 		// Cannot cancel
 		return false
 	}
-	// Difference in start time in hours
-	timeDelta := float32(math.Abs(float64(date1.Unix()-date2.Unix())) / (60 * 60))
+	// Difference in start time
+	timeDelta := date2.Sub(date1)
 	return a.Duration >= timeDelta+t.Duration
 }
 
+// CancelsSubtask determines if this anti task cancels out a subtask of a recurring task
+func (a AntiTask) CancelsSubtask(r RecurringTask, t Task) bool {
+	if !a.IsRecurring() {
+		return a.Cancels(t)
+	}
+	if t.ID != r.ID || t.Date > a.EndDate {
+		return false
+	}
+	first, ok := a.firstCancelled(r)
+	if !ok || t.Date < first.Date {
+		return false
+	}
+	firstDate, _ := intToDate(first.Date)
+	date, err := intToDate(t.Date)
+	if err != nil {
+		return false
+	}
+	return (r.subtaskIndex(epochDay(date))-r.subtaskIndex(epochDay(firstDate)))%int64(a.Frequency) == 0
+}
+
 // GetCancelledSubtask returns the subtask this anti task cancels and a bool to indicate if such a task was found
+// Range and recurring anti tasks cancel no single subtask, see GetCancelledSubtasks
 func (a AntiTask) GetCancelledSubtask(r RecurringTask) (Task, bool) {
-	aStart, _ := a.GetStartDate()
-	rStart, _ := r.GetStartDate()
-	rEnd, _ := r.GetEndDate()
-	if aStart.Before(rStart) || rEnd.Before(aStart) {
-		// This anti task is outside of the recurring range
+	if a.IsRecurring() {
 		return Task{}, false
 	}
-	if a.StartTime != r.StartTime || a.Duration != r.Duration {
+	return a.firstCancelled(r)
+}
+
+// firstCancelled returns the subtask a single or recurring anti task lines up with, which is the first subtask a
+// recurring anti task cancels, and a bool to indicate if such a task was found
+func (a AntiTask) firstCancelled(r RecurringTask) (Task, bool) {
+	if a.IsRange() {
+		return Task{}, false
+	}
+	aStart, err := a.GetStartDate()
+	if err != nil {
+		return Task{}, false
+	}
+	// Determine if the anti task lines up with a subtask, counting days in the recurring task's time zone
+	day := epochDay(aStart.In(r.Location()))
+	if len(r.subtaskDays(day, day)) == 0 {
+		// This anti task is outside of the recurring range or between subtasks
+		return Task{}, false
+	}
+	t := r.subtaskOn(day)
+	if tStart, _ := t.GetStartDate(); !tStart.Equal(aStart) || a.Duration != r.Duration {
 		// Start time or duration does not match up
 		return Task{}, false
 	}
-	// Determine if the anti task lines up with the recurrence cycle
-	if dayDelta := int64(aStart.Sub(rStart).Hours()); dayDelta%int64(r.Frequency) == 0 {
-		t, _ := NewTask(r.Name, r.Type, a.Date, a.StartTime, a.Duration)
-		return t, true
+	return t, true
+}
+
+// GetCancelledSubtasks returns the subtasks of a recurring task this anti task cancels
+func (a AntiTask) GetCancelledSubtasks(r RecurringTask) []Task {
+	result := []Task{}
+	if a.IsRecurring() {
+		first, ok := a.firstCancelled(r)
+		if !ok {
+			return result
+		}
+		start, _ := intToDate(first.Date)
+		end, _ := intToDate(a.EndDate)
+		for i, d := range r.subtaskDays(epochDay(start), epochDay(end)) {
+			if i%a.Frequency == 0 {
+				result = append(result, r.subtaskOn(d))
+			}
+		}
+		return result
+	}
+	if !a.IsRange() {
+		if t, ok := a.GetCancelledSubtask(r); ok {
+			result = append(result, t)
+		}
+		return result
+	}
+	if a.Series != "" && a.Series != r.ID {
+		return result
+	}
+	start, _ := intToDate(a.Date)
+	end, _ := intToDate(a.EndDate)
+	for _, d := range r.subtaskDays(epochDay(start), epochDay(end)) {
+		result = append(result, r.subtaskOn(d))
 	}
-	return Task{}, false
+	return result
 }
 
 //!--
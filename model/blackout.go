@@ -0,0 +1,242 @@
+// Package model provides functionality for creating and managing a schedule of tasks
+// blackout.go provides blackout periods in which nothing may be scheduled
+package model
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	// Type of the blackout entries of a json task list
+	BLACKOUT = "Blackout"
+	// Policies for the subtasks of recurring tasks that fall in a blackout period
+	BLACKOUT_REJECT = "Reject" // Recurring tasks with a subtask in the period are rejected
+	BLACKOUT_SKIP   = "Skip"   // Subtasks in the period are skipped as if cancelled
+)
+
+// Blackout is a period in which nothing may be scheduled (eg. a campus closure)
+// The period runs from StartTime on Date up to EndTime on EndDate in its time zone, and no transient task or
+// override may overlap it, while the subtasks of recurring tasks that overlap it are dealt with by its Policy
+type Blackout struct {
+	Name      string
+	Date      int
+	StartTime time.Duration
+	EndDate   int
+	EndTime   time.Duration
+	TimeZone  string
+	Policy    string
+}
+
+func (b Blackout) String() string {
+	return fmt.Sprintf("Name: %v\nType: %v\nFrom: %v %v\nUntil: %v %v\nPolicy: %v", b.Name, BLACKOUT, dateIntToString(b.Date),
+		formatClock(b.StartTime), dateIntToString(b.EndDate), formatClock(b.EndTime), b.Policy)
+}
+
+// start gets the time the blackout period begins
+func (b Blackout) start() time.Time {
+	t, _ := Task{Date: b.Date, StartTime: b.StartTime, TimeZone: b.TimeZone}.GetStartDate()
+	return t
+}
+
+// end gets the time the blackout period ends
+func (b Blackout) end() time.Time {
+	t, _ := Task{Date: b.EndDate, StartTime: b.EndTime, TimeZone: b.TimeZone}.GetStartDate()
+	return t
+}
+
+// days returns the first and last day, counted from the Unix epoch, that the blackout period touches
+func (b Blackout) days() (int64, int64) {
+	return floorDiv(b.start().Unix(), SECONDS_PER_DAY), floorDiv(b.end().Unix(), SECONDS_PER_DAY)
+}
+
+// overlaps returns true if a task overlaps the blackout period
+func (b Blackout) overlaps(t Task) bool {
+	return t.overlapsSpan(b.start(), b.end())
+}
+
+// check checks the values of a blackout period
+func (b Blackout) check() error {
+	if b.Name == "" {
+		return invalidf("name cannot be empty")
+	}
+	if _, err := intToDate(b.Date); err != nil || b.Date > MAX_DATE {
+		return invalidf("bad date")
+	}
+	if _, err := intToDate(b.EndDate); err != nil || b.EndDate > MAX_DATE {
+		return invalidf("bad end date")
+	}
+	for _, t := range []time.Duration{b.StartTime, b.EndTime} {
+		if t < 0 || t >= 24*time.Hour {
+			return invalidf("bad time %v", formatClock(t))
+		}
+	}
+	if _, err := loadLocation(b.TimeZone); err != nil {
+		return invalidf("unknown time zone %q", b.TimeZone)
+	}
+	if !b.end().After(b.start()) {
+		return invalidf("blackout period ends before it begins")
+	}
+	if b.Policy != BLACKOUT_REJECT && b.Policy != BLACKOUT_SKIP {
+		return invalidf("policy must be %q or %q", BLACKOUT_REJECT, BLACKOUT_SKIP)
+	}
+	return nil
+}
+
+// blackoutError reports the blackout period a task falls in
+func blackoutError(method string, b Blackout) error {
+	return fmt.Errorf("%s: %w %q", method, ErrBlackout, b.Name)
+}
+
+// blackoutOver returns a blackout period that a task overlaps and a bool to indicate if one was found
+func (ts taskSet) blackoutOver(t Task) (Blackout, bool) {
+	for _, b := range ts.blackouts {
+		if b.overlaps(t) {
+			return b, true
+		}
+	}
+	return Blackout{}, false
+}
+
+// skipped checks if a subtask falls in a blackout period that skips subtasks
+func (ts taskSet) skipped(sub Task) bool {
+	for _, b := range ts.blackouts {
+		if b.Policy == BLACKOUT_SKIP && b.overlaps(sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// rejectedBy returns a blackout period that rejects a subtask of a recurring task and a bool to indicate if one
+// was found, leaving out subtasks that are hidden
+func (ts taskSet) rejectedBy(r RecurringTask) (Blackout, bool) {
+	for _, b := range ts.blackouts {
+		if b.Policy != BLACKOUT_REJECT {
+			continue
+		}
+		// Subtasks are dated in the time zone of their recurring task and may start the day before
+		first, last := b.days()
+		found := false
+		r.eachSubtask(first-2, last+1, func(sub Task) bool {
+			found = b.overlaps(sub) && !ts.hidden(sub)
+			return !found
+		})
+		if found {
+			return b, true
+		}
+	}
+	return Blackout{}, false
+}
+
+// Blackout gets a blackout period by name
+func (ts taskSet) Blackout(name string) (Blackout, bool) {
+	b, ok := ts.blackouts[name]
+	return b, ok
+}
+
+// Blackouts gets all blackout periods sorted by name
+func (ts taskSet) Blackouts() []Blackout {
+	result := make([]Blackout, 0, len(ts.blackouts))
+	for _, b := range ts.blackouts {
+		result = append(result, b)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// Blackout gets a blackout period by name
+func (s *Schedule) Blackout(name string) (Blackout, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.taskSet.Blackout(name)
+}
+
+// Blackouts gets all blackout periods sorted by name
+func (s *Schedule) Blackouts() []Blackout {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.taskSet.Blackouts()
+}
+
+// AddBlackout adds a blackout period to the schedule
+// A blackout period without a time zone is given the schedule's and one without a policy rejects recurring tasks
+// It must not overlap a transient task or override, nor, if it rejects recurring tasks, a subtask
+func (s *Schedule) AddBlackout(b Blackout) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.atomically(fmt.Sprintf("add blackout %q", b.Name), func() error {
+		return s.addBlackout(b)
+	})
+}
+
+func (s *Schedule) addBlackout(b Blackout) error {
+	if _, ok := s.blackouts[b.Name]; ok {
+		return fmt.Errorf("AddBlackout: %w", ErrNameTaken)
+	}
+	if b.TimeZone == "" {
+		b.TimeZone = zoneName(s.location)
+	}
+	if b.Policy == "" {
+		b.Policy = BLACKOUT_REJECT
+	}
+	if err := b.check(); err != nil {
+		return fmt.Errorf("AddBlackout: %w", err)
+	}
+	first, last := b.days()
+	for _, id := range s.transientDays.between(first, last) {
+		if t := s.transientTasks[id]; b.overlaps(t) {
+			return fmt.Errorf("AddBlackout: blackout period overlaps %q, a %w", t.Name, ErrConflict)
+		}
+	}
+	for _, key := range s.overrideDays.between(first, last) {
+		if o := s.overrides[key]; b.overlaps(o.Task) {
+			return fmt.Errorf("AddBlackout: blackout period overlaps %q on %v, a %w", o.Name, dateIntToString(o.Date), ErrConflict)
+		}
+	}
+	s.putBlackout(b)
+	for _, r := range s.recurringTasks {
+		if _, ok := s.rejectedBy(r); ok {
+			return fmt.Errorf("AddBlackout: blackout period overlaps %q, a %w", r.Name, ErrConflict)
+		}
+	}
+	return nil
+}
+
+// DeleteBlackout deletes a blackout period from the schedule
+// Deleting a blackout period that skips subtasks is refused if a subtask it brings back conflicts with another task
+func (s *Schedule) DeleteBlackout(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.atomically(fmt.Sprintf("delete blackout %q", name), func() error {
+		return s.deleteBlackout(name)
+	})
+}
+
+func (s *Schedule) deleteBlackout(name string) error {
+	b, ok := s.blackouts[name]
+	if !ok {
+		return fmt.Errorf("DeleteBlackout: %w", ErrNotFound)
+	}
+	s.removeBlackout(name)
+	if b.Policy != BLACKOUT_SKIP {
+		return nil
+	}
+	first, last := b.days()
+	for _, r := range s.recurringTasks {
+		conflict := false
+		r.eachSubtask(first-2, last+1, func(sub Task) bool {
+			conflict = b.overlaps(sub) && !s.hidden(sub) && s.hasConflictReplacing(sub, overrideKey(sub.ID, sub.Date))
+			return !conflict
+		})
+		if conflict {
+			return fmt.Errorf("DeleteBlackout: bringing back the subtasks of %q creates a %w", r.Name, ErrConflict)
+		}
+	}
+	return nil
+}
+
+//!--
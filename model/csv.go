@@ -0,0 +1,357 @@
+// Package model provides functionality for creating and managing a schedule of tasks
+// csv.go provides importing and exporting of task lists as CSV spreadsheets
+package model
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Accepted formats of CSV cells
+	CSV_DATE_FORMAT     = `^(\d{4})-(\d{1,2})-(\d{1,2})$` // eg. 2020-04-28
+	CSV_TIME_FORMAT     = `^(\d{1,2}):(\d{2})$`           // eg. 17:30
+	CSV_DURATION_FORMAT = `^(\d+):(\d{2})$`               // eg. 2:30 or 32:00
+)
+
+// csvHeader is the header row written by WriteCSV
+var csvHeader = []string{NAME_KEY, TYPE_KEY, DATE_KEY, START_TIME_KEY, DURATION_KEY, END_DATE_KEY, FREQUENCY_KEY, TIME_ZONE_KEY,
+	WEEKDAYS_KEY, MONTH_DAY_KEY, MONTH_WEEK_KEY, COUNT_KEY, SERIES_KEY, HOLIDAY_KEY}
+
+// WriteCSV writes all tasks in the schedule to a specified file in CSV format
+// Recurring tasks fill in the Frequency column, the EndDate or Count column of a series that ends, and the
+// Weekdays, MonthDay and MonthWeek columns of their rule, which are left empty for other tasks
+// Range anti tasks fill in the EndDate column and the Series column if they name a recurring task, and
+// recurring anti tasks fill in the EndDate and Frequency columns, and anti tasks generated for holidays fill in
+// the Holiday column
+// Overrides of recurring tasks and blackout periods have no columns and are not written
+// Rows carry no IDs, so writing fails if a range anti task cancels a recurring task whose name another has
+func (s *Schedule) WriteCSV(path string) error {
+	s.mu.RLock()
+	rows, err := s.csvRows()
+	s.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("WriteCSV: %w", err)
+	}
+	if err := writeCSV(path, rows); err != nil {
+		return fmt.Errorf("WriteCSV: %v", err)
+	}
+	return nil
+}
+
+// WriteTaskListCSV writes a list of tasks into a specified file in CSV format
+func (s *Schedule) WriteTaskListCSV(path string, tasks []Task) error {
+	rows := [][]string{}
+	for _, t := range tasks {
+		rows = append(rows, taskToRow(t))
+	}
+	if err := writeCSV(path, rows); err != nil {
+		return fmt.Errorf("WriteTaskListCSV: %v", err)
+	}
+	return nil
+}
+
+// LoadCSV loads the tasks of the CSV file at the specified path into the schedule
+// The first row must be a header naming the Name, Type, Date (or StartDate), StartTime and Duration
+// columns, with optional EndDate, Frequency, Weekdays, MonthDay, MonthWeek and Count columns for recurring tasks
+// and optional TimeZone and Series columns, the latter with EndDate giving the range of range anti tasks
+// Dates may be written as 2020-04-28 or 20200428, times as 17:30 or 17.5 and durations as 2:30 or 2.5
+// Durations are written as 2:30
+// Either all of the tasks are added or the schedule is left unchanged
+func (s *Schedule) LoadCSV(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("LoadCSV: error reading file %q: %v", path, err)
+	}
+	defer f.Close()
+	batch, err := parseCSV(f, s.Types())
+	if err != nil {
+		return fmt.Errorf("LoadCSV: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.addBatch(batch); err != nil {
+		return fmt.Errorf("LoadCSV: error loading tasks: %w", err)
+	}
+	return nil
+}
+
+// csvRows converts all tasks in the set to CSV rows
+func (ts taskSet) csvRows() ([][]string, error) {
+	rows := [][]string{}
+	for _, t := range ts.transientTasks {
+		rows = append(rows, taskToRow(t))
+	}
+	for _, a := range ts.antiTasks {
+		row := taskToRow(a.Task)
+		if a.IsRange() || a.IsRecurring() {
+			row[5] = dateIntToString(a.EndDate)
+		}
+		if a.Series != "" {
+			// Rows carry no IDs, so the recurring task is given by a name no other recurring task has
+			name := ts.recurringTasks[a.Series].Name
+			if id, err := ts.resolve(name, ts.isRecurring); err != nil || id != a.Series {
+				return nil, fmt.Errorf("anti task %q cancels %q: %w", a.Name, name, ErrAmbiguous)
+			}
+			row[12] = name
+		}
+		if a.IsRecurring() {
+			row[6] = strconv.Itoa(a.Frequency)
+		}
+		row[13] = a.Holiday
+		rows = append(rows, row)
+	}
+	for _, r := range ts.recurringTasks {
+		rows = append(rows, recurToRow(r))
+	}
+	return rows, nil
+}
+
+// writeCSV writes the header and rows to the file at path
+func writeCSV(path string, rows [][]string) error {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write(csvHeader)
+	w.WriteAll(rows)
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("error encoding csv: %v", err)
+	}
+	return writeFile(path, buf.Bytes())
+}
+
+// taskToRow converts a task to a CSV row
+func taskToRow(t Task) []string {
+	return []string{t.Name, t.Type, dateIntToString(t.Date), formatClock(t.StartTime), formatHours(t.Duration), "", "", t.TimeZone, "", "", "", "", "", ""}
+}
+
+// recurToRow converts a recurring task to a CSV row
+func recurToRow(r RecurringTask) []string {
+	row := taskToRow(r.Task)
+	if r.EndDate != 0 {
+		row[5] = dateIntToString(r.EndDate)
+	}
+	row[6] = strconv.Itoa(r.Frequency)
+	row[8] = r.Weekdays.String()
+	if r.MonthDay != 0 {
+		row[9] = strconv.Itoa(r.MonthDay)
+	}
+	if r.MonthWeek != 0 {
+		row[10] = strconv.Itoa(r.MonthWeek)
+	}
+	if r.Count != 0 {
+		row[11] = strconv.Itoa(r.Count)
+	}
+	return row
+}
+
+// parseCSV converts the rows of a CSV file into a batch of tasks, telling the kinds of tasks apart by the types of
+// a registry
+func parseCSV(r io.Reader, types *TypeRegistry) (taskBatch, error) {
+	var batch taskBatch
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return batch, invalidf("error reading csv: %v", err)
+	}
+	if len(records) == 0 {
+		return batch, invalidf("error parsing csv: missing header row")
+	}
+	columns, err := csvColumns(records[0])
+	if err != nil {
+		return batch, err
+	}
+	for i, record := range records[1:] {
+		if err := addCSVRow(&batch, record, columns, types); err != nil {
+			// Rows are numbered as in a spreadsheet, counting the header
+			return batch, invalidf("error parsing csv: row %d: %v", i+2, err)
+		}
+	}
+	return batch, nil
+}
+
+// csvColumns maps the known column names onto their index in the header row
+func csvColumns(header []string) (map[string]int, error) {
+	columns := map[string]int{}
+	for i, h := range header {
+		key := strings.ToLower(strings.Join(strings.Fields(h), ""))
+		found := false
+		for _, name := range csvHeader {
+			if key == strings.ToLower(name) {
+				columns[name] = i
+				found = true
+			}
+		}
+		if key == strings.ToLower(START_DATE_KEY) {
+			// StartDate is accepted as a name for the Date column, as in the json format
+			columns[DATE_KEY] = i
+			found = true
+		}
+		if !found {
+			return nil, invalidf("error parsing csv: unknown column %q", h)
+		}
+	}
+	for _, name := range []string{NAME_KEY, TYPE_KEY, DATE_KEY, START_TIME_KEY, DURATION_KEY} {
+		if _, ok := columns[name]; !ok {
+			return nil, invalidf("error parsing csv: missing %q column", name)
+		}
+	}
+	return columns, nil
+}
+
+// addCSVRow converts a CSV row into a task and adds it to the batch
+func addCSVRow(batch *taskBatch, record []string, columns map[string]int, types *TypeRegistry) error {
+	cell := func(name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	date, err := parseDate(cell(DATE_KEY))
+	if err != nil {
+		return fmt.Errorf("bad %s: %v", DATE_KEY, err)
+	}
+	startTime, err := parseClock(cell(START_TIME_KEY))
+	if err != nil {
+		return fmt.Errorf("bad %s: %v", START_TIME_KEY, err)
+	}
+	duration, err := parseHours(cell(DURATION_KEY))
+	if err != nil {
+		return fmt.Errorf("bad %s: %v", DURATION_KEY, err)
+	}
+	task := Task{Name: cell(NAME_KEY), Type: cell(TYPE_KEY), Date: date, StartTime: startTime, Duration: duration, TimeZone: cell(TIME_ZONE_KEY)}
+	if types.is(task.Type, ANTI) && (cell(END_DATE_KEY) != "" || cell(SERIES_KEY) != "" || cell(FREQUENCY_KEY) != "") {
+		// A range or recurring anti task
+		a := AntiTask{Task: task, Series: cell(SERIES_KEY), Holiday: cell(HOLIDAY_KEY)}
+		if a.EndDate, err = parseDate(cell(END_DATE_KEY)); err != nil {
+			return fmt.Errorf("bad %s: %v", END_DATE_KEY, err)
+		}
+		if cell(FREQUENCY_KEY) != "" {
+			if a.Frequency, err = strconv.Atoi(cell(FREQUENCY_KEY)); err != nil {
+				return fmt.Errorf("bad %s %q", FREQUENCY_KEY, cell(FREQUENCY_KEY))
+			}
+		}
+		batch.anti = append(batch.anti, a)
+		return nil
+	}
+	if cell(END_DATE_KEY) != "" || cell(FREQUENCY_KEY) != "" || cell(WEEKDAYS_KEY) != "" || cell(MONTH_DAY_KEY) != "" || cell(MONTH_WEEK_KEY) != "" || cell(COUNT_KEY) != "" {
+		// A recurring task, which has no end date if it ends after a count or never
+		endDate := 0
+		if cell(END_DATE_KEY) != "" {
+			if endDate, err = parseDate(cell(END_DATE_KEY)); err != nil {
+				return fmt.Errorf("bad %s: %v", END_DATE_KEY, err)
+			}
+		}
+		frequency, err := strconv.Atoi(cell(FREQUENCY_KEY))
+		if err != nil {
+			return fmt.Errorf("bad %s %q", FREQUENCY_KEY, cell(FREQUENCY_KEY))
+		}
+		r := RecurringTask{Task: task, EndDate: endDate, Frequency: frequency}
+		if err := r.Weekdays.UnmarshalText([]byte(cell(WEEKDAYS_KEY))); err != nil {
+			return fmt.Errorf("bad %s: %v", WEEKDAYS_KEY, err)
+		}
+		if r.MonthDay, err = optionalInt(cell(MONTH_DAY_KEY)); err != nil {
+			return fmt.Errorf("bad %s %q", MONTH_DAY_KEY, cell(MONTH_DAY_KEY))
+		}
+		if r.MonthWeek, err = optionalInt(cell(MONTH_WEEK_KEY)); err != nil {
+			return fmt.Errorf("bad %s %q", MONTH_WEEK_KEY, cell(MONTH_WEEK_KEY))
+		}
+		if r.Count, err = optionalInt(cell(COUNT_KEY)); err != nil {
+			return fmt.Errorf("bad %s %q", COUNT_KEY, cell(COUNT_KEY))
+		}
+		batch.recurring = append(batch.recurring, r)
+		return nil
+	}
+	switch {
+	case types.is(task.Type, TRANSIENT):
+		batch.transient = append(batch.transient, task)
+	case types.is(task.Type, ANTI):
+		batch.anti = append(batch.anti, AntiTask{Task: task, Holiday: cell(HOLIDAY_KEY)})
+	case types.is(task.Type, RECURRING):
+		batch.subtasks = append(batch.subtasks, task)
+	default:
+		return fmt.Errorf("bad type found: %q", task.Type)
+	}
+	return nil
+}
+
+// optionalInt converts a cell that may be left empty to an integer, which is 0 if empty
+func optionalInt(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(s)
+}
+
+// parseDate converts a date of the form 2020-04-28 or 20200428 to an integer date
+func parseDate(s string) (int, error) {
+	if m := regexp.MustCompile(CSV_DATE_FORMAT).FindStringSubmatch(s); m != nil {
+		year, _ := strconv.Atoi(m[1])
+		month, _ := strconv.Atoi(m[2])
+		day, _ := strconv.Atoi(m[3])
+		s = fmt.Sprintf("%04d%02d%02d", year, month, day)
+	}
+	date, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a date", s)
+	}
+	if _, err := intToDate(date); err != nil {
+		return 0, fmt.Errorf("%q is not a date", s)
+	}
+	return date, nil
+}
+
+// parseClock converts a time of day of the form 17:30 or 17.5 to a time since midnight
+func parseClock(s string) (time.Duration, error) {
+	if m := regexp.MustCompile(CSV_TIME_FORMAT).FindStringSubmatch(s); m != nil {
+		hour, _ := strconv.Atoi(m[1])
+		min, _ := strconv.Atoi(m[2])
+		if hour > 23 || min > 59 {
+			return 0, fmt.Errorf("%q is not a time", s)
+		}
+		return time.Duration(hour)*time.Hour + time.Duration(min)*time.Minute, nil
+	}
+	hours, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a time", s)
+	}
+	return floatHours(hours), nil
+}
+
+// parseHours converts a duration of the form 2:30 or 2.5 to a duration
+func parseHours(s string) (time.Duration, error) {
+	if m := regexp.MustCompile(CSV_DURATION_FORMAT).FindStringSubmatch(s); m != nil {
+		hour, _ := strconv.Atoi(m[1])
+		min, _ := strconv.Atoi(m[2])
+		if min > 59 {
+			return 0, fmt.Errorf("%q is not a duration", s)
+		}
+		return time.Duration(hour)*time.Hour + time.Duration(min)*time.Minute, nil
+	}
+	hours, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a duration", s)
+	}
+	return floatHours(hours), nil
+}
+
+// formatClock converts a time since midnight to a time of day of the form 17:30
+func formatClock(d time.Duration) string {
+	minutes := int(d / time.Minute)
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
+
+// formatHours converts a duration to the form 2:30
+func formatHours(d time.Duration) string {
+	minutes := int(d / time.Minute)
+	return fmt.Sprintf("%d:%02d", minutes/60, minutes%60)
+}
+
+//!--
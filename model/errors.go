@@ -0,0 +1,44 @@
+// Package model provides functionality for creating and managing a schedule of tasks
+// errors.go provides errors that callers can test for with errors.Is
+package model
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrConflict is returned when a change would create a scheduling conflict
+	ErrConflict = errors.New("scheduling conflict")
+	// ErrNameTaken is returned when a task name is already in use
+	ErrNameTaken = errors.New("task name already exists")
+	// ErrNotFound is returned when a task name does not exist in the schedule
+	ErrNotFound = errors.New("task name does not exist in schedule")
+	// ErrIDTaken is returned when a task is given an ID that is already in use
+	ErrIDTaken = errors.New("task ID already exists")
+	// ErrAmbiguous is returned when a task is named by a name that more than one task has
+	ErrAmbiguous = errors.New("task name is shared by more than one task, use its ID")
+	// ErrBlackout is returned when a task would fall in a blackout period
+	ErrBlackout = errors.New("task falls in blackout period")
+	// ErrInvalid is matched by errors caused by bad task values (eg. type, date, time)
+	ErrInvalid = errors.New("invalid task")
+)
+
+// invalidError is an error caused by bad task values
+// It keeps its own message but matches ErrInvalid
+type invalidError string
+
+func (e invalidError) Error() string {
+	return string(e)
+}
+
+func (e invalidError) Is(target error) bool {
+	return target == ErrInvalid
+}
+
+// invalidf formats an error that matches ErrInvalid
+func invalidf(format string, a ...interface{}) error {
+	return invalidError(fmt.Sprintf(format, a...))
+}
+
+//!--
@@ -0,0 +1,92 @@
+// Package model provides functionality for creating and managing a schedule of tasks
+// history.go provides undo and redo of the operations made on a schedule
+package model
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	// Maximum number of operations kept for undo
+	MAX_HISTORY = 100
+)
+
+var (
+	// ErrNothingToUndo is returned by Undo when there is no operation to undo
+	ErrNothingToUndo = errors.New("nothing to undo")
+	// ErrNothingToRedo is returned by Redo when there is no operation to redo
+	ErrNothingToRedo = errors.New("nothing to redo")
+)
+
+// operation is a completed change to the schedule that can be undone and redone
+// It holds every change to the task maps the operation made, so undoing a deletion of a recurring
+// task also restores the anti tasks deleted with it
+type operation struct {
+	desc    string
+	changes []change
+}
+
+// Undo reverts the most recent operation on the schedule and returns its description
+// Operations are additions, edits, deletions, file loads and committed transactions
+func (s *Schedule) Undo() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.undoStack) == 0 {
+		return "", fmt.Errorf("Undo: %w", ErrNothingToUndo)
+	}
+	op := s.undoStack[len(s.undoStack)-1]
+	s.undoStack = s.undoStack[:len(s.undoStack)-1]
+	for i := len(op.changes) - 1; i >= 0; i-- {
+		op.changes[i].undo()
+	}
+	s.redoStack = append(s.redoStack, op)
+	return op.desc, nil
+}
+
+// Redo reapplies the most recently undone operation and returns its description
+// Any new operation on the schedule clears the operations that can be redone
+func (s *Schedule) Redo() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.redoStack) == 0 {
+		return "", fmt.Errorf("Redo: %w", ErrNothingToRedo)
+	}
+	op := s.redoStack[len(s.redoStack)-1]
+	s.redoStack = s.redoStack[:len(s.redoStack)-1]
+	for _, c := range op.changes {
+		c.redo()
+	}
+	s.undoStack = append(s.undoStack, op)
+	return op.desc, nil
+}
+
+// CanUndo reports whether there is an operation to undo
+func (s *Schedule) CanUndo() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.undoStack) > 0
+}
+
+// CanRedo reports whether there is an operation to redo
+func (s *Schedule) CanRedo() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.redoStack) > 0
+}
+
+// record adds a completed operation to the history
+// Operations that did not change anything are not recorded
+func (s *Schedule) record(desc string, changes []change) {
+	if len(changes) == 0 {
+		return
+	}
+	op := operation{desc: desc, changes: append([]change{}, changes...)}
+	s.undoStack = append(s.undoStack, op)
+	if len(s.undoStack) > MAX_HISTORY {
+		s.undoStack = s.undoStack[len(s.undoStack)-MAX_HISTORY:]
+	}
+	s.redoStack = nil
+}
+
+//!--
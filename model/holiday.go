@@ -0,0 +1,204 @@
+// Package model provides functionality for creating and managing a schedule of tasks
+// holiday.go provides holiday calendars that generate anti tasks for the subtasks falling on holidays
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Holiday is a day off named by a holiday calendar
+type Holiday struct {
+	Name string
+	Date int
+}
+
+// ReadHolidays reads a holiday calendar from a file
+// A file ending in .ics is read as iCalendar, where every event is a holiday on each day it covers, and any
+// other file as a json list of holidays with a Name and a Date
+func ReadHolidays(path string) ([]Holiday, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ReadHolidays: error reading file %q: %v", path, err)
+	}
+	var holidays []Holiday
+	if strings.EqualFold(filepath.Ext(path), ".ics") {
+		holidays, err = parseICalHolidays(string(content))
+	} else if err = json.Unmarshal(content, &holidays); err != nil {
+		err = invalidf("error parsing json: %v", err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ReadHolidays: %w", err)
+	}
+	for _, h := range holidays {
+		if err := h.check(); err != nil {
+			return nil, fmt.Errorf("ReadHolidays: %w", err)
+		}
+	}
+	return holidays, nil
+}
+
+// parseICalHolidays converts the events of an iCalendar object into holidays
+func parseICalHolidays(content string) ([]Holiday, error) {
+	events, err := icalEvents(content)
+	if err != nil {
+		return nil, err
+	}
+	holidays := []Holiday{}
+	for i, e := range events {
+		summary, ok := e.get("SUMMARY")
+		if !ok || summary.value == "" {
+			return nil, invalidf("event #%d: missing SUMMARY", i+1)
+		}
+		name := icalUnescape(summary.value)
+		start, err := parseICalDay(e, "DTSTART")
+		if err != nil {
+			return nil, invalidf("event %q: %v", name, err)
+		}
+		// The end of an all-day event is the day after its last day
+		end := start + 1
+		if _, ok := e.get("DTEND"); ok {
+			if end, err = parseICalDay(e, "DTEND"); err != nil {
+				return nil, invalidf("event %q: %v", name, err)
+			}
+		}
+		if end <= start {
+			end = start + 1
+		}
+		for day := start; day < end; day++ {
+			holidays = append(holidays, Holiday{Name: name, Date: dateToInt(epochDate(day))})
+		}
+	}
+	return holidays, nil
+}
+
+// parseICalDay parses a DATE or DATE-TIME property of an event into a day counted from the Unix epoch
+func parseICalDay(e icalEvent, name string) (int64, error) {
+	p, ok := e.get(name)
+	if !ok {
+		return 0, fmt.Errorf("missing %s", name)
+	}
+	if p.params["VALUE"] == "DATE" || len(p.value) == len(ICAL_DATE_FORMAT) {
+		date, err := time.Parse(ICAL_DATE_FORMAT, p.value)
+		if err != nil {
+			return 0, fmt.Errorf("bad %s: %v", name, err)
+		}
+		return epochDay(date), nil
+	}
+	t, err := parseICalTime(p)
+	if err != nil {
+		return 0, fmt.Errorf("bad %s: %v", name, err)
+	}
+	return epochDay(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)), nil
+}
+
+// check checks that a holiday has a name and a valid date
+func (h Holiday) check() error {
+	if h.Name == "" {
+		return invalidf("holiday on %d has no name", h.Date)
+	}
+	if _, err := intToDate(h.Date); err != nil || h.Date > MAX_DATE {
+		return invalidf("holiday %q has a bad date", h.Name)
+	}
+	return nil
+}
+
+// holidayTaskName returns the name of the anti task generated for the subtask of a recurring task on a holiday
+func holidayTaskName(h Holiday, r RecurringTask) string {
+	return fmt.Sprintf("%s: %s (%s)", h.Name, r.Name, dateIntToString(h.Date))
+}
+
+// HolidayCancellations gets the anti tasks generated for holidays sorted by name
+func (s *Schedule) HolidayCancellations() []AntiTask {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.holidayCancellations()
+}
+
+// holidayCancellations gets the anti tasks of the set generated for holidays sorted by name
+func (ts taskSet) holidayCancellations() []AntiTask {
+	result := []AntiTask{}
+	for _, a := range ts.AntiTasks() {
+		if a.Holiday != "" {
+			result = append(result, a)
+		}
+	}
+	return result
+}
+
+// CancelHolidays replaces the anti tasks generated for holidays with anti tasks cancelling every subtask of the
+// recurring tasks of the given types that falls on one of the holidays
+// Subtasks that are already cancelled or overridden are left alone, and anti tasks made by hand are never changed
+// The schedule is left unchanged if a subtask that is no longer cancelled conflicts with another task
+func (s *Schedule) CancelHolidays(holidays []Holiday, types []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.atomically("cancel holidays", func() error {
+		return s.cancelHolidays(holidays, types)
+	})
+}
+
+// RemoveHolidayCancellations deletes every anti task generated for holidays
+// The schedule is left unchanged if a subtask that is no longer cancelled conflicts with another task
+func (s *Schedule) RemoveHolidayCancellations() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.atomically("remove holiday cancellations", func() error {
+		return s.cancelHolidays(nil, nil)
+	})
+}
+
+func (s *Schedule) cancelHolidays(holidays []Holiday, types []string) error {
+	for _, h := range holidays {
+		if err := h.check(); err != nil {
+			return fmt.Errorf("CancelHolidays: %w", err)
+		}
+	}
+	selected := map[string]bool{}
+	for _, t := range types {
+		name, ok := s.types.resolve(t, RECURRING)
+		if !ok {
+			return fmt.Errorf("CancelHolidays: %w", invalidf("%q is not a recurring task type", t))
+		}
+		selected[name] = true
+	}
+	removed := s.holidayCancellations()
+	for _, a := range removed {
+		s.removeAnti(a.ID)
+	}
+	for _, r := range s.taskSet.RecurringTasks() {
+		if !selected[r.Type] {
+			continue
+		}
+		for _, h := range holidays {
+			date, _ := intToDate(h.Date)
+			day := epochDay(date)
+			if len(r.subtaskDays(day, day)) == 0 {
+				continue
+			}
+			sub := r.subtaskOn(day)
+			if s.hidden(sub) {
+				continue
+			}
+			a := AntiTask{Task: Task{Name: holidayTaskName(h, r), Type: CANCEL, Date: sub.Date, StartTime: r.StartTime, Duration: r.Duration, TimeZone: r.TimeZone}, Holiday: h.Name}
+			a.ID, _ = s.claimID("")
+			a, err := s.checkAnti(a)
+			if err != nil {
+				return fmt.Errorf("CancelHolidays: %q: %w", a.Name, err)
+			}
+			s.putAnti(a)
+		}
+	}
+	for _, a := range removed {
+		if s.hasDeleteConflict(a) {
+			return fmt.Errorf("CancelHolidays: bringing back the subtask cancelled by %q creates a %w", a.Name, ErrConflict)
+		}
+	}
+	return nil
+}
+
+//!--
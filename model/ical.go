@@ -0,0 +1,654 @@
+// Package model provides functionality for creating and managing a schedule of tasks
+// ical.go provides importing and exporting of the schedule in iCalendar (RFC 5545) format
+package model
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ICAL_PRODID = "-//CS3560//PSS Scheduler//EN"
+	// Format of UTC date-time values in iCalendar
+	ICAL_TIME_FORMAT = "20060102T150405Z"
+	// Formats of local date-time and date values in iCalendar
+	ICAL_LOCAL_TIME_FORMAT = "20060102T150405"
+	ICAL_DATE_FORMAT       = "20060102"
+	// Format of iCalendar durations (eg. PT1H15M, P1D)
+	ICAL_DURATION_FORMAT = `^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`
+	// Maximum length of a content line in octets, not counting the line break
+	ICAL_LINE_LIMIT = 75
+)
+
+// WriteICal writes all tasks in the schedule to a specified file in iCalendar format
+// Transient tasks become single events and recurring tasks become repeating events, with the
+// occurrences cancelled by anti tasks listed as exceptions, which load back as one anti task each, and the occurrences moved by overrides written
+// as events of the same UID with a RECURRENCE-ID
+// Times of tasks with a time zone are written with the IANA name of the zone as their TZID
+func (s *Schedule) WriteICal(path string) error {
+	if err := writeFile(path, s.MarshalICal()); err != nil {
+		return fmt.Errorf("WriteICal: %v", err)
+	}
+	return nil
+}
+
+// MarshalICal encodes all tasks in the schedule as an iCalendar object
+func (s *Schedule) MarshalICal() []byte {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.taskSet.MarshalICal()
+}
+
+// MarshalICal encodes all tasks in the set as an iCalendar object
+func (ts taskSet) MarshalICal() []byte {
+	w := icalWriter{}
+	stamp := time.Now().UTC()
+	w.line("BEGIN", "VCALENDAR")
+	w.line("VERSION", "2.0")
+	w.line("PRODID", ICAL_PRODID)
+	w.line("CALSCALE", "GREGORIAN")
+	for _, t := range ts.TransientTasks() {
+		w.beginEvent(t, stamp)
+		w.line("END", "VEVENT")
+	}
+	for _, r := range ts.RecurringTasks() {
+		w.beginEvent(r.Task, stamp)
+		switch {
+		case r.Count > 0:
+			w.line("RRULE", fmt.Sprintf("%s;COUNT=%d", icalRule(r), r.Count))
+		case r.EndDate == 0:
+			w.line("RRULE", icalRule(r))
+		default:
+			last := r.Task
+			last.Date = r.EndDate
+			// UNTIL is always in UTC, even when the start is in a time zone
+			until := icalStart(last).UTC()
+			w.line("RRULE", fmt.Sprintf("%s;UNTIL=%s", icalRule(r), until.Format(ICAL_TIME_FORMAT)))
+		}
+		// Anti tasks are rendered as exceptions to the series they cancel, with a range anti task giving
+		// an exception for each subtask it cancels
+		exDates := []time.Time{}
+		seen := map[int]bool{}
+		for _, a := range ts.antiTasks {
+			for _, cancelled := range a.GetCancelledSubtasks(r) {
+				if !seen[cancelled.Date] {
+					seen[cancelled.Date] = true
+					exDates = append(exDates, icalStart(cancelled))
+				}
+			}
+		}
+		sort.Slice(exDates, func(i, j int) bool {
+			return exDates[i].Before(exDates[j])
+		})
+		for _, d := range exDates {
+			w.dateTime("EXDATE", d, r.TimeZone)
+		}
+		w.line("END", "VEVENT")
+		for _, o := range ts.overridesOf(r.ID) {
+			original := r.Task
+			original.Date = o.Original
+			w.beginEvent(o.Task, stamp)
+			w.dateTime("RECURRENCE-ID", icalStart(original), r.TimeZone)
+			w.line("END", "VEVENT")
+		}
+	}
+	w.line("END", "VCALENDAR")
+	return []byte(w.String())
+}
+
+// icalWriter builds the content lines of an iCalendar object
+type icalWriter struct {
+	strings.Builder
+}
+
+// line writes a content line, folding it if it exceeds the line limit
+func (w *icalWriter) line(name, value string) {
+	l := name + ":" + value
+	for len(l) > ICAL_LINE_LIMIT {
+		// Do not split a multi-byte character across lines
+		cut := ICAL_LINE_LIMIT
+		for cut > 0 && l[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		w.WriteString(l[:cut] + "\r\n")
+		l = " " + l[cut:]
+	}
+	w.WriteString(l + "\r\n")
+}
+
+// beginEvent writes the opening of an event with the properties common to all tasks
+func (w *icalWriter) beginEvent(t Task, stamp time.Time) {
+	start := icalStart(t)
+	w.line("BEGIN", "VEVENT")
+	w.line("UID", icalUID(t.ID))
+	w.line("DTSTAMP", stamp.Format(ICAL_TIME_FORMAT))
+	w.dateTime("DTSTART", start, t.TimeZone)
+	w.dateTime("DTEND", start.Add(t.Duration), t.TimeZone)
+	w.line("SUMMARY", icalEscape(t.Name))
+	w.line("CATEGORIES", icalEscape(t.Type))
+}
+
+// dateTime writes a DATE-TIME property, in UTC if the task has no time zone and in the zone otherwise
+func (w *icalWriter) dateTime(name string, t time.Time, zone string) {
+	if zone == "" {
+		w.line(name, t.UTC().Format(ICAL_TIME_FORMAT))
+		return
+	}
+	w.line(name+";TZID="+zone, t.Format(ICAL_LOCAL_TIME_FORMAT))
+}
+
+// icalStart returns the start of a task to the minute in the task's time zone
+func icalStart(t Task) time.Time {
+	start, _ := t.GetStartDate()
+	return start
+}
+
+// icalRule writes the recurrence rule of a recurring task as the FREQ, INTERVAL and BY parts of an RRULE
+func icalRule(r RecurringTask) string {
+	days := []string{}
+	for _, d := range r.Weekdays.mondayFirst() {
+		days = append(days, icalWeekday(d))
+	}
+	switch r.rule() {
+	case ruleWeekly:
+		return fmt.Sprintf("FREQ=WEEKLY;INTERVAL=%d;BYDAY=%s;WKST=MO", r.Frequency, strings.Join(days, ","))
+	case ruleMonthDay:
+		return fmt.Sprintf("FREQ=MONTHLY;INTERVAL=%d;BYMONTHDAY=%d", r.Frequency, r.MonthDay)
+	case ruleMonthWeek:
+		return fmt.Sprintf("FREQ=MONTHLY;INTERVAL=%d;BYDAY=%d%s", r.Frequency, r.MonthWeek, days[0])
+	}
+	return fmt.Sprintf("FREQ=DAILY;INTERVAL=%d", r.Frequency)
+}
+
+// icalWeekday returns the two letter iCalendar name of a day of the week (eg. MO)
+func icalWeekday(d time.Weekday) string {
+	return strings.ToUpper(d.String()[:2])
+}
+
+// icalUID derives a unique identifier for an event from its task ID
+func icalUID(id string) string {
+	return id + "@pss"
+}
+
+// icalEscape escapes special characters in a text value
+func icalEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
+
+// LoadICal loads the events of the iCalendar file at the specified path into the schedule
+// Events become transient tasks, events with a daily, weekly or monthly RRULE become recurring tasks and
+// their EXDATEs become anti tasks, and events with a RECURRENCE-ID become overrides of the occurrence of
+// the repeating event of the same UID, or of the same name if they have no UID
+// The CATEGORIES of an event decide its task type; categories maps categories onto task types and is
+// only needed for categories that are not already the name of a type
+// Either all of the events are added or the schedule is left unchanged
+func (s *Schedule) LoadICal(path string, categories map[string]string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("LoadICal: error reading file %q: %v", path, err)
+	}
+	batch, err := parseICal(string(content), categories, s.Types())
+	if err != nil {
+		return fmt.Errorf("LoadICal: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.addBatch(batch); err != nil {
+		return fmt.Errorf("LoadICal: error loading events: %w", err)
+	}
+	return nil
+}
+
+// icalProperty is a single content line of an iCalendar object
+type icalProperty struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// icalEvent holds the properties of a VEVENT
+type icalEvent map[string][]icalProperty
+
+// get returns the first property with the given name
+func (e icalEvent) get(name string) (icalProperty, bool) {
+	if p, ok := e[name]; ok && len(p) > 0 {
+		return p[0], true
+	}
+	return icalProperty{}, false
+}
+
+// parseICal converts the events of an iCalendar object into a batch of tasks with the types of a registry
+func parseICal(content string, categories map[string]string, types *TypeRegistry) (taskBatch, error) {
+	var batch taskBatch
+	events, err := icalEvents(content)
+	if err != nil {
+		return batch, err
+	}
+	lookup := map[string]string{}
+	for category, taskType := range categories {
+		lookup[strings.ToLower(category)] = taskType
+	}
+	// Report every bad event rather than only the first
+	problems := []string{}
+	masters := map[string]string{}
+	for i, e := range events {
+		if err := addICalEvent(&batch, e, lookup, types, masters); err != nil {
+			summary := fmt.Sprintf("#%d", i+1)
+			if p, ok := e.get("SUMMARY"); ok {
+				summary = fmt.Sprintf("%q", icalUnescape(p.value))
+			}
+			problems = append(problems, fmt.Sprintf("event %s: %v", summary, err))
+		}
+	}
+	// Moved occurrences may come before their repeating event, so their UIDs are swapped for the IDs of the
+	// recurring tasks once every event is read
+	for i, o := range batch.overrides {
+		if o.ID == "" {
+			continue
+		}
+		id, ok := masters[o.ID]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("event %q: no repeating event with UID %q", o.Name, o.ID))
+			continue
+		}
+		batch.overrides[i].ID = id
+	}
+	if len(problems) > 0 {
+		return batch, invalidf("error parsing events: %s", strings.Join(problems, "; "))
+	}
+	return batch, nil
+}
+
+// icalEvents unfolds the content lines of an iCalendar object and groups them by VEVENT
+func icalEvents(content string) ([]icalEvent, error) {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	content = strings.ReplaceAll(content, "\n ", "")
+	content = strings.ReplaceAll(content, "\n\t", "")
+	events := []icalEvent{}
+	var current icalEvent
+	depth := 0 // Depth of components nested inside the current event (eg. VALARM)
+	for _, line := range strings.Split(content, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		p, err := parseICalLine(line)
+		if err != nil {
+			return nil, invalidf("error parsing line %q: %v", line, err)
+		}
+		switch {
+		case p.name == "BEGIN" && strings.EqualFold(p.value, "VEVENT") && current == nil:
+			current = icalEvent{}
+		case current == nil:
+			// Outside of an event
+		case p.name == "BEGIN":
+			depth++
+		case p.name == "END" && depth > 0:
+			depth--
+		case p.name == "END" && strings.EqualFold(p.value, "VEVENT"):
+			events = append(events, current)
+			current = nil
+		case depth == 0:
+			current[p.name] = append(current[p.name], p)
+		}
+	}
+	if current != nil {
+		return nil, invalidf("error parsing events: unterminated VEVENT")
+	}
+	return events, nil
+}
+
+// parseICalLine splits a content line into its name, parameters and value
+func parseICalLine(line string) (icalProperty, error) {
+	p := icalProperty{params: map[string]string{}}
+	// The value starts at the first colon that is not inside a quoted parameter value
+	quoted := false
+	colon := -1
+	for i, c := range line {
+		if c == '"' {
+			quoted = !quoted
+		}
+		if c == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return p, fmt.Errorf("missing ':'")
+	}
+	p.value = line[colon+1:]
+	tok := strings.Split(line[:colon], ";")
+	p.name = strings.ToUpper(tok[0])
+	for _, param := range tok[1:] {
+		kv := strings.SplitN(param, "=", 2)
+		if len(kv) != 2 {
+			return p, fmt.Errorf("bad parameter %q", param)
+		}
+		p.params[strings.ToUpper(kv[0])] = strings.Trim(kv[1], `"`)
+	}
+	return p, nil
+}
+
+// addICalEvent converts an event into tasks and adds them to the batch
+// The recurring task of a repeating event is given an ID, recorded in masters by the UID of the event, and the
+// override of a moved occurrence keeps the UID as its ID
+func addICalEvent(batch *taskBatch, e icalEvent, categories map[string]string, types *TypeRegistry, masters map[string]string) error {
+	summary, ok := e.get("SUMMARY")
+	if !ok || summary.value == "" {
+		return fmt.Errorf("missing SUMMARY")
+	}
+	name := icalUnescape(summary.value)
+	taskType, err := icalTaskType(e, categories, types)
+	if err != nil {
+		return err
+	}
+	dtStart, ok := e.get("DTSTART")
+	if !ok {
+		return fmt.Errorf("missing DTSTART")
+	}
+	start, err := parseICalTime(dtStart)
+	if err != nil {
+		return fmt.Errorf("bad DTSTART: %v", err)
+	}
+	var duration time.Duration
+	if dtEnd, ok := e.get("DTEND"); ok {
+		end, err := parseICalTime(dtEnd)
+		if err != nil {
+			return fmt.Errorf("bad DTEND: %v", err)
+		}
+		duration = end.Sub(start)
+	} else if d, ok := e.get("DURATION"); ok {
+		if duration, err = parseICalDuration(d.value); err != nil {
+			return fmt.Errorf("bad DURATION: %v", err)
+		}
+	}
+	task := Task{
+		Name:      name,
+		Type:      taskType,
+		Date:      dateToInt(start),
+		StartTime: time.Duration(start.Hour())*time.Hour + time.Duration(start.Minute())*time.Minute,
+		Duration:  duration,
+		TimeZone:  icalZone(dtStart),
+	}
+	if recurrenceID, ok := e.get("RECURRENCE-ID"); ok {
+		original, err := parseICalTime(recurrenceID)
+		if err != nil {
+			return fmt.Errorf("bad RECURRENCE-ID: %v", err)
+		}
+		original = original.In(start.Location())
+		if uid, ok := e.get("UID"); ok {
+			task.ID = uid.value
+		}
+		batch.overrides = append(batch.overrides, Override{Task: task, Original: dateToInt(original)})
+		return nil
+	}
+	rule, ok := e.get("RRULE")
+	if !ok {
+		if _, ok := e.get("EXDATE"); ok {
+			return fmt.Errorf("EXDATE without RRULE")
+		}
+		switch {
+		case types.is(taskType, TRANSIENT):
+			batch.transient = append(batch.transient, task)
+		case types.is(taskType, ANTI):
+			batch.anti = append(batch.anti, AntiTask{Task: task})
+		default:
+			batch.subtasks = append(batch.subtasks, task)
+		}
+		return nil
+	}
+	if !types.is(taskType, RECURRING) {
+		return fmt.Errorf("repeating event has non-recurring type %q", taskType)
+	}
+	r, err := parseICalRule(rule.value, task, start)
+	if err != nil {
+		return err
+	}
+	if uid, ok := e.get("UID"); ok {
+		if _, ok := masters[uid.value]; ok {
+			return fmt.Errorf("repeating event with UID %q already read", uid.value)
+		}
+		r.ID = NewID()
+		masters[uid.value] = r.ID
+	}
+	batch.recurring = append(batch.recurring, r)
+	// Every excluded occurrence becomes an anti task for the series
+	for _, p := range e["EXDATE"] {
+		for _, v := range strings.Split(p.value, ",") {
+			exDate, err := parseICalTime(icalProperty{name: p.name, params: p.params, value: v})
+			if err != nil {
+				return fmt.Errorf("bad EXDATE: %v", err)
+			}
+			exDate = exDate.In(start.Location())
+			batch.anti = append(batch.anti, AntiTask{Task: Task{
+				Name:      fmt.Sprintf("%s (cancelled %s)", name, dateIntToString(dateToInt(exDate))),
+				Type:      CANCEL,
+				Date:      dateToInt(exDate),
+				StartTime: task.StartTime,
+				Duration:  task.Duration,
+				TimeZone:  task.TimeZone,
+			}})
+		}
+	}
+	return nil
+}
+
+// icalTaskType picks the task type for an event from its CATEGORIES
+func icalTaskType(e icalEvent, categories map[string]string, types *TypeRegistry) (string, error) {
+	found := []string{}
+	for _, p := range e["CATEGORIES"] {
+		for _, c := range splitICalList(p.value) {
+			c = strings.TrimSpace(icalUnescape(c))
+			if c == "" {
+				continue
+			}
+			if t, ok := categories[strings.ToLower(c)]; ok {
+				return t, nil
+			}
+			if t, ok := types.Lookup(c); ok {
+				return t.Name, nil
+			}
+			found = append(found, fmt.Sprintf("%q", c))
+		}
+	}
+	if len(found) == 0 {
+		return "", fmt.Errorf("no CATEGORIES to map onto a task type")
+	}
+	if len(found) == 1 {
+		return "", fmt.Errorf("category %s does not map onto a task type", found[0])
+	}
+	return "", fmt.Errorf("categories %s do not map onto a task type", strings.Join(found, ", "))
+}
+
+// parseICalRule converts a daily, weekly or monthly RRULE into a recurring task starting with task
+// Weekly rules on the day of the start alone become tasks repeating every 7 days
+func parseICalRule(rule string, task Task, start time.Time) (RecurringTask, error) {
+	r := RecurringTask{Task: task}
+	parts := map[string]string{}
+	for _, part := range strings.Split(rule, ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return r, fmt.Errorf("bad RRULE part %q", part)
+		}
+		parts[strings.ToUpper(kv[0])] = strings.ToUpper(kv[1])
+	}
+	for key := range parts {
+		if key != "FREQ" && key != "INTERVAL" && key != "UNTIL" && key != "COUNT" && key != "BYDAY" && key != "BYMONTHDAY" && key != "WKST" {
+			return r, fmt.Errorf("unsupported RRULE part %q", key)
+		}
+	}
+	r.Frequency = 1
+	if v, ok := parts["INTERVAL"]; ok {
+		i, err := strconv.Atoi(v)
+		if err != nil || i < 1 {
+			return r, fmt.Errorf("bad RRULE INTERVAL %q", v)
+		}
+		r.Frequency = i
+	}
+	byDay, hasByDay := parts["BYDAY"]
+	_, hasByMonthDay := parts["BYMONTHDAY"]
+	switch parts["FREQ"] {
+	case "DAILY":
+		if hasByDay || hasByMonthDay {
+			return r, fmt.Errorf("unsupported RRULE BY part for FREQ DAILY")
+		}
+	case "WEEKLY":
+		if hasByMonthDay {
+			return r, fmt.Errorf("unsupported RRULE BYMONTHDAY for FREQ WEEKLY")
+		}
+		if wkst, ok := parts["WKST"]; ok && wkst != "MO" && r.Frequency > 1 {
+			return r, fmt.Errorf("unsupported RRULE WKST %q", wkst)
+		}
+		r.Weekdays = NewWeekdays(start.Weekday())
+		if hasByDay {
+			w, err := parseICalWeekdays(byDay)
+			if err != nil {
+				return r, err
+			}
+			r.Weekdays = w
+		}
+		if r.Weekdays == NewWeekdays(start.Weekday()) && r.Frequency == 1 {
+			r.Weekdays, r.Frequency = 0, 7
+		}
+	case "MONTHLY":
+		switch {
+		case hasByMonthDay && hasByDay:
+			return r, fmt.Errorf("unsupported RRULE with both BYDAY and BYMONTHDAY")
+		case hasByDay:
+			// An ordinal weekday such as 2TU or -1FR
+			m := regexp.MustCompile(`^([+-]?\d)(MO|TU|WE|TH|FR|SA|SU)$`).FindStringSubmatch(byDay)
+			if m == nil {
+				return r, fmt.Errorf("unsupported RRULE BYDAY %q", byDay)
+			}
+			r.MonthWeek, _ = strconv.Atoi(m[1])
+			r.Weekdays, _ = parseICalWeekdays(m[2])
+		case hasByMonthDay:
+			d, err := strconv.Atoi(parts["BYMONTHDAY"])
+			if err != nil {
+				return r, fmt.Errorf("unsupported RRULE BYMONTHDAY %q", parts["BYMONTHDAY"])
+			}
+			r.MonthDay = d
+		default:
+			r.MonthDay = start.Day()
+		}
+	default:
+		return r, fmt.Errorf("unsupported RRULE FREQ %q", parts["FREQ"])
+	}
+	if until, ok := parts["UNTIL"]; ok {
+		end, err := parseICalTime(icalProperty{value: until, params: map[string]string{}})
+		if err != nil {
+			return r, fmt.Errorf("bad RRULE UNTIL: %v", err)
+		}
+		r.EndDate = dateToInt(end.In(start.Location()))
+		return r, nil
+	}
+	if count, ok := parts["COUNT"]; ok {
+		n, err := strconv.Atoi(count)
+		if err != nil || n < 1 {
+			return r, fmt.Errorf("bad RRULE COUNT %q", count)
+		}
+		r.Count = n
+		return r, nil
+	}
+	// A series without UNTIL or COUNT never ends
+	return r, nil
+}
+
+// parseICalWeekdays converts a list of two letter iCalendar days (eg. MO,WE,FR) into a set of days
+func parseICalWeekdays(s string) (Weekdays, error) {
+	var w Weekdays
+	for _, name := range strings.Split(s, ",") {
+		found := false
+		for d := time.Sunday; d <= time.Saturday; d++ {
+			if name == icalWeekday(d) {
+				w |= NewWeekdays(d)
+				found = true
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("unsupported RRULE BYDAY %q", s)
+		}
+	}
+	return w, nil
+}
+
+// icalZone returns the time zone a task takes from a DATE-TIME property
+// Times with a TZID are in that zone, UTC times are in UTC and floating times are left to the schedule's
+// default time zone
+func icalZone(p icalProperty) string {
+	if tzid, ok := p.params["TZID"]; ok {
+		return tzid
+	}
+	if strings.HasSuffix(p.value, "Z") {
+		return "UTC"
+	}
+	return ""
+}
+
+// parseICalTime parses a DATE-TIME value
+// Times with a TZID are in that zone and UTC and floating times are in UTC
+func parseICalTime(p icalProperty) (time.Time, error) {
+	if p.params["VALUE"] == "DATE" || len(p.value) == len(ICAL_DATE_FORMAT) {
+		return time.Time{}, fmt.Errorf("all-day events are not supported")
+	}
+	if strings.HasSuffix(p.value, "Z") {
+		return time.Parse(ICAL_TIME_FORMAT, p.value)
+	}
+	loc := time.UTC
+	if tzid, ok := p.params["TZID"]; ok {
+		l, err := time.LoadLocation(tzid)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("unknown TZID %q", tzid)
+		}
+		loc = l
+	}
+	return time.ParseInLocation(ICAL_LOCAL_TIME_FORMAT, p.value, loc)
+}
+
+// parseICalDuration parses a DURATION value
+func parseICalDuration(s string) (time.Duration, error) {
+	m := regexp.MustCompile(ICAL_DURATION_FORMAT).FindStringSubmatch(s)
+	if m == nil || s == "P" || s == "PT" {
+		return 0, fmt.Errorf("bad duration %q", s)
+	}
+	if m[1] == "-" {
+		return 0, fmt.Errorf("negative duration %q", s)
+	}
+	units := []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+	var d time.Duration
+	for i, unit := range units {
+		if m[i+2] != "" {
+			n, _ := strconv.Atoi(m[i+2])
+			d += time.Duration(n) * unit
+		}
+	}
+	return d, nil
+}
+
+// splitICalList splits a comma separated list of text values, keeping escaped commas
+func splitICalList(s string) []string {
+	result := []string{}
+	start := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if s[i] == ',' {
+			result = append(result, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(result, s[start:])
+}
+
+// icalUnescape reverses icalEscape
+func icalUnescape(s string) string {
+	return strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n").Replace(s)
+}
+
+//!--
@@ -0,0 +1,120 @@
+// Package model provides functionality for creating and managing a schedule of tasks
+// ids.go provides the IDs that tasks are stored under and the lookup of tasks by ID or name
+package model
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+)
+
+const (
+	// Number of random bytes in a task ID
+	ID_BYTES = 8
+)
+
+// NewID returns a random task ID
+// A task added without an ID is given one, so callers only need this to know the ID of a task before adding it
+func NewID() string {
+	b := make([]byte, ID_BYTES)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("NewID: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
+
+// hasID checks if a transient, anti or recurring task has an ID
+func (ts taskSet) hasID(id string) bool {
+	return ts.isTransient(id) || ts.isAnti(id) || ts.isRecurring(id)
+}
+
+// isTransient checks if a transient task or recurring subtask has an ID
+func (ts taskSet) isTransient(id string) bool {
+	_, ok := ts.transientTasks[id]
+	return ok
+}
+
+// isAnti checks if an anti task has an ID
+func (ts taskSet) isAnti(id string) bool {
+	_, ok := ts.antiTasks[id]
+	return ok
+}
+
+// isRecurring checks if a recurring task has an ID
+func (ts taskSet) isRecurring(id string) bool {
+	_, ok := ts.recurringTasks[id]
+	return ok
+}
+
+// claimID gives a task about to be added a new ID if it has none, or checks that its ID is not in use
+func (ts taskSet) claimID(id string) (string, error) {
+	if id == "" {
+		for id = NewID(); ts.hasID(id); id = NewID() {
+		}
+		return id, nil
+	}
+	if ts.hasID(id) {
+		return id, ErrIDTaken
+	}
+	return id, nil
+}
+
+// TaskID gets the ID of the transient, anti or recurring task that a reference names
+// A reference is the ID of a task or a name no other task has, and a name shared by several tasks gives ErrAmbiguous
+func (s *Schedule) TaskID(ref string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	id, err := s.resolve(ref, s.hasID)
+	if err != nil {
+		return "", fmt.Errorf("TaskID: %w", err)
+	}
+	return id, nil
+}
+
+// The following methods keep the name index in step with the task maps
+// Names are not unique, so the index lists the IDs of every task with each name
+
+// addName lists the ID of a task under its name
+func (ts taskSet) addName(name, id string) {
+	ts.names[name] = append(ts.names[name], id)
+}
+
+// removeName removes the ID of a task listed by addName
+func (ts taskSet) removeName(name, id string) {
+	ids := ts.names[name]
+	for i, n := range ids {
+		if n == id {
+			ids = append(ids[:i:i], ids[i+1:]...)
+			break
+		}
+	}
+	if len(ids) == 0 {
+		delete(ts.names, name)
+		return
+	}
+	ts.names[name] = ids
+}
+
+// resolve gets the ID of the task a reference names among the tasks for which in returns true
+// A reference is the ID of a task or, failing that, a name that only one of those tasks has
+func (ts taskSet) resolve(ref string, in func(id string) bool) (string, error) {
+	if in(ref) {
+		return ref, nil
+	}
+	found := ""
+	for _, id := range ts.names[ref] {
+		if !in(id) {
+			continue
+		}
+		if found != "" {
+			return "", ErrAmbiguous
+		}
+		found = id
+	}
+	if found == "" {
+		return "", ErrNotFound
+	}
+	return found, nil
+}
+
+//!--
@@ -0,0 +1,119 @@
+// Package model provides functionality for creating and managing a schedule of tasks
+// index.go provides an index of the days touched by tasks for fast conflict checks and range queries
+package model
+
+import (
+	"sort"
+)
+
+const (
+	SECONDS_PER_DAY = 24 * 60 * 60
+)
+
+// dayIndex lists the tasks touching each day, with days counted from the Unix epoch
+// Days with tasks are kept sorted so that the tasks in a range of days are found by binary search
+// A task is listed under every day it touches, so a conflict check only has to look at the tasks sharing
+// a day with the task being checked, even for tasks running past midnight or over several days
+type dayIndex struct {
+	days    []int64            // Days with at least one task, in increasing order
+	buckets map[int64][]string // IDs of the tasks touching each day
+}
+
+// newDayIndex creates an empty index
+func newDayIndex() *dayIndex {
+	return &dayIndex{buckets: map[int64][]string{}}
+}
+
+// taskDays returns the first and last day touched by a task
+// A task ending exactly at midnight is counted as touching the next day, which costs nothing but
+// an extra bucket to look at
+func taskDays(t Task) (int64, int64) {
+	start, _ := t.GetStartDate()
+	end := start.Add(t.Duration)
+	return floorDiv(start.Unix(), SECONDS_PER_DAY), floorDiv(end.Unix(), SECONDS_PER_DAY)
+}
+
+// add lists a task under every day it touches
+func (idx *dayIndex) add(t Task) {
+	first, last := taskDays(t)
+	idx.addDays(t.ID, first, last)
+}
+
+// addDays lists an ID under every day from first to last
+func (idx *dayIndex) addDays(name string, first, last int64) {
+	for d := first; d <= last; d++ {
+		bucket, ok := idx.buckets[d]
+		if !ok {
+			i := idx.search(d)
+			idx.days = append(idx.days, 0)
+			copy(idx.days[i+1:], idx.days[i:])
+			idx.days[i] = d
+		}
+		idx.buckets[d] = append(bucket, name)
+	}
+}
+
+// remove removes a task listed by add
+func (idx *dayIndex) remove(t Task) {
+	first, last := taskDays(t)
+	idx.removeDays(t.ID, first, last)
+}
+
+// removeDays removes an ID listed by addDays
+func (idx *dayIndex) removeDays(name string, first, last int64) {
+	for d := first; d <= last; d++ {
+		bucket := idx.buckets[d]
+		for i, n := range bucket {
+			if n == name {
+				bucket[i] = bucket[len(bucket)-1]
+				bucket = bucket[:len(bucket)-1]
+				break
+			}
+		}
+		if len(bucket) > 0 {
+			idx.buckets[d] = bucket
+			continue
+		}
+		delete(idx.buckets, d)
+		if i := idx.search(d); i < len(idx.days) && idx.days[i] == d {
+			idx.days = append(idx.days[:i], idx.days[i+1:]...)
+		}
+	}
+}
+
+// between returns the IDs of the tasks touching any day from first to last
+// Each ID is returned once, in order of the first day it touches
+func (idx *dayIndex) between(first, last int64) []string {
+	result := []string{}
+	seen := map[string]bool{}
+	for i := idx.search(first); i < len(idx.days) && idx.days[i] <= last; i++ {
+		for _, n := range idx.buckets[idx.days[i]] {
+			if !seen[n] {
+				seen[n] = true
+				result = append(result, n)
+			}
+		}
+	}
+	return result
+}
+
+// search returns the position of the first indexed day not before d
+func (idx *dayIndex) search(d int64) int {
+	return sort.Search(len(idx.days), func(i int) bool {
+		return idx.days[i] >= d
+	})
+}
+
+// copy returns an index listing the same tasks that is not changed by changes to idx
+func (idx *dayIndex) copy() *dayIndex {
+	c := &dayIndex{
+		days:    append([]int64{}, idx.days...),
+		buckets: make(map[int64][]string, len(idx.buckets)),
+	}
+	for d, bucket := range idx.buckets {
+		c.buckets[d] = append([]string{}, bucket...)
+	}
+	return c
+}
+
+//!--
@@ -2,52 +2,171 @@
 // json_containers.go provides structs that define the json marshaling formats for tasks
 package model
 
-// taskContainer is a container for the fields of Tasks and AntiTasks
+import "encoding/json"
+
+// taskContainer is a container for the fields of Tasks
 // This class is not needed but provided for the sake of consistency due to the unfortunate
 // necessity of recurContainer
 type taskContainer struct {
+	ID        string `json:",omitempty"`
 	Name      string
 	Type      string
 	Date      int
-	StartTime float32
-	Duration  float32
+	StartTime string // eg. "17:30"
+	Duration  string // eg. "2:30"
+	TimeZone  string `json:",omitempty"`
+}
+
+// antiContainer is a container for the fields of AntiTasks, which only have an end date and a series
+// if they cancel a range of dates, or an end date and a frequency if they recur, and only have a holiday if
+// they were generated for one
+type antiContainer struct {
+	taskContainer
+	EndDate   int    `json:",omitempty"`
+	Series    string `json:",omitempty"`
+	Frequency int    `json:",omitempty"`
+	Holiday   string `json:",omitempty"`
 }
 
 // recurContainer is a container for the fields of RecurringTask for the sole purpose of complying
 // with the convention of importing/exporting the "Date" field as "StartDate" for recurring tasks
 // We cannot simply embed taskContainer due to the discrepency in "Date" and "StartDate" naming conventions
 type recurContainer struct {
+	ID        string `json:",omitempty"`
 	Name      string
 	Type      string
 	StartDate int
-	StartTime float32
-	Duration  float32
-	EndDate   int
+	StartTime string
+	Duration  string
+	EndDate   int `json:",omitempty"`
+	Count     int `json:",omitempty"`
 	Frequency int
+	Weekdays  Weekdays            `json:",omitempty"`
+	MonthDay  int                 `json:",omitempty"`
+	MonthWeek int                 `json:",omitempty"`
+	TimeZone  string              `json:",omitempty"`
+	Overrides []overrideContainer `json:",omitempty"`
+}
+
+// overrideContainer is a container for the fields of an Override, which is written inside its recurring task
+// and so leaves out the name and type
+type overrideContainer struct {
+	Original  int
+	Date      int
+	StartTime string
+	Duration  string
+	TimeZone  string `json:",omitempty"`
+}
+
+// blackoutContainer is a container for the fields of a Blackout, which is written in the task list with the
+// type "Blackout"
+type blackoutContainer struct {
+	Name      string
+	Type      string
+	Date      int
+	StartTime string
+	EndDate   int
+	EndTime   string
+	TimeZone  string `json:",omitempty"`
+	Policy    string
 }
 
 // taskToContainer populates a taskContainer with the fields of a Task
 func taskToContainer(t Task) taskContainer {
 	return taskContainer{
+		ID:        t.ID,
 		Name:      t.Name,
 		Type:      t.Type,
 		Date:      t.Date,
-		StartTime: t.StartTime,
-		Duration:  t.Duration,
+		StartTime: formatClock(t.StartTime),
+		Duration:  formatHours(t.Duration),
+		TimeZone:  t.TimeZone,
+	}
+}
+
+// antiToContainer populates an antiContainer with the fields of an AntiTask
+func antiToContainer(a AntiTask) antiContainer {
+	return antiContainer{
+		taskContainer: taskToContainer(a.Task),
+		EndDate:       a.EndDate,
+		Series:        a.Series,
+		Frequency:     a.Frequency,
+		Holiday:       a.Holiday,
 	}
 }
 
 // recurToContainer populates a recurContainer with the fields of a RecurringTask
 func recurToContainer(r RecurringTask) recurContainer {
 	return recurContainer{
+		ID:        r.ID,
 		Name:      r.Name,
 		Type:      r.Type,
 		StartDate: r.Date,
-		StartTime: r.StartTime,
-		Duration:  r.Duration,
+		StartTime: formatClock(r.StartTime),
+		Duration:  formatHours(r.Duration),
 		EndDate:   r.EndDate,
+		Count:     r.Count,
 		Frequency: r.Frequency,
+		Weekdays:  r.Weekdays,
+		MonthDay:  r.MonthDay,
+		MonthWeek: r.MonthWeek,
+		TimeZone:  r.TimeZone,
 	}
 }
 
+// overrideToContainer populates an overrideContainer with the fields of an Override
+func overrideToContainer(o Override) overrideContainer {
+	return overrideContainer{
+		Original:  o.Original,
+		Date:      o.Date,
+		StartTime: formatClock(o.StartTime),
+		Duration:  formatHours(o.Duration),
+		TimeZone:  o.TimeZone,
+	}
+}
+
+// blackoutToContainer populates a blackoutContainer with the fields of a Blackout
+func blackoutToContainer(b Blackout) blackoutContainer {
+	return blackoutContainer{
+		Name:      b.Name,
+		Type:      BLACKOUT,
+		Date:      b.Date,
+		StartTime: formatClock(b.StartTime),
+		EndDate:   b.EndDate,
+		EndTime:   formatClock(b.EndTime),
+		TimeZone:  b.TimeZone,
+		Policy:    b.Policy,
+	}
+}
+
+// MarshalJSON encodes a task in the same format as WriteTasks
+func (t Task) MarshalJSON() ([]byte, error) {
+	return json.Marshal(taskToContainer(t))
+}
+
+// MarshalJSON encodes an anti task in the same format as WriteTasks
+func (a AntiTask) MarshalJSON() ([]byte, error) {
+	return json.Marshal(antiToContainer(a))
+}
+
+// MarshalJSON encodes a recurring task in the same format as WriteTasks
+func (r RecurringTask) MarshalJSON() ([]byte, error) {
+	return json.Marshal(recurToContainer(r))
+}
+
+// MarshalJSON encodes a blackout period in the same format as WriteTasks
+func (b Blackout) MarshalJSON() ([]byte, error) {
+	return json.Marshal(blackoutToContainer(b))
+}
+
+// MarshalJSON encodes an override with the ID, name and type of its recurring task
+func (o Override) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		ID   string
+		Name string
+		Type string
+		overrideContainer
+	}{o.ID, o.Name, o.Type, overrideToContainer(o)})
+}
+
 //!--
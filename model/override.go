@@ -0,0 +1,221 @@
+// Package model provides functionality for creating and managing a schedule of tasks
+// override.go provides overrides that move or change a single subtask of a recurring task
+package model
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Override replaces the subtask of a recurring task on the date Original with its Task
+// The task keeps the ID, name and type of its recurring task but may be on another date, at another time or
+// for another duration
+type Override struct {
+	Task
+	Original int // Date of the subtask it replaces
+}
+
+func (o Override) String() string {
+	return o.Task.String() + fmt.Sprintf("\nReplaces: %v", dateIntToString(o.Original))
+}
+
+// key identifies the subtask an override replaces
+func (o Override) key() string {
+	return overrideKey(o.ID, o.Original)
+}
+
+// overrideKey identifies the subtask of a recurring task on a date by the ID of the task (eg. "3f2a9c0d1b7e4f56 (2020-04-30)")
+func overrideKey(id string, date int) string {
+	return fmt.Sprintf("%s (%s)", id, dateIntToString(date))
+}
+
+// The following methods keep the override index in step with the override map
+// The index lists overrides under their key rather than their ID, which they share with their recurring task
+
+// setOverride adds or replaces an override
+func (ts taskSet) setOverride(o Override) {
+	ts.deleteOverride(o.key())
+	ts.overrides[o.key()] = o
+	indexed := o.Task
+	indexed.ID = o.key()
+	ts.overrideDays.add(indexed)
+}
+
+// deleteOverride removes an override
+func (ts taskSet) deleteOverride(key string) {
+	if old, ok := ts.overrides[key]; ok {
+		indexed := old.Task
+		indexed.ID = key
+		ts.overrideDays.remove(indexed)
+		delete(ts.overrides, key)
+	}
+}
+
+// overridesNear gets the overrides touching any of the days touched by a task
+func (ts taskSet) overridesNear(t Task) []Override {
+	result := []Override{}
+	for _, key := range ts.overrideDays.between(taskDays(t)) {
+		result = append(result, ts.overrides[key])
+	}
+	return result
+}
+
+// overridesOf gets the overrides of a recurring task by its ID
+func (ts taskSet) overridesOf(id string) []Override {
+	result := []Override{}
+	for _, o := range ts.overrides {
+		if o.ID == id {
+			result = append(result, o)
+		}
+	}
+	return result
+}
+
+// overridden checks if a subtask of a recurring task has been replaced by an override
+func (ts taskSet) overridden(sub Task) bool {
+	_, ok := ts.overrides[overrideKey(sub.ID, sub.Date)]
+	return ok
+}
+
+// hidden checks if a subtask of a recurring task is cancelled by an anti task or replaced by an override
+func (ts taskSet) hidden(sub Task) bool {
+	return ts.overridden(sub) || ts.hasAnti(sub) || ts.skipped(sub)
+}
+
+// Override gets the override of the subtask on a date of a recurring task named by its ID or a name no other one has
+func (ts taskSet) Override(ref string, date int) (Override, bool) {
+	id, err := ts.resolve(ref, ts.isRecurring)
+	if err != nil {
+		return Override{}, false
+	}
+	o, ok := ts.overrides[overrideKey(id, date)]
+	return o, ok
+}
+
+// Overrides gets all overrides sorted by name and the date of the subtask they replace
+func (ts taskSet) Overrides() []Override {
+	result := make([]Override, 0, len(ts.overrides))
+	for _, o := range ts.overrides {
+		result = append(result, o)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Name != result[j].Name || result[i].ID != result[j].ID {
+			return byName(result[i].Task, result[j].Task)
+		}
+		return result[i].Original < result[j].Original
+	})
+	return result
+}
+
+// Override gets the override of the subtask on a date of a recurring task named by its ID or a name no other one has
+func (s *Schedule) Override(ref string, date int) (Override, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.taskSet.Override(ref, date)
+}
+
+// Overrides gets all overrides sorted by name and the date of the subtask they replace
+func (s *Schedule) Overrides() []Override {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.taskSet.Overrides()
+}
+
+// AddOverride moves or changes the subtask of a recurring task on the date o.Original
+// The recurring task is given by o.ID or, if o.ID is empty, by o.Name, either of which may be its ID or a name
+// no other recurring task has
+// Any earlier override of the same subtask is replaced, and an override without a time zone is given the
+// time zone of its recurring task
+func (s *Schedule) AddOverride(o Override) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.atomically(fmt.Sprintf("override %q on %v", o.Name, dateIntToString(o.Original)), func() error {
+		return s.addOverride(o)
+	})
+}
+
+func (s *Schedule) addOverride(o Override) error {
+	ref := o.ID
+	if ref == "" {
+		ref = o.Name
+	}
+	id, err := s.resolve(ref, s.isRecurring)
+	if err != nil {
+		return fmt.Errorf("AddOverride: %w", err)
+	}
+	r := s.recurringTasks[id]
+	o.ID, o.Name = r.ID, r.Name
+	original, err := intToDate(o.Original)
+	if err != nil {
+		return invalidf("AddOverride: bad date of the subtask to replace")
+	}
+	day := epochDay(original)
+	if len(r.subtaskDays(day, day)) == 0 {
+		return invalidf("AddOverride: %q has no subtask on %v", r.Name, dateIntToString(o.Original))
+	}
+	if s.hasAnti(r.subtaskOn(day)) {
+		return invalidf("AddOverride: the subtask on %v is cancelled", dateIntToString(o.Original))
+	}
+	o.Type = r.Type
+	if o.TimeZone == "" {
+		o.TimeZone = r.TimeZone
+	}
+	t, err := o.Task.normalize(s.snap)
+	if err != nil {
+		return fmt.Errorf("AddOverride: error creating task: %w", err)
+	}
+	o.Task = t
+	s.removeOverride(o.key())
+	if b, ok := s.blackoutOver(o.Task); ok {
+		return blackoutError("AddOverride", b)
+	}
+	if s.hasConflictReplacing(o.Task, o.key()) {
+		return fmt.Errorf("AddOverride: task creates %w", ErrConflict)
+	}
+	s.putOverride(o)
+	return nil
+}
+
+// DeleteOverride restores the subtask of a recurring task on a date that an override replaced
+// The recurring task is named by its ID or a name no other one has
+func (s *Schedule) DeleteOverride(ref string, date int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.atomically(fmt.Sprintf("delete override of %q on %v", ref, dateIntToString(date)), func() error {
+		return s.deleteOverrideOf(ref, date)
+	})
+}
+
+func (s *Schedule) deleteOverrideOf(ref string, date int) error {
+	id, err := s.resolve(ref, s.isRecurring)
+	if err != nil {
+		return fmt.Errorf("DeleteOverride: %w", err)
+	}
+	key := overrideKey(id, date)
+	if _, ok := s.overrides[key]; !ok {
+		return fmt.Errorf("DeleteOverride: %w", ErrNotFound)
+	}
+	s.removeOverride(key)
+	// The restored subtask must not overlap anything that was added in its place
+	original, _ := intToDate(date)
+	if s.hasConflictReplacing(s.recurringTasks[id].subtaskOn(epochDay(original)), key) {
+		return fmt.Errorf("DeleteOverride: restoring the subtask creates a %w", ErrConflict)
+	}
+	return nil
+}
+
+// overridesInRange gets the overrides that overlap the span from start up to end
+func (ts taskSet) overridesInRange(start, end time.Time) []Task {
+	result := []Task{}
+	first := floorDiv(start.Unix(), SECONDS_PER_DAY)
+	last := floorDiv(end.Add(-time.Nanosecond).Unix(), SECONDS_PER_DAY)
+	for _, key := range ts.overrideDays.between(first, last) {
+		if o := ts.overrides[key]; o.overlapsSpan(start, end) {
+			result = append(result, o.Task)
+		}
+	}
+	return result
+}
+
+//!--
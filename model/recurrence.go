@@ -0,0 +1,444 @@
+// Package model provides functionality for creating and managing a schedule of tasks
+// recurrence.go provides the rules that decide which days the subtasks of a recurring task fall on
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// Largest Frequency of each kind of rule
+	MAX_DAILY_FREQUENCY   = 7
+	MAX_WEEKLY_FREQUENCY  = 52
+	MAX_MONTHLY_FREQUENCY = 12
+	// MonthWeek of the last week of the month
+	LAST_WEEK = -1
+	// Most subtasks of a series ending after a count
+	MAX_COUNT = 10000
+	// Last date a series can run to, which is where open-ended series stop
+	MAX_DATE = 99991231
+	// Years after its start that GetSubtasks expands an open-ended series to
+	OPEN_HORIZON_YEARS = 5
+	// Days in 400 years of the Gregorian calendar, after which dates fall on the same days of the week
+	DAYS_PER_CYCLE = 146097
+)
+
+// Weekdays is a set of days of the week
+type Weekdays uint8
+
+// NewWeekdays creates a set of days of the week
+func NewWeekdays(days ...time.Weekday) Weekdays {
+	var w Weekdays
+	for _, d := range days {
+		w |= 1 << uint(d)
+	}
+	return w
+}
+
+// ParseWeekdays converts a comma separated list of days (eg. "Mon,Wed,Fri" or "monday, wednesday")
+// into a set of days of the week
+func ParseWeekdays(s string) (Weekdays, error) {
+	var w Weekdays
+	for _, name := range strings.Split(s, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		found := false
+		for d := time.Sunday; d <= time.Saturday; d++ {
+			full := strings.ToLower(d.String())
+			if len(name) >= 2 && strings.HasPrefix(full, name) {
+				w |= NewWeekdays(d)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("%q is not a day of the week", name)
+		}
+	}
+	return w, nil
+}
+
+// Has returns true if the set contains a day of the week
+func (w Weekdays) Has(d time.Weekday) bool {
+	return w&(1<<uint(d)) != 0
+}
+
+// Days returns the days in the set, starting from Sunday
+func (w Weekdays) Days() []time.Weekday {
+	result := []time.Weekday{}
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if w.Has(d) {
+			result = append(result, d)
+		}
+	}
+	return result
+}
+
+// String lists the days from Monday, as weeks of a weekly rule begin on Monday (eg. "Mon,Wed,Sun")
+func (w Weekdays) String() string {
+	names := []string{}
+	for _, d := range w.mondayFirst() {
+		names = append(names, d.String()[:3])
+	}
+	return strings.Join(names, ",")
+}
+
+// mondayFirst returns the days in the set, starting from Monday
+func (w Weekdays) mondayFirst() []time.Weekday {
+	days := w.Days()
+	if len(days) > 0 && days[0] == time.Sunday {
+		days = append(days[1:], time.Sunday)
+	}
+	return days
+}
+
+// MarshalText encodes the set as a list of days (eg. "Mon,Wed,Fri")
+func (w Weekdays) MarshalText() ([]byte, error) {
+	return []byte(w.String()), nil
+}
+
+// UnmarshalText decodes a list of days written by MarshalText or accepted by ParseWeekdays
+func (w *Weekdays) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*w = 0
+		return nil
+	}
+	parsed, err := ParseWeekdays(string(text))
+	if err != nil {
+		return err
+	}
+	*w = parsed
+	return nil
+}
+
+// ruleKind is the kind of rule a recurring task repeats by
+type ruleKind int
+
+const (
+	ruleDaily     ruleKind = iota // Every Frequency days
+	ruleWeekly                    // On Weekdays, every Frequency weeks
+	ruleMonthDay                  // On day MonthDay, every Frequency months
+	ruleMonthWeek                 // On the MonthWeek-th Weekdays day, every Frequency months
+)
+
+// rule gets the kind of rule the recurring task repeats by
+func (r RecurringTask) rule() ruleKind {
+	switch {
+	case r.MonthDay != 0:
+		return ruleMonthDay
+	case r.MonthWeek != 0:
+		return ruleMonthWeek
+	case r.Weekdays != 0:
+		return ruleWeekly
+	}
+	return ruleDaily
+}
+
+// checkRule checks the recurrence rule of a recurring task
+func (r RecurringTask) checkRule() error {
+	if r.Weekdays >= 1<<7 {
+		return invalidf("bad days of the week")
+	}
+	switch r.rule() {
+	case ruleDaily:
+		if r.Frequency < 1 || r.Frequency > MAX_DAILY_FREQUENCY {
+			return invalidf("bad frequency")
+		}
+	case ruleWeekly:
+		if r.Frequency < 1 || r.Frequency > MAX_WEEKLY_FREQUENCY {
+			return invalidf("bad frequency in weeks")
+		}
+	case ruleMonthDay:
+		if r.MonthDay < 1 || r.MonthDay > 31 || r.MonthWeek != 0 || r.Weekdays != 0 {
+			return invalidf("bad day of the month")
+		}
+		if r.Frequency < 1 || r.Frequency > MAX_MONTHLY_FREQUENCY {
+			return invalidf("bad frequency in months")
+		}
+	case ruleMonthWeek:
+		if (r.MonthWeek < 1 || r.MonthWeek > 5) && r.MonthWeek != LAST_WEEK {
+			return invalidf("bad week of the month")
+		}
+		if len(r.Weekdays.Days()) != 1 {
+			return invalidf("a week of the month needs exactly one day of the week")
+		}
+		if r.Frequency < 1 || r.Frequency > MAX_MONTHLY_FREQUENCY {
+			return invalidf("bad frequency in months")
+		}
+	}
+	first, _ := intToDate(r.Date)
+	if !r.onRule(epochDay(first)) {
+		return invalidf("start date is not on a day of the rule")
+	}
+	if last, _ := intToDate(MAX_DATE); r.lastDay() > epochDay(last) {
+		return invalidf("series ends after %v", dateIntToString(MAX_DATE))
+	}
+	// Subtasks may not overlap each other
+	if r.Duration > time.Duration(r.minGap())*24*time.Hour {
+		return invalidf("duration longer than the days between subtasks")
+	}
+	return nil
+}
+
+// minGap returns the fewest days between two subtasks of the rule
+func (r RecurringTask) minGap() int {
+	switch r.rule() {
+	case ruleWeekly:
+		// Count days from Monday, from the last day of one week of the rule to the first of the next
+		pos := []int{}
+		for _, d := range r.Weekdays.mondayFirst() {
+			pos = append(pos, (int(d)+6)%7)
+		}
+		gap := 7*r.Frequency - (pos[len(pos)-1] - pos[0])
+		for i := 1; i < len(pos); i++ {
+			if g := pos[i] - pos[i-1]; g < gap {
+				gap = g
+			}
+		}
+		return gap
+	case ruleMonthDay, ruleMonthWeek:
+		// The shortest month has 28 days
+		return 28 * r.Frequency
+	}
+	return r.Frequency
+}
+
+// ruleString describes the recurrence rule (eg. "every 2 weeks on Mon,Wed")
+func (r RecurringTask) ruleString() string {
+	every := func(n int, unit string) string {
+		if n == 1 {
+			return "every " + unit
+		}
+		return fmt.Sprintf("every %d %ss", n, unit)
+	}
+	switch r.rule() {
+	case ruleWeekly:
+		return fmt.Sprintf("%s on %v", every(r.Frequency, "week"), r.Weekdays)
+	case ruleMonthDay:
+		return fmt.Sprintf("on day %d of %s", r.MonthDay, every(r.Frequency, "month"))
+	case ruleMonthWeek:
+		week := "last"
+		if r.MonthWeek != LAST_WEEK {
+			week = []string{"1st", "2nd", "3rd", "4th", "5th"}[r.MonthWeek-1]
+		}
+		return fmt.Sprintf("on the %s %v of %s", week, r.Weekdays.Days()[0], every(r.Frequency, "month"))
+	}
+	return every(r.Frequency, "day")
+}
+
+// onRule returns true if the rule puts a subtask on a day counted from the Unix epoch, ignoring the
+// start and end dates
+func (r RecurringTask) onRule(day int64) bool {
+	first, _ := intToDate(r.Date)
+	firstDay := epochDay(first)
+	switch r.rule() {
+	case ruleWeekly:
+		// Weeks begin on Monday and count from the week of the start date
+		week := floorDiv(day-mondayOf(firstDay), 7)
+		return week%int64(r.Frequency) == 0 && r.Weekdays.Has(epochWeekday(day))
+	case ruleMonthDay, ruleMonthWeek:
+		date := epochDate(day)
+		months := (date.Year()-first.Year())*12 + int(date.Month()-first.Month())
+		return months%r.Frequency == 0 && r.dayInMonth(date.Year(), date.Month()) == date.Day()
+	}
+	return floorDiv(day-firstDay, int64(r.Frequency))*int64(r.Frequency) == day-firstDay
+}
+
+// dayInMonth returns the day of a month that a monthly rule falls on, or 0 if the month has no such day
+func (r RecurringTask) dayInMonth(year int, month time.Month) int {
+	days := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	if r.rule() == ruleMonthDay {
+		if r.MonthDay > days {
+			return 0
+		}
+		return r.MonthDay
+	}
+	weekday := r.Weekdays.Days()[0]
+	if r.MonthWeek == LAST_WEEK {
+		last := time.Date(year, month, days, 0, 0, 0, 0, time.UTC).Weekday()
+		return days - (int(last)-int(weekday)+7)%7
+	}
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC).Weekday()
+	day := 1 + (int(weekday)-int(first)+7)%7 + 7*(r.MonthWeek-1)
+	if day > days {
+		return 0
+	}
+	return day
+}
+
+// lastDay returns the day, counted from the Unix epoch, of the last subtask of the series
+// A series with an EndDate ends on the last subtask starting before the end date, or on it if the subtask has a
+// duration, a series with a Count on its Count-th subtask and an open-ended series on MAX_DATE
+func (r RecurringTask) lastDay() int64 {
+	switch {
+	case r.Count > 0:
+		return r.nthDay(int64(r.Count - 1))
+	case r.EndDate == 0:
+		last, _ := intToDate(MAX_DATE)
+		return epochDay(last)
+	}
+	end, err := intToDate(r.EndDate)
+	if err != nil {
+		return 0
+	}
+	if r.Duration == 0 {
+		return epochDay(end) - 1
+	}
+	return epochDay(end)
+}
+
+// nthDay returns the day, counted from the Unix epoch, of subtask n of the rule, counting from 0
+// Days past MAX_DATE are returned as the day after it
+func (r RecurringTask) nthDay(n int64) int64 {
+	first, _ := intToDate(r.Date)
+	firstDay := epochDay(first)
+	last, _ := intToDate(MAX_DATE)
+	freq := int64(r.Frequency)
+	switch r.rule() {
+	case ruleWeekly:
+		// Subtask n is on day n of the rule counting from the start, with len(pos) days in each week of the rule
+		pos := []int64{}
+		for _, d := range r.Weekdays.mondayFirst() {
+			if d == epochWeekday(firstDay) {
+				n += int64(len(pos))
+			}
+			pos = append(pos, int64((d+6)%7))
+		}
+		k := int64(len(pos))
+		return minInt64(mondayOf(firstDay)+(n/k)*7*freq+pos[n%k], epochDay(last)+1)
+	case ruleMonthDay, ruleMonthWeek:
+		for month := time.Date(first.Year(), first.Month(), 1, 0, 0, 0, 0, time.UTC); !month.After(last); month = month.AddDate(0, r.Frequency, 0) {
+			if day := r.dayInMonth(month.Year(), month.Month()); day != 0 {
+				if n == 0 {
+					return epochDay(month) + int64(day-1)
+				}
+				n--
+			}
+		}
+		return epochDay(last) + 1
+	}
+	return minInt64(firstDay+n*freq, epochDay(last)+1)
+}
+
+// subtaskIndex returns the number of the subtask of the rule on a day counted from the Unix epoch, counting
+// from 0, and is the inverse of nthDay for days on the rule
+func (r RecurringTask) subtaskIndex(day int64) int64 {
+	first, _ := intToDate(r.Date)
+	firstDay := epochDay(first)
+	freq := int64(r.Frequency)
+	switch r.rule() {
+	case ruleWeekly:
+		pos := map[time.Weekday]int64{}
+		for _, d := range r.Weekdays.mondayFirst() {
+			pos[d] = int64(len(pos))
+		}
+		weeks := floorDiv(mondayOf(day)-mondayOf(firstDay), 7*freq)
+		return weeks*int64(len(pos)) + pos[epochWeekday(day)] - pos[epochWeekday(firstDay)]
+	case ruleMonthDay, ruleMonthWeek:
+		n := int64(0)
+		date := epochDate(day)
+		for month := time.Date(first.Year(), first.Month(), 1, 0, 0, 0, 0, time.UTC); month.Before(date); month = month.AddDate(0, r.Frequency, 0) {
+			if d := r.dayInMonth(month.Year(), month.Month()); d != 0 && epochDay(month)+int64(d-1) < day {
+				n++
+			}
+		}
+		return n
+	}
+	return floorDiv(day-firstDay, freq)
+}
+
+// repeatDays returns a number of days after which the rule puts subtasks on the same days again
+func (r RecurringTask) repeatDays() int64 {
+	switch r.rule() {
+	case ruleWeekly:
+		return 7 * int64(r.Frequency)
+	case ruleMonthDay, ruleMonthWeek:
+		// The calendar repeats every 400 years, which is 4800 months, so the rule repeats after the
+		// smallest number of cycles that is a whole number of its months
+		g, _, _ := extendedGCD(4800, int64(r.Frequency))
+		return DAYS_PER_CYCLE * int64(r.Frequency) / g
+	}
+	return int64(r.Frequency)
+}
+
+// eachSubtask calls fn with the subtasks starting from day from to day to in order, stopping when fn
+// returns false
+// Subtasks are found a year at a time so that long and open-ended series are never expanded in full
+func (r RecurringTask) eachSubtask(from, to int64, fn func(Task) bool) {
+	const chunk = 366
+	for ; from <= to; from += chunk {
+		for _, d := range r.subtaskDays(from, minInt64(from+chunk-1, to)) {
+			if !fn(r.subtaskOn(d)) {
+				return
+			}
+		}
+	}
+}
+
+// subtaskDays returns the days, counted from the Unix epoch, of the subtasks starting from day from to
+// day to in the recurring task's time zone
+func (r RecurringTask) subtaskDays(from, to int64) []int64 {
+	result := []int64{}
+	first, err := intToDate(r.Date)
+	if err != nil {
+		return result
+	}
+	firstDay, lastDay := epochDay(first), r.lastDay()
+	from, to = maxInt64(from, firstDay), minInt64(to, lastDay)
+	switch r.rule() {
+	case ruleDaily:
+		freq := int64(r.Frequency)
+		for d := firstDay + ceilDiv(from-firstDay, freq)*freq; d <= to; d += freq {
+			result = append(result, d)
+		}
+	case ruleWeekly:
+		freq := int64(r.Frequency)
+		for d := from; d <= to; d++ {
+			if r.onRule(d) {
+				result = append(result, d)
+			}
+			if epochWeekday(d) == time.Sunday {
+				// Skip the weeks between the weeks of the rule
+				if weeks := floorDiv(d+1-mondayOf(firstDay), 7) % freq; weeks != 0 {
+					d += 7 * (freq - weeks)
+				}
+			}
+		}
+	default:
+		date := epochDate(from)
+		for month := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC); epochDay(month) <= to; month = month.AddDate(0, 1, 0) {
+			if day := r.dayInMonth(month.Year(), month.Month()); day != 0 {
+				d := epochDay(month) + int64(day-1)
+				if d >= from && d <= to && r.onRule(d) {
+					result = append(result, d)
+				}
+			}
+		}
+	}
+	return result
+}
+
+// subtaskOn returns the subtask of the recurring task starting on a day counted from the Unix epoch
+func (r RecurringTask) subtaskOn(day int64) Task {
+	t := r.Task
+	t.Date = dateToInt(epochDate(day))
+	return t
+}
+
+// epochDate returns midnight in UTC on a day counted from the Unix epoch
+func epochDate(day int64) time.Time {
+	return time.Unix(day*SECONDS_PER_DAY, 0).UTC()
+}
+
+// epochWeekday returns the day of the week of a day counted from the Unix epoch, which was a Thursday
+func epochWeekday(day int64) time.Weekday {
+	return time.Weekday((day%7 + 7 + 4) % 7)
+}
+
+// mondayOf returns the Monday beginning the week of a day counted from the Unix epoch
+func mondayOf(day int64) int64 {
+	return day - int64((epochWeekday(day)+6)%7)
+}
+
+//!--
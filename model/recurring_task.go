@@ -4,46 +4,103 @@ package model
 
 import (
 	"fmt"
-	"math"
 	"time"
 )
 
 // RecurringTask implements a recurrint task in the schedule
+// Subtasks repeat every Frequency days, or by a weekly or monthly rule if Weekdays, MonthDay or
+// MonthWeek is set, see recurrence.go
+// The series ends on EndDate, after Count subtasks, or never if neither is set
 type RecurringTask struct {
 	Task
-	EndDate   int
-	Frequency int
+	EndDate   int      // Last date of the series, or 0
+	Count     int      // Number of subtasks of the series, or 0
+	Frequency int      // Days, weeks or months between subtasks, depending on the rule
+	Weekdays  Weekdays // Days of the week of a weekly rule, or the day of the week of a MonthWeek rule
+	MonthDay  int      // Day of the month of a monthly rule (eg. 15)
+	MonthWeek int      // Week of the month of a monthly rule by day of the week (eg. 2 for the 2nd Tuesday)
 }
 
-func NewRecurringTask(name, taskType string, date int, startTime, duration float32, endDate, frequency int) (RecurringTask, error) {
-	t, err := NewTask(name, taskType, date, startTime, duration)
+// NewRecurringTask creates a recurring task with its start time and duration rounded to the nearest DEFAULT_SNAP
+func NewRecurringTask(name, taskType string, date int, startTime, duration time.Duration, endDate, frequency int) (RecurringTask, error) {
+	return newRecurringTask(name, taskType, date, startTime, duration, endDate, frequency, DEFAULT_SNAP)
+}
+
+// newRecurringTask creates a recurring task with its start time and duration rounded to the nearest multiple of snap
+func newRecurringTask(name, taskType string, date int, startTime, duration time.Duration, endDate, frequency int, snap time.Duration) (RecurringTask, error) {
+	t, err := newTask(name, taskType, date, startTime, duration, snap)
 	if err != nil {
 		return RecurringTask{}, err
 	}
-	start, _ := intToDate(date)
-	end, err := intToDate(endDate)
-	if err != nil {
-		return RecurringTask{}, fmt.Errorf("bad end date")
-	}
-	if end.Before(start) {
-		return RecurringTask{}, fmt.Errorf("end date before start date")
-	}
-	if frequency < 1 || frequency > 7 {
-		return RecurringTask{}, fmt.Errorf("bad frequency")
-	}
-	// if frequency != 1 && frequency != 7 {
-	// 	return RecurringTask{}, fmt.Errorf("bad frequency")
-	// }
 	result := RecurringTask{
 		Task:      t,
 		EndDate:   endDate,
 		Frequency: frequency,
 	}
+	if err := result.check(); err != nil {
+		return RecurringTask{}, err
+	}
 	return result, nil
 }
 
+// normalize checks the fields of a recurring task built by the caller and rounds its times to the
+// nearest multiple of snap
+func (r RecurringTask) normalize(snap time.Duration) (RecurringTask, error) {
+	t, err := r.Task.normalize(snap)
+	if err != nil {
+		return RecurringTask{}, err
+	}
+	r.Task = t
+	if err := r.check(); err != nil {
+		return RecurringTask{}, err
+	}
+	return r, nil
+}
+
+// check checks the end and recurrence rule of a recurring task
+func (r RecurringTask) check() error {
+	if r.Count < 0 || r.Count > MAX_COUNT {
+		return invalidf("bad count")
+	}
+	if r.EndDate != 0 {
+		if r.Count != 0 {
+			return invalidf("both an end date and a count")
+		}
+		start, _ := intToDate(r.Date)
+		end, err := intToDate(r.EndDate)
+		if err != nil {
+			return invalidf("bad end date")
+		}
+		if end.Before(start) {
+			return invalidf("end date before start date")
+		}
+	}
+	return r.checkRule()
+}
+
 func (r RecurringTask) String() string {
-	return r.Task.String() + fmt.Sprintf("\nEnd Date: %v\nFrequency: %v", dateIntToString(r.EndDate), r.Frequency)
+	end := "End Date: " + dateIntToString(r.EndDate)
+	switch {
+	case r.Count > 0:
+		end = fmt.Sprintf("Count: %v", r.Count)
+	case r.EndDate == 0:
+		end = "End Date: none"
+	}
+	if r.rule() != ruleDaily {
+		return r.Task.String() + fmt.Sprintf("\n%v\nRepeats: %v", end, r.ruleString())
+	}
+	return r.Task.String() + fmt.Sprintf("\n%v\nFrequency: %v", end, r.Frequency)
+}
+
+// Open returns true if the series has no end date or count
+func (r RecurringTask) Open() bool {
+	return r.EndDate == 0 && r.Count == 0
+}
+
+// sameTimes returns true if two recurring tasks differ in at most their names and types
+func (r RecurringTask) sameTimes(op RecurringTask) bool {
+	r.Name, r.Type = op.Name, op.Type
+	return r == op
 }
 
 func (r RecurringTask) GetEndYear() int {
@@ -58,38 +115,63 @@ func (r RecurringTask) GetEndDay() int {
 	return r.EndDate % 100
 }
 
+// GetEndDate gets the end of the last possible subtask as a Time struct in the task's time zone
+// The end of a series with a Count is the end of its last subtask, and an open-ended series has no end
 func (r RecurringTask) GetEndDate() (time.Time, error) {
-	date, err := intToDate(r.EndDate)
+	last := r.Task
+	switch {
+	case r.Count > 0:
+		last = r.subtaskOn(r.lastDay())
+	case r.EndDate == 0:
+		return time.Time{}, fmt.Errorf("GetEndDate: open-ended series has no end")
+	default:
+		last.Date = r.EndDate
+	}
+	date, err := last.GetStartDate()
 	if err != nil {
 		return date, fmt.Errorf("GetEndDate: %v", err)
 	}
-	// Account for start time
-	date = date.Add((time.Hour * time.Duration(r.StartTime)) +
-		(time.Minute * 60 * time.Duration(float64(r.StartTime)-math.Floor(float64(r.StartTime)))))
 	// Account for duration
-	date = date.Add((time.Hour * time.Duration(r.Duration)) +
-		(time.Minute * 60 * time.Duration(float64(r.Duration)-math.Floor(float64(r.Duration)))))
-	return date, nil
+	return date.Add(r.Duration), nil
 }
 
 // GetSubtasks expands the recurring tasks into a series of subtasks
+// Subtasks are Frequency calendar days apart in the task's time zone, so they keep their wall clock
+// start time when daylight saving time begins or ends
+// An open-ended series is expanded up to OPEN_HORIZON_YEARS after it starts, so use GetSubtasksInRange
+// for later subtasks
 func (r RecurringTask) GetSubtasks() ([]Task, error) {
 	result := []Task{}
-	startDate, err := r.GetStartDate()
-	if err != nil {
+	if _, err := r.GetStartDate(); err != nil {
 		return result, fmt.Errorf("GetSubtasks: %v", err)
 	}
-	endDate, err := r.GetEndDate()
-	if err != nil {
+	first, _ := r.GetStartDateWithoutTime()
+	to := r.lastDay()
+	if r.Open() {
+		to = epochDay(first.AddDate(OPEN_HORIZON_YEARS, 0, 0)) - 1
+	} else if _, err := r.GetEndDate(); err != nil {
 		return result, fmt.Errorf("GetSubtasks: %v", err)
 	}
-	for startDate.Before(endDate) {
-		t, err := NewTask(r.Name, r.Type, dateToInt(startDate), r.StartTime, r.Duration)
-		if err != nil {
-			return []Task{}, err
+	for _, d := range r.subtaskDays(epochDay(first), to) {
+		result = append(result, r.subtaskOn(d))
+	}
+	return result, nil
+}
+
+// GetSubtasksInRange expands only the subtasks that overlap the span from start up to end
+func (r RecurringTask) GetSubtasksInRange(start, end time.Time) ([]Task, error) {
+	result := []Task{}
+	if _, err := r.GetStartDate(); err != nil {
+		return result, fmt.Errorf("GetSubtasksInRange: %v", err)
+	}
+	// Only subtasks starting less than a subtask's duration before the span, up to the day it ends, can
+	// overlap it
+	loc := r.Location()
+	from, to := epochDay(start.Add(-r.Duration).In(loc)), epochDay(end.In(loc))
+	for _, d := range r.subtaskDays(from, to) {
+		if t := r.subtaskOn(d); t.overlapsSpan(start, end) {
+			result = append(result, t)
 		}
-		result = append(result, t)
-		startDate = startDate.Add(24 * time.Hour * time.Duration(r.Frequency))
 	}
 	return result, nil
 }
@@ -97,68 +179,21 @@ func (r RecurringTask) GetSubtasks() ([]Task, error) {
 // GetOverlappingSubtasks returns the set of subtasks that overlap a given task
 func (r RecurringTask) GetOverlappingSubtasks(task Task) ([]Task, error) {
 	result := []Task{}
-	rStartDate, err := r.GetStartDateWithoutTime()
-	if err != nil {
-		return result, fmt.Errorf("GetOverlappingSubtasks: %v", err)
-	}
-	rEndDate, err := r.GetEndDate()
-	if err != nil {
+	if _, err := r.GetStartDate(); err != nil {
 		return result, fmt.Errorf("GetOverlappingSubtasks: %v", err)
 	}
-	taskDate, err := task.GetStartDateWithoutTime()
+	taskStart, err := task.GetStartDate()
 	if err != nil {
 		return result, fmt.Errorf("GetOverlappingSubtasks: %v", err)
 	}
-	if rEndDate.Before(taskDate) {
-		return result, nil
-	}
-	// Difference in start days in days
-	startDayDelta := int(taskDate.Unix()-rStartDate.Unix()) / (60 * 60 * 24)
-	if startDayDelta < 0 {
-		// Only have to check the very first recurring subtask
-		t, err := NewTask(r.Name, r.Type, r.Date, r.StartTime, r.Duration)
-		if err != nil {
-			return result, fmt.Errorf("GetOverlappingSubtasks: %v", err)
-		}
-		if t.Overlaps(task) {
+	taskEnd := taskStart.Add(task.Duration)
+	// Only subtasks starting less than a subtask's duration before the task starts, up to the day it ends,
+	// can overlap it, counting days in the recurring task's time zone
+	loc := r.Location()
+	for _, d := range r.subtaskDays(epochDay(taskStart.Add(-r.Duration).In(loc)), epochDay(taskEnd.In(loc))) {
+		if t := r.subtaskOn(d); t.Overlaps(task) {
 			result = append(result, t)
 		}
-		return result, nil
-	}
-	// Reintroduce start times into start dates
-	rStartDate, _ = r.GetStartDate()
-	taskDate, _ = task.GetStartDate()
-	if startDayDelta%r.Frequency == 0 {
-		// Get the subtask for this day
-		t, err := NewTask(r.Name, r.Type, task.Date, r.StartTime, r.Duration)
-		if err != nil {
-			return result, fmt.Errorf("GetOverlappingSubtasks: error creating today subtask: %v", err)
-		}
-		if t.Overlaps(task) {
-			result = append(result, t)
-		}
-	}
-	// Have to check the cycle before and the cycle after for potential overlaps
-	prevCycleDistance := startDayDelta % r.Frequency
-	if startDayDelta == 0 {
-		prevCycleDistance = r.Frequency
-	}
-	nextCycleDistance := r.Frequency - (startDayDelta % r.Frequency)
-	yesterday := time.Unix(taskDate.Unix()-((24*60*60)*int64(prevCycleDistance)), 0)
-	tomorrow := time.Unix(taskDate.Unix()+((24*60*60)*int64(nextCycleDistance)), 0)
-	yt, err := NewTask(r.Name, r.Type, dateToInt(yesterday), r.StartTime, r.Duration)
-	if err != nil {
-		return result, fmt.Errorf("GetOverlappingSubtasks: error creating yesterday task: %v", err)
-	}
-	tt, err := NewTask(r.Name, r.Type, dateToInt(tomorrow), r.StartTime, r.Duration)
-	if err != nil {
-		return result, fmt.Errorf("GetOverlappingSubtasks: error creating tomorrow task: %v", err)
-	}
-	if rStartDate.Before(yesterday) && yt.Overlaps(task) {
-		result = append(result, yt)
-	}
-	if tomorrow.Before(rEndDate) && tt.Overlaps(task) {
-		result = append(result, tt)
 	}
 	return result, nil
 }
@@ -173,35 +208,213 @@ func (r RecurringTask) Overlaps(task Task) bool {
 
 // GetOverlappingSubtasksRecurring returns the set of subtasks that overlap a recurring task
 // This can also find the overlap with a non recurring task but is less optimal
+// Every subtask in the span of both series is expanded, so use FirstOverlapRecurring to only find whether
+// the tasks overlap, and for two open-ended series only the overlaps up to OPEN_HORIZON_YEARS after the later
+// start are returned
 func (r RecurringTask) GetOverlappingSubtasksRecurring(task RecurringTask) ([]Task, error) {
 	result := []Task{}
-	startDate, err := r.GetStartDate()
-	if err != nil {
+	if _, err := r.GetStartDate(); err != nil {
 		return result, fmt.Errorf("GetOverlappingSubtasksRecurring: %v", err)
 	}
-	endDate, err := r.GetEndDate()
-	if err != nil {
-		return result, fmt.Errorf("GetOverlappingSubtasksRecurring: %v", err)
+	from, to := r.overlapDays(task)
+	if r.Open() && task.Open() {
+		later := epochDate(r.laterStart(task))
+		to = epochDay(later.AddDate(OPEN_HORIZON_YEARS, 0, 0)) - 1
 	}
-	for startDate.Before(endDate) {
-		t, err := NewTask(r.Name, r.Type, dateToInt(startDate), r.StartTime, r.Duration)
-		if err != nil {
-			return result, fmt.Errorf("GetOverlappingSubtasksRecurring: %v", err)
-		}
+	r.eachSubtask(from, to, func(t Task) bool {
 		if task.Overlaps(t) {
 			result = append(result, t)
 		}
-		startDate = startDate.Add(24 * time.Hour * time.Duration(r.Frequency))
-	}
+		return true
+	})
 	return result, nil
 }
 
+// spanDays returns the first and last day, counted from the Unix epoch in UTC like taskDays, touched by
+// the subtasks of a recurring task
+func (r RecurringTask) spanDays() (int64, int64) {
+	first, _ := taskDays(r.Task)
+	_, last := taskDays(r.subtaskOn(r.lastDay()))
+	return first, last
+}
+
+// laterStart returns the day, counted from the Unix epoch, of the later start date of two recurring tasks
+func (r RecurringTask) laterStart(task RecurringTask) int64 {
+	a, _ := intToDate(r.Date)
+	b, _ := intToDate(task.Date)
+	return maxInt64(epochDay(a), epochDay(b))
+}
+
+// overlapDays returns the days, counted from the Unix epoch, that the subtasks of this recurring task
+// overlapping a subtask of another can start on
+func (r RecurringTask) overlapDays(task RecurringTask) (int64, int64) {
+	// A subtask of r can start up to its duration before the other series starts, and a day either side for
+	// the difference between time zones
+	first, _ := intToDate(task.Date)
+	from := epochDay(first) - int64(r.Duration/(24*time.Hour)) - 2
+	to := minInt64(r.lastDay(), task.lastDay()+int64(task.Duration/(24*time.Hour))+2)
+	return from, to
+}
+
+// OverlapsRecurring returns true if any subtask of this recurring task overlaps a subtask of another
 func (r RecurringTask) OverlapsRecurring(task RecurringTask) bool {
-	l, err := r.GetOverlappingSubtasksRecurring(task)
+	_, ok := r.FirstOverlapRecurring(task)
+	return ok
+}
+
+// FirstOverlapRecurring returns the first subtask of this recurring task that overlaps a subtask of
+// another and a bool to indicate if such a subtask was found
+// Rather than expanding the subtasks, it solves for the subtask numbers in constant time: subtask i of r
+// and subtask j of task start (a - b) + (i*P - j*Q) days apart, where a and b are the first starts and P
+// and Q the frequencies, and i*P - j*Q can only be a multiple of gcd(P, Q)
+// Tasks in the same time zone are compared by wall clock time, and checked in real time around the daylight
+// saving time changes where the two differ; tasks in different time zones or with weekly or monthly rules are
+// compared by expanding the subtasks of this task in the span of both series, which for two open-ended series
+// ends once both rules have repeated from the later start
+func (r RecurringTask) FirstOverlapRecurring(task RecurringTask) (Task, bool) {
+	const minutesPerDay = 24 * 60
+	aStart, err := r.GetStartDate()
 	if err != nil {
-		fmt.Printf("Warning: RecurringTask.OverlapsRecurring: %v\n", err)
+		return Task{}, false
 	}
-	return len(l) > 0
+	bStart, err := task.GetStartDate()
+	if err != nil {
+		return Task{}, false
+	}
+	sameZone := r.Location().String() == task.Location().String()
+	// The subtasks of two open-ended series are on the same days again after a common multiple of their
+	// repeats, and at the same real times after a whole number of 400 year cycles as well
+	repeat := lcm(r.repeatDays(), task.repeatDays())
+	cycle := r.laterStart(task) + lcm(repeat, DAYS_PER_CYCLE)
+	if !sameZone || r.rule() != ruleDaily || task.rule() != ruleDaily {
+		from, to := r.overlapDays(task)
+		if r.Open() && task.Open() {
+			if !sameZone {
+				repeat = lcm(repeat, DAYS_PER_CYCLE)
+			}
+			to = minInt64(to, r.laterStart(task)+repeat)
+		}
+		var result Task
+		found := false
+		r.eachSubtask(from, to, func(sub Task) bool {
+			if task.Overlaps(sub) {
+				result, found = sub, true
+			}
+			return !found
+		})
+		if found || !sameZone || !(r.Open() && task.Open()) {
+			return result, found
+		}
+		return r.firstOverlapAroundChanges(task, to+1, cycle, result, found)
+	}
+	nA, nB := r.numSubtasks(), task.numSubtasks()
+	if nA == 0 || nB == 0 {
+		return Task{}, false
+	}
+	aStart, bStart = wallClock(aStart), wallClock(bStart)
+	p, q := int64(r.Frequency), int64(task.Frequency)
+	g, x, y := extendedGCD(p, q)
+	// Minutes between the first starts and the durations of the subtasks
+	delta := int64(aStart.Sub(bStart).Minutes())
+	durA, durB := int64(r.Duration/time.Minute), int64(task.Duration/time.Minute)
+	// Subtasks overlap when the start of r's is less than durA minutes before and less than durB minutes
+	// after the start of task's, or at the same time if either takes any time, which happens for at most
+	// (durA + durB) / (gcd(P, Q) days) + 2 multiples k
+	lo, hi := -durA, durB
+	if durA == 0 && durB > 0 {
+		lo--
+	}
+	if durB == 0 && durA > 0 {
+		hi++
+	}
+	step := g * minutesPerDay
+	best := int64(-1)
+	for k := floorDiv(lo-delta, step) + 1; delta+k*step < hi; k++ {
+		// Solve i*P - j*Q = k*g for the earliest subtask numbers in range
+		i0, j0 := x*k, -y*k
+		tLo := maxInt64(ceilDiv(-i0, q/g), ceilDiv(-j0, p/g))
+		tHi := minInt64(floorDiv(nA-1-i0, q/g), floorDiv(nB-1-j0, p/g))
+		// The wall clock shows an overlap that is not there when a daylight saving time change falls between
+		// the subtasks, so each solution is checked in real time
+		for t := tLo; t <= tHi && (best < 0 || i0+t*(q/g) < best); t++ {
+			if i := i0 + t*(q/g); r.subtask(i).Overlaps(task.subtask(j0 + t*(p/g))) {
+				best = i
+				break
+			}
+		}
+	}
+	var result Task
+	if best >= 0 {
+		result = r.subtask(best)
+	}
+	from, to := r.overlapDays(task)
+	if r.Open() && task.Open() {
+		to = minInt64(to, cycle)
+	}
+	return r.firstOverlapAroundChanges(task, from, to, result, best >= 0)
+}
+
+// firstOverlapAroundChanges returns the first subtask of this recurring task that overlaps a subtask of another
+// in the same time zone around a change of the zone's UTC offset from day from to day to, if it comes before
+// the subtask found already
+// Only around the changes can subtasks that are apart on the wall clock overlap, and only if they are less than
+// the change apart, so series that are never that close are not checked
+func (r RecurringTask) firstOverlapAroundChanges(task RecurringTask, from, to int64, result Task, found bool) (Task, bool) {
+	if !r.nearOnClock(task) {
+		return result, found
+	}
+	if found {
+		date, _ := intToDate(result.Date)
+		to = minInt64(to, epochDay(date))
+	}
+	earlier := false
+	eachZoneChange(r.Location(), from, to, func(day int64) bool {
+		// A subtask spanning the change starts on its day or the day after in time zones ahead of UTC
+		r.eachSubtask(day-int64(r.Duration/(24*time.Hour))-1, day+int64(task.Duration/(24*time.Hour))+1, func(sub Task) bool {
+			if (!found || sub.Date < result.Date) && task.Overlaps(sub) {
+				result, found, earlier = sub, true, true
+			}
+			return !earlier
+		})
+		return !earlier
+	})
+	return result, found
+}
+
+// nearOnClock checks if the subtasks of two recurring tasks are ever less than the largest change of a UTC offset
+// in use apart on the wall clock, whatever days they are on
+func (r RecurringTask) nearOnClock(task RecurringTask) bool {
+	const (
+		day      = 24 * time.Hour
+		maxShift = 2 * time.Hour
+	)
+	if r.Duration+task.Duration+2*maxShift >= day {
+		return true
+	}
+	// Time from the start of task's subtasks to the start of r's on a 24 hour clock
+	d := ((r.StartTime-task.StartTime)%day + day) % day
+	return d < task.Duration+maxShift || day-d < r.Duration+maxShift
+}
+
+// numSubtasks returns the number of subtasks of a recurring task with a daily rule
+func (r RecurringTask) numSubtasks() int64 {
+	start, err := intToDate(r.Date)
+	if err != nil {
+		return 0
+	}
+	days := r.lastDay() - epochDay(start)
+	if days < 0 {
+		return 0
+	}
+	return days/int64(r.Frequency) + 1
+}
+
+// subtask returns subtask i of a recurring task with a daily rule, counting from 0
+func (r RecurringTask) subtask(i int64) Task {
+	t := r.Task
+	date, _ := intToDate(r.Date)
+	t.Date = dateToInt(date.AddDate(0, 0, int(i)*r.Frequency))
+	return t
 }
 
 //!--
@@ -4,316 +4,872 @@ package model
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"sort"
+	"sync"
 	"time"
 )
 
+// Schedule stores the tasks of a schedule
+// A schedule is safe to use from multiple goroutines; queries share a read lock and changes take
+// the write lock, so every change is applied and conflict checked as a whole
 type Schedule struct {
-	TransientTasks map[string]Task
-	AntiTasks      map[string]AntiTask
-	RecurringTasks map[string]RecurringTask
+	mu sync.RWMutex // Guards every field below
+	taskSet
+	journal   []change       // Changes made by the operations in progress, see transaction.go
+	depth     int            // Number of nested operations in progress
+	tx        *Tx            // The open transaction, if any
+	undoStack []operation    // Completed operations, most recent last, see history.go
+	redoStack []operation    // Undone operations, most recently undone last
+	weekStart time.Weekday   // First day of the week for the week queries
+	location  *time.Location // Time zone given to tasks added without one
+	snap      time.Duration  // Granularity that start times and durations are rounded to
+	types     *TypeRegistry  // Task types the schedule accepts, see types.go
 }
 
 // NewSchedule creates and returns a schedule
 func NewSchedule() *Schedule {
-	return &Schedule{
-		TransientTasks: map[string]Task{},
-		AntiTasks:      map[string]AntiTask{},
-		RecurringTasks: map[string]RecurringTask{},
+	return &Schedule{taskSet: newTaskSet(), weekStart: time.Monday, location: time.UTC, snap: DEFAULT_SNAP, types: DefaultTypes()}
+}
+
+// Snap gets the granularity that the start times and durations of added tasks are rounded to
+func (s *Schedule) Snap() time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.snap
+}
+
+// SetSnap sets the granularity that the start times and durations of added and edited tasks are
+// rounded to, which may be 1, 5 or 15 minutes
+// Tasks already in the schedule keep their times
+func (s *Schedule) SetSnap(snap time.Duration) error {
+	if snap != time.Minute && snap != 5*time.Minute && snap != 15*time.Minute {
+		return invalidf("SetSnap: %v is not 1, 5 or 15 minutes", snap)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snap = snap
+	return nil
+}
+
+// Location gets the time zone given to tasks added without one
+func (s *Schedule) Location() *time.Location {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.location
+}
+
+// SetLocation sets the time zone given to tasks added without one
+// Tasks already in the schedule keep their time zones
+func (s *Schedule) SetLocation(loc *time.Location) error {
+	if loc == nil {
+		return invalidf("SetLocation: no time zone given")
+	}
+	if _, err := loadLocation(zoneName(loc)); err != nil {
+		return invalidf("SetLocation: %q is not a named time zone", loc)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.location = loc
+	return nil
+}
+
+// withDefaultZone gives a task without a time zone the schedule's default time zone
+func (s *Schedule) withDefaultZone(t Task) Task {
+	if t.TimeZone == "" {
+		t.TimeZone = zoneName(s.location)
 	}
+	return t
+}
+
+// AddTask adds a task built by the caller, which unlike the other add methods can set its time zone
+// The task is added as a transient task, recurring subtask or anti task according to its type
+func (s *Schedule) AddTask(t Task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.atomically(fmt.Sprintf("add task %q", t.Name), func() error {
+		return s.addTask(t)
+	})
+}
+
+func (s *Schedule) addTask(t Task) error {
+	switch {
+	case s.types.is(t.Type, TRANSIENT):
+		return s.addTransientTask(t)
+	case s.types.is(t.Type, ANTI):
+		return s.addAntiTask(AntiTask{Task: t})
+	case s.types.is(t.Type, RECURRING):
+		return s.addSubtask(t)
+	}
+	return invalidf("AddTask: %q is not a task type", t.Type)
 }
 
 // AddTransientTask creates and adds a transient task to the schedule
-func (s *Schedule) AddTransientTask(name, taskType string, date int, startTime, duration float32) error {
-	if len(name) == 0 {
-		return fmt.Errorf("AddTransientTask: name cannot be empty")
+func (s *Schedule) AddTransientTask(name, taskType string, date int, startTime, duration time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.atomically(fmt.Sprintf("add transient task %q", name), func() error {
+		return s.addTransientTask(Task{Name: name, Type: taskType, Date: date, StartTime: startTime, Duration: duration})
+	})
+}
+
+func (s *Schedule) addTransientTask(t Task) error {
+	if len(t.Name) == 0 {
+		return invalidf("AddTransientTask: name cannot be empty")
 	}
-	if s.hasNameConflict(name) {
-		return fmt.Errorf("AddTransientTask: task name already exists")
+	var ok bool
+	if t.Type, ok = s.types.resolve(t.Type, TRANSIENT); !ok {
+		return invalidf("AddTransientTask: %q is not a transient type", t.Type)
 	}
-	if !isTransientType(taskType) {
-		return fmt.Errorf("AddTransientTask: %q is not a transient type", taskType)
+	var err error
+	if t.ID, err = s.claimID(t.ID); err != nil {
+		return fmt.Errorf("AddTransientTask: %w", err)
 	}
-	t, err := NewTask(name, taskType, date, startTime, duration)
+	t, err = s.withDefaultZone(t).normalize(s.snap)
 	if err != nil {
-		return fmt.Errorf("AddTransientTask: error creating task: %v", err)
+		return fmt.Errorf("AddTransientTask: error creating task: %w", err)
+	}
+	if b, ok := s.blackoutOver(t); ok {
+		return blackoutError("AddTransientTask", b)
 	}
 	if s.hasAddConflict(t) {
-		return fmt.Errorf("AddTransientTask: task creates scheduling conflict")
+		return fmt.Errorf("AddTransientTask: task creates %w", ErrConflict)
 	}
-	s.TransientTasks[name] = t
+	s.putTransient(t)
 	return nil
 }
 
 // AddSubtask creates and adds a recurring subtask to the schedule
-func (s *Schedule) AddSubtask(name, taskType string, date int, startTime, duration float32) error {
-	if len(name) == 0 {
-		return fmt.Errorf("AddSubtask: name cannot be empty")
+func (s *Schedule) AddSubtask(name, taskType string, date int, startTime, duration time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.atomically(fmt.Sprintf("add subtask %q", name), func() error {
+		return s.addSubtask(Task{Name: name, Type: taskType, Date: date, StartTime: startTime, Duration: duration})
+	})
+}
+
+func (s *Schedule) addSubtask(t Task) error {
+	if len(t.Name) == 0 {
+		return invalidf("AddSubtask: name cannot be empty")
 	}
-	if s.hasNameConflict(name) {
-		return fmt.Errorf("AddSubtask: task name already exists")
+	var ok bool
+	if t.Type, ok = s.types.resolve(t.Type, RECURRING); !ok {
+		return invalidf("AddSubtask: %q is not a recurring type", t.Type)
 	}
-	if !isRecurringType(taskType) {
-		return fmt.Errorf("AddSubtask: %q is not a recurring type", taskType)
+	var err error
+	if t.ID, err = s.claimID(t.ID); err != nil {
+		return fmt.Errorf("AddSubtask: %w", err)
 	}
-	t, err := NewTask(name, taskType, date, startTime, duration)
+	t, err = s.withDefaultZone(t).normalize(s.snap)
 	if err != nil {
-		return fmt.Errorf("AddSubtask: error creating task: %v", err)
+		return fmt.Errorf("AddSubtask: error creating task: %w", err)
+	}
+	if b, ok := s.blackoutOver(t); ok {
+		return blackoutError("AddSubtask", b)
 	}
 	if s.hasAddConflict(t) {
-		return fmt.Errorf("AddSubtask: task creates scheduling conflict")
+		return fmt.Errorf("AddSubtask: task creates %w", ErrConflict)
 	}
-	s.TransientTasks[name] = t
+	s.putTransient(t)
 	return nil
 }
 
 // AddAntiTask creates and adds an anti task to the schedule
-func (s *Schedule) AddAntiTask(name, taskType string, date int, startTime, duration float32) error {
-	var cancelledExists bool
-	if len(name) == 0 {
-		return fmt.Errorf("AddAntiTask: name cannot be empty")
+func (s *Schedule) AddAntiTask(name, taskType string, date int, startTime, duration time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.atomically(fmt.Sprintf("add anti task %q", name), func() error {
+		return s.addAntiTask(AntiTask{Task: Task{Name: name, Type: taskType, Date: date, StartTime: startTime, Duration: duration}})
+	})
+}
+
+// AddAnti adds an anti task built by the caller, which unlike AddAntiTask can cancel a range of dates
+func (s *Schedule) AddAnti(a AntiTask) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.atomically(fmt.Sprintf("add anti task %q", a.Name), func() error {
+		return s.addAntiTask(a)
+	})
+}
+
+func (s *Schedule) addAntiTask(a AntiTask) error {
+	if len(a.Name) == 0 {
+		return invalidf("AddAntiTask: name cannot be empty")
 	}
-	if s.hasNameConflict(name) {
-		return fmt.Errorf("AddAntiTask: task name already exists")
+	var ok bool
+	if a.Type, ok = s.types.resolve(a.Type, ANTI); !ok {
+		return invalidf("AddAntiTask: %q is not an anti type", a.Type)
 	}
-	if !isAntiType(taskType) {
-		return fmt.Errorf("AddAntiTask: %q is not an anti type", taskType)
+	var err error
+	if a.ID, err = s.claimID(a.ID); err != nil {
+		return fmt.Errorf("AddAntiTask: %w", err)
 	}
-	a, err := NewAntiTask(name, taskType, date, startTime, duration)
+	t, err := s.withDefaultZone(a.Task).normalize(s.snap)
 	if err != nil {
-		return fmt.Errorf("AddAntiTask: error creating task: %v", err)
+		return fmt.Errorf("AddAntiTask: error creating task: %w", err)
+	}
+	a.Task = t
+	if a, err = s.checkAnti(a); err != nil {
+		return fmt.Errorf("AddAntiTask: %w", err)
 	}
-	for _, t := range s.AntiTasks {
-		if t.Overlaps(a.Task) {
-			return fmt.Errorf("AddAntiTask: task overlaps with another anti task")
+	s.putAnti(a)
+	return nil
+}
+
+// checkAnti checks that an anti task has something to cancel
+// A single anti task must cancel a subtask that has no override and must not overlap another single anti task,
+// a recurring anti task must line up with a subtask like a single anti task, and a range anti task only needs the
+// recurring task it names to exist, and has its Series set to that task's ID if it names it by name
+func (ts taskSet) checkAnti(a AntiTask) (AntiTask, error) {
+	if a.IsRecurring() {
+		if err := a.checkRecurrence(); err != nil {
+			return a, err
+		}
+		for _, r := range ts.recurringTasks {
+			if _, ok := a.firstCancelled(r); ok {
+				return a, nil
+			}
+		}
+		return a, invalidf("no corresponding recurring task exists")
+	}
+	if a.IsRange() {
+		a, err := a.checkRange()
+		if err != nil {
+			return a, err
 		}
+		if a.Series == "" {
+			return a, nil
+		}
+		id, err := ts.resolve(a.Series, ts.isRecurring)
+		if errors.Is(err, ErrAmbiguous) {
+			return a, fmt.Errorf("series %q: %w", a.Series, err)
+		}
+		if err != nil {
+			return a, invalidf("no recurring task named %q", a.Series)
+		}
+		a.Series = id
+		return a, nil
 	}
-	for _, t := range s.RecurringTasks {
-		if _, ok := a.GetCancelledSubtask(t); ok {
-			cancelledExists = true
-			break
+	for _, t := range ts.antiNear(a.Task) {
+		if !t.IsRange() && !t.IsRecurring() && t.Overlaps(a.Task) {
+			return a, invalidf("task overlaps with another anti task")
 		}
 	}
-	if !cancelledExists {
-		return fmt.Errorf("AddAntiTask: no corresponding recurring task exists")
+	for _, r := range ts.recurringTasks {
+		if sub, ok := a.GetCancelledSubtask(r); ok {
+			if ts.overridden(sub) {
+				return a, invalidf("the subtask has an override, delete the override instead")
+			}
+			return a, nil
+		}
 	}
-	s.AntiTasks[name] = a
-	return nil
+	return a, invalidf("no corresponding recurring task exists")
+}
+
+// AddRecurring adds a recurring task built by the caller, which unlike AddRecurringTask can set its time zone
+func (s *Schedule) AddRecurring(r RecurringTask) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.atomically(fmt.Sprintf("add recurring task %q", r.Name), func() error {
+		return s.addRecurringTask(r)
+	})
 }
 
 // AddRecurringTask creates and adds a recurring task to the schedule
-func (s *Schedule) AddRecurringTask(name, taskType string, date int, startTime, duration float32, endDate, frequency int) error {
-	if len(name) == 0 {
-		return fmt.Errorf("AddRecurringTask: name cannot be empty")
+func (s *Schedule) AddRecurringTask(name, taskType string, date int, startTime, duration time.Duration, endDate, frequency int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.atomically(fmt.Sprintf("add recurring task %q", name), func() error {
+		return s.addRecurringTask(RecurringTask{
+			Task:      Task{Name: name, Type: taskType, Date: date, StartTime: startTime, Duration: duration},
+			EndDate:   endDate,
+			Frequency: frequency,
+		})
+	})
+}
+
+func (s *Schedule) addRecurringTask(r RecurringTask) error {
+	if len(r.Name) == 0 {
+		return invalidf("AddRecurringTask: name cannot be empty")
 	}
-	if s.hasNameConflict(name) {
-		return fmt.Errorf("AddRecurringTask: task name already exists")
+	var ok bool
+	if r.Type, ok = s.types.resolve(r.Type, RECURRING); !ok {
+		return invalidf("AddRecurringTask: %q is not a recurring type", r.Type)
 	}
-	if !isRecurringType(taskType) {
-		return fmt.Errorf("AddRecurringTask: %q is not a recurring type", taskType)
+	var err error
+	if r.ID, err = s.claimID(r.ID); err != nil {
+		return fmt.Errorf("AddRecurringTask: %w", err)
 	}
-	t, err := NewRecurringTask(name, taskType, date, startTime, duration, endDate, frequency)
+	r.Task = s.withDefaultZone(r.Task)
+	r, err = r.normalize(s.snap)
 	if err != nil {
-		return fmt.Errorf("AddRecurringTask: error creating task: %v", err)
+		return fmt.Errorf("AddRecurringTask: error creating task: %w", err)
+	}
+	if b, ok := s.rejectedBy(r); ok {
+		return blackoutError("AddRecurringTask", b)
 	}
-	if s.hasAddConflictRecurring(t) {
-		return fmt.Errorf("AddRecurringTask: task creates scheduling conflict")
+	if s.hasAddConflictRecurring(r) {
+		return fmt.Errorf("AddRecurringTask: task creates %w", ErrConflict)
 	}
-	s.RecurringTasks[name] = t
+	s.putRecurring(r)
 	return nil
 }
 
-// DeleteTask deletes a task in the schedule by name
-func (s *Schedule) DeleteTask(name string) error {
-	if _, ok := s.TransientTasks[name]; ok {
-		delete(s.TransientTasks, name)
+// DeleteTask deletes a task in the schedule by ID or by a name no other task has
+func (s *Schedule) DeleteTask(ref string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.atomically(fmt.Sprintf("delete task %q", ref), func() error {
+		return s.deleteTask(ref)
+	})
+}
+
+func (s *Schedule) deleteTask(ref string) error {
+	id, err := s.resolve(ref, s.hasID)
+	if err != nil {
+		return fmt.Errorf("DeleteTask: %w", err)
+	}
+	if _, ok := s.transientTasks[id]; ok {
+		s.removeTransient(id)
 		return nil
 	}
-	if r, ok := s.RecurringTasks[name]; ok {
-		delete(s.RecurringTasks, name)
-		// Delete all corresponding anti tasks for the recurring task
-		for _, a := range s.AntiTasks {
-			if _, ok := a.GetCancelledSubtask(r); ok {
-				delete(s.AntiTasks, a.Name)
+	if r, ok := s.recurringTasks[id]; ok {
+		s.removeRecurring(id)
+		// Delete all corresponding anti tasks and overrides for the recurring task
+		for _, a := range s.antiTasks {
+			if _, ok := a.firstCancelled(r); ok || a.Series == id {
+				s.removeAnti(a.ID)
 			}
 		}
-		return nil
-	}
-	if a, ok := s.AntiTasks[name]; ok {
-		// For anti tasks, we have to check if deleting will not create a conflict
-		if s.hasDeleteConflict(a.Task) {
-			return fmt.Errorf("DeleteTask: deletion creates a schedule conflict")
+		for _, o := range s.overridesOf(id) {
+			s.removeOverride(o.key())
 		}
-		delete(s.AntiTasks, name)
 		return nil
 	}
-	return fmt.Errorf("DeleteTask: task name does not exist in schedule")
+	a := s.antiTasks[id]
+	// For anti tasks, we have to check if deleting will not create a conflict
+	// The anti task is removed first, as the subtasks a range brings back may conflict with each other
+	s.removeAnti(id)
+	if s.hasDeleteConflict(a) {
+		return fmt.Errorf("DeleteTask: deletion creates a %w", ErrConflict)
+	}
+	return nil
+}
+
+// EditTask replaces the details of an existing transient task, subtask or anti task with a task built
+// by the caller, which unlike the other edit methods can change its time zone
+// A task without a time zone keeps the time zone of the task it replaces, and anti tasks keep their type
+// Like the other edit methods, the task is named by its ID or a name no other task has and keeps its ID
+func (s *Schedule) EditTask(ref string, t Task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.atomically(fmt.Sprintf("edit task %q", ref), func() error {
+		return s.editTask(ref, t)
+	})
+}
+
+func (s *Schedule) editTask(ref string, t Task) error {
+	id, err := s.resolve(ref, func(id string) bool { return s.isTransient(id) || s.isAnti(id) })
+	if err != nil {
+		return fmt.Errorf("EditTask: %w", err)
+	}
+	if a, ok := s.antiTasks[id]; ok {
+		return s.editAntiTask(id, a.withDetails(t))
+	}
+	return s.editTransientTask(id, t)
 }
 
 // EditTransienTask edits the details of an existing transient task in the schedule
-func (s *Schedule) EditTransientTask(taskName, newName, newType string, newDate int, newStartTime, newDuration float32) error {
-	t, ok := s.TransientTasks[taskName]
-	if !ok {
-		return fmt.Errorf("EditTransientTask: task name does not exist in schedule")
+func (s *Schedule) EditTransientTask(ref, newName, newType string, newDate int, newStartTime, newDuration time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.atomically(fmt.Sprintf("edit transient task %q", ref), func() error {
+		return s.editTransientTask(ref, Task{Name: newName, Type: newType, Date: newDate, StartTime: newStartTime, Duration: newDuration})
+	})
+}
+
+func (s *Schedule) editTransientTask(ref string, newTask Task) error {
+	id, err := s.resolve(ref, s.isTransient)
+	if err != nil {
+		return fmt.Errorf("EditTransientTask: %w", err)
+	}
+	t := s.transientTasks[id]
+	newTask.ID = t.ID
+	var ok bool
+	if newTask.Type, ok = s.types.resolve(newTask.Type, TRANSIENT); !ok {
+		return invalidf("EditTransientTask: %q is not a transient type", newTask.Type)
 	}
-	if newName != taskName && s.hasNameConflict(newName) {
-		return fmt.Errorf("EditTransientTask: new name already exists in schedule")
+	if newTask.TimeZone == "" {
+		newTask.TimeZone = t.TimeZone
 	}
-	newTask, err := NewTask(newName, newType, newDate, newStartTime, newDuration)
+	newTask, err = newTask.normalize(s.snap)
 	if err != nil {
-		return fmt.Errorf("EditTransientTask: %v", err)
+		return fmt.Errorf("EditTransientTask: %w", err)
 	}
-	if t.Date == newDate && t.StartTime == newStartTime && t.Duration == newDuration {
+	s.removeTransient(id)
+	if t.Date == newTask.Date && t.StartTime == newTask.StartTime && t.Duration == newTask.Duration && t.TimeZone == newTask.TimeZone {
 		// Only the name changed and type changed
-		delete(s.TransientTasks, taskName)
-		s.TransientTasks[newName] = newTask
+		s.putTransient(newTask)
 		return nil
 	}
-	s.DeleteTask(taskName)
+	if b, ok := s.blackoutOver(newTask); ok {
+		return blackoutError("EditTransientTask", b)
+	}
 	if s.hasAddConflict(newTask) {
-		// Add back the old task
-		s.TransientTasks[taskName] = t
-		return fmt.Errorf("EditTransientTask: new details create a schedule conflict")
+		// The old task is restored when the edit is rolled back
+		return fmt.Errorf("EditTransientTask: new details create a %w", ErrConflict)
 	}
-	s.TransientTasks[newName] = newTask
+	s.putTransient(newTask)
 	return nil
 }
 
 // EditAntiTask edits the details of an existing anti task in the schedule
-func (s *Schedule) EditAntiTask(taskName, newName string, newDate int, newStartTime, newDuration float32) error {
-	a, ok := s.AntiTasks[taskName]
-	if !ok {
-		return fmt.Errorf("EditAntiTask: task name does not exist in schedule")
+// A range or recurring anti task keeps its end date, recurring task and frequency, and a generated anti task
+// keeps its holiday
+func (s *Schedule) EditAntiTask(ref, newName string, newDate int, newStartTime, newDuration time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.atomically(fmt.Sprintf("edit anti task %q", ref), func() error {
+		a, _ := s.taskSet.AntiTask(ref)
+		return s.editAntiTask(ref, a.withDetails(Task{Name: newName, Date: newDate, StartTime: newStartTime, Duration: newDuration}))
+	})
+}
+
+// EditAnti replaces the details of an existing anti task with an anti task built by the caller, which unlike
+// EditAntiTask can change its range or frequency
+func (s *Schedule) EditAnti(ref string, a AntiTask) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.atomically(fmt.Sprintf("edit anti task %q", ref), func() error {
+		return s.editAntiTask(ref, a)
+	})
+}
+
+func (s *Schedule) editAntiTask(ref string, newTask AntiTask) error {
+	id, err := s.resolve(ref, s.isAnti)
+	if err != nil {
+		return fmt.Errorf("EditAntiTask: %w", err)
 	}
-	if newName != taskName && s.hasNameConflict(newName) {
-		return fmt.Errorf("EditAntiTask: new name already exists in schedule")
+	a := s.antiTasks[id]
+	newTask.ID = a.ID
+	newTask.Type = a.Type
+	if newTask.TimeZone == "" {
+		newTask.TimeZone = a.TimeZone
 	}
-	newTask, err := NewAntiTask(newName, a.Type, newDate, newStartTime, newDuration)
+	t, err := newTask.Task.normalize(s.snap)
 	if err != nil {
-		return fmt.Errorf("EditAntiTask: %v", err)
+		return fmt.Errorf("EditAntiTask: %w", err)
 	}
-	if a.Date == newDate && a.StartTime == newStartTime && a.Duration == newDuration {
+	newTask.Task = t
+	if newTask.IsRange() {
+		if newTask, err = newTask.checkRange(); err != nil {
+			return fmt.Errorf("EditAntiTask: %w", err)
+		}
+	}
+	old := a
+	old.Name = newTask.Name
+	if old == newTask {
 		// Only name changed
-		delete(s.AntiTasks, taskName)
-		s.AntiTasks[newName] = newTask
+		s.putAnti(newTask)
 		return nil
 	}
-	if err := s.DeleteTask(taskName); err != nil {
-		return fmt.Errorf("EditAntiTask: %v", err)
+	if err := s.deleteTask(id); err != nil {
+		return fmt.Errorf("EditAntiTask: %w", err)
+	}
+	if newTask, err = s.checkAnti(newTask); err != nil {
+		return fmt.Errorf("EditAntiTask: new anti task: %w", err)
+	}
+	s.putAnti(newTask)
+	return nil
+}
+
+// EditRecurring replaces the details of an existing recurring task with a recurring task built by the
+// caller, which unlike EditRecurringTask can change its time zone
+// A task without a time zone keeps the time zone of the task it replaces
+func (s *Schedule) EditRecurring(ref string, r RecurringTask) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.atomically(fmt.Sprintf("edit recurring task %q", ref), func() error {
+		return s.editRecurringTask(ref, r)
+	})
+}
+
+// EditRecurringTask edits the details of an existing recurring task in the schedule
+func (s *Schedule) EditRecurringTask(ref, newName, newType string, newDate int, newStartTime, newDuration time.Duration, newEndDate, newFrequency int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.atomically(fmt.Sprintf("edit recurring task %q", ref), func() error {
+		return s.editRecurringTask(ref, RecurringTask{
+			Task:      Task{Name: newName, Type: newType, Date: newDate, StartTime: newStartTime, Duration: newDuration},
+			EndDate:   newEndDate,
+			Frequency: newFrequency,
+		})
+	})
+}
+
+func (s *Schedule) editRecurringTask(ref string, newTask RecurringTask) error {
+	id, err := s.resolve(ref, s.isRecurring)
+	if err != nil {
+		return fmt.Errorf("EditRecurringTask: %w", err)
+	}
+	r := s.recurringTasks[id]
+	// Subtasks, overrides and the anti tasks that name the task all go by its ID, so they follow a rename
+	newTask.ID = r.ID
+	var ok bool
+	if newTask.Type, ok = s.types.resolve(newTask.Type, RECURRING); !ok {
+		return invalidf("EditRecurringTask: %q is not a recurring type", newTask.Type)
 	}
-	// Find a corresponding recurring task
-	var foundCancelledTask bool
-	for _, r := range s.RecurringTasks {
-		if _, ok := newTask.GetCancelledSubtask(r); ok {
-			foundCancelledTask = true
-			break
+	if newTask.TimeZone == "" {
+		newTask.TimeZone = r.TimeZone
+	}
+	newTask, err = newTask.normalize(s.snap)
+	if err != nil {
+		return fmt.Errorf("EditRecurringTask: %w", err)
+	}
+	// The new task is put in place before it is checked so that the recurring anti tasks lined up with it cancel
+	// its subtasks, and the old task is restored when the edit is rolled back
+	s.removeRecurring(id)
+	s.putRecurring(newTask)
+	// Overrides are taken off while the new task is checked and put back on the subtasks it still has
+	overrides := s.overridesOf(id)
+	for _, o := range overrides {
+		s.removeOverride(o.key())
+	}
+	if b, ok := s.rejectedBy(newTask); ok {
+		return blackoutError("EditRecurringTask", b)
+	}
+	if !r.sameTimes(newTask) && s.hasAddConflictRecurring(newTask) {
+		return fmt.Errorf("EditRecurringTask: new details create a %w", ErrConflict)
+	}
+	if !r.sameTimes(newTask) {
+		// Delete all anti tasks of the old recurring task that do not match up with the new task
+		for _, a := range s.antiTasks {
+			if _, ok := a.firstCancelled(r); ok {
+				if _, ok := a.firstCancelled(newTask); !ok {
+					s.removeAnti(a.ID)
+				}
+			}
 		}
 	}
-	if !foundCancelledTask {
-		s.AntiTasks[taskName] = a
-		return fmt.Errorf("EditAntiTask: new anti task does not correspond with any recurring task")
+	for _, o := range overrides {
+		original, _ := intToDate(o.Original)
+		if day := epochDay(original); len(newTask.subtaskDays(day, day)) == 0 {
+			continue
+		}
+		o.Name = newTask.Name
+		if err := s.addOverride(o); err != nil {
+			return fmt.Errorf("EditRecurringTask: override on %v: %w", dateIntToString(o.Original), err)
+		}
 	}
-	s.AntiTasks[newName] = newTask
 	return nil
 }
 
-// EditRecurringTask edits the details of an existing recurring task in the schedule
-func (s *Schedule) EditRecurringTask(taskName, newName, newType string, newDate int, newStartTime, newDuration float32, newEndDate, newFrequency int) error {
-	r, ok := s.RecurringTasks[taskName]
-	if !ok {
-		return fmt.Errorf("EditRecurringTask: task name does not exist in schedule")
+// SplitRecurring changes a recurring task from a date onward, as when a class changes time partway through a term
+// The task is cut short before the date and newTask, which must start on or after the date and is given an ID of
+// its own, takes over from there
+// Anti tasks and overrides stay with whichever of the two tasks has the subtask they belong to, and only newTask is
+// checked for conflicts
+func (s *Schedule) SplitRecurring(ref string, from int, newTask RecurringTask) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.atomically(fmt.Sprintf("split recurring task %q on %v", ref, dateIntToString(from)), func() error {
+		return s.splitRecurringTask(ref, from, newTask)
+	})
+}
+
+func (s *Schedule) splitRecurringTask(ref string, from int, newTask RecurringTask) error {
+	id, err := s.resolve(ref, s.isRecurring)
+	if err != nil {
+		return fmt.Errorf("SplitRecurring: %w", err)
 	}
-	if newName != taskName && s.hasNameConflict(newName) {
-		return fmt.Errorf("EditRecurringTask: new name already exists in schedule")
+	r := s.recurringTasks[id]
+	fromDate, err := intToDate(from)
+	if err != nil {
+		return invalidf("SplitRecurring: bad date to split on")
 	}
-	newTask, err := NewRecurringTask(newName, newType, newDate, newStartTime, newDuration, newEndDate, newFrequency)
+	start, _ := intToDate(r.Date)
+	firstDay, fromDay := epochDay(start), epochDay(fromDate)
+	if fromDay <= firstDay {
+		return invalidf("SplitRecurring: %q does not start before %v, edit it instead", r.Name, dateIntToString(from))
+	}
+	if fromDay > r.lastDay() {
+		return invalidf("SplitRecurring: %q has no subtasks on or after %v", r.Name, dateIntToString(from))
+	}
+	if newTask.Date < from {
+		return invalidf("SplitRecurring: new task starts before %v", dateIntToString(from))
+	}
+	if newTask.ID, err = s.claimID(newTask.ID); err != nil {
+		return fmt.Errorf("SplitRecurring: new task: %w", err)
+	}
+	var ok bool
+	if newTask.Type, ok = s.types.resolve(newTask.Type, RECURRING); !ok {
+		return invalidf("SplitRecurring: %q is not a recurring type", newTask.Type)
+	}
+	if newTask.TimeZone == "" {
+		newTask.TimeZone = r.TimeZone
+	}
+	newTask, err = newTask.normalize(s.snap)
 	if err != nil {
-		return fmt.Errorf("EditRecurringTask: %v", err)
+		return fmt.Errorf("SplitRecurring: %w", err)
+	}
+	// The old task keeps its subtasks before the split, ending after as many of them if it ends after a count
+	head := r
+	if r.Count > 0 {
+		head.Count = len(r.subtaskDays(firstDay, fromDay-1))
+	} else if r.Duration == 0 {
+		// Subtasks without a duration end before their end date
+		head.EndDate = from
+	} else {
+		head.EndDate = dateToInt(epochDate(fromDay - 1))
+	}
+	s.putRecurring(head)
+	moved := []Override{}
+	for _, o := range s.overridesOf(id) {
+		if o.Original >= from {
+			s.removeOverride(o.key())
+			moved = append(moved, o)
+		}
 	}
-	if r.Date == newDate && r.StartTime == newStartTime && r.Duration == newDuration && r.EndDate == newEndDate && r.Frequency == newFrequency {
-		// Only name changed and type changed
-		delete(s.RecurringTasks, taskName)
-		s.RecurringTasks[newName] = newTask
-		return nil
+	// Range anti tasks wholly after the split move to the new task, which is put in place before it is checked so
+	// that they and the recurring anti tasks lined up with it cancel its subtasks
+	s.retargetAnti(id, newTask.ID, from)
+	s.putRecurring(newTask)
+	if b, ok := s.rejectedBy(newTask); ok {
+		return blackoutError("SplitRecurring", b)
 	}
-	delete(s.RecurringTasks, taskName)
 	if s.hasAddConflictRecurring(newTask) {
-		// Add back old task
-		s.RecurringTasks[taskName] = r
-		return fmt.Errorf("EditRecurringTask: new details create a schedule conflict")
+		return fmt.Errorf("SplitRecurring: new details create a %w", ErrConflict)
 	}
-	// Delete all anti tasks of the old recurring task that do not match up with the new task
-	for _, a := range s.AntiTasks {
-		if _, ok := a.GetCancelledSubtask(r); ok {
-			if _, ok := a.GetCancelledSubtask(newTask); !ok {
-				delete(s.AntiTasks, a.Name)
+	// Delete the anti tasks after the split that do not match up with the new task
+	for _, a := range s.antiTasks {
+		if sub, ok := a.firstCancelled(r); ok && sub.Date >= from {
+			if _, ok := a.firstCancelled(newTask); !ok {
+				s.removeAnti(a.ID)
 			}
 		}
 	}
+	for _, o := range moved {
+		original, _ := intToDate(o.Original)
+		if day := epochDay(original); len(newTask.subtaskDays(day, day)) == 0 {
+			continue
+		}
+		o.ID, o.Name = newTask.ID, newTask.Name
+		if err := s.addOverride(o); err != nil {
+			return fmt.Errorf("SplitRecurring: override on %v: %w", dateIntToString(o.Original), err)
+		}
+	}
 	return nil
 }
 
+// retargetAnti moves the range anti tasks of a recurring task starting on or after a date to another recurring task
+func (s *Schedule) retargetAnti(oldID, newID string, from int) {
+	for _, a := range s.antiTasks {
+		if a.IsRange() && a.Series == oldID && a.Date >= from {
+			a.Series = newID
+			s.putAnti(a)
+		}
+	}
+}
+
 // Project specifications are vagues so we'll consider all years in get by date range functions
 
-// GetTasksByMonth gets all tasks/subtasks within a specified month
-func (s Schedule) GetTasksByMonth(month int) ([]Task, error) {
+// GetTasksByMonth gets all tasks/subtasks touching a specified month
+func (s *Schedule) GetTasksByMonth(month int) ([]Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.taskSet.GetTasksByMonth(month)
+}
+
+// GetTasksByDay gets all tasks/subtasks touching a specified month and day, including tasks that start on an earlier day
+func (s *Schedule) GetTasksByDay(month, day int) ([]Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.taskSet.GetTasksByDay(month, day)
+}
+
+// GetTasksByWeek gets all tasks/subtasks occuring in the week of the specified month and day
+// The week begins on the schedule's first day of the week and may cross into another month or year
+func (s *Schedule) GetTasksByWeek(month, day int) ([]Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.taskSet.getTasksByWeek(month, day, s.weekStart)
+}
+
+// GetTasksInWeek gets all tasks/subtasks in the seven days of the week containing a date
+// The week begins on the schedule's first day of the week
+func (s *Schedule) GetTasksInWeek(date time.Time) ([]Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	start, end := WeekOf(date, s.weekStart)
+	return s.taskSet.GetTasksInRange(start, end)
+}
+
+// WeekStart gets the first day of the week used by the week queries
+func (s *Schedule) WeekStart() time.Weekday {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.weekStart
+}
+
+// SetWeekStart sets the first day of the week used by the week queries to Sunday or Monday
+func (s *Schedule) SetWeekStart(day time.Weekday) error {
+	if day != time.Sunday && day != time.Monday {
+		return invalidf("SetWeekStart: weeks must start on Sunday or Monday, not %v", day)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.weekStart = day
+	return nil
+}
+
+// WeekOf returns the start of the week containing a date and the start of the week after
+// Weeks begin at midnight on the weekStart day
+func WeekOf(date time.Time, weekStart time.Weekday) (time.Time, time.Time) {
+	y, m, d := date.Date()
+	start := time.Date(y, m, d-(int(date.Weekday())-int(weekStart)+7)%7, 0, 0, 0, 0, date.Location())
+	return start, start.AddDate(0, 0, 7)
+}
+
+// GetTasksByMonth gets all tasks/subtasks touching a specified month
+func (ts taskSet) GetTasksByMonth(month int) ([]Task, error) {
 	result := []Task{}
+	inMonth := func(t Task) bool {
+		for _, d := range t.Days() {
+			if (d/100)%100 == month {
+				return true
+			}
+		}
+		return false
+	}
 	// Get the transient tasks
-	for _, t := range s.TransientTasks {
-		if t.GetStartMonth() == month {
+	for _, t := range ts.transientTasks {
+		if inMonth(t) {
 			result = append(result, t)
 		}
 	}
 	// Get the recurring subtasks
-	for _, r := range s.RecurringTasks {
+	for _, r := range ts.recurringTasks {
 		subtasks, err := r.GetSubtasks()
 		if err != nil {
 			return []Task{}, fmt.Errorf("GetTasksByMonth: error getting subtasks: %v", err)
 		}
 		for _, sub := range subtasks {
-			if sub.GetStartMonth() == month && !s.hasAnti(sub) {
+			if inMonth(sub) && !ts.hidden(sub) {
 				result = append(result, sub)
 			}
 		}
 	}
+	// Get the subtasks moved or changed by overrides
+	for _, o := range ts.overrides {
+		if inMonth(o.Task) {
+			result = append(result, o.Task)
+		}
+	}
 	return result, nil
 }
 
-// GetTasksByDay gets all tasks/subtasks starting at a specified month and day
-func (s Schedule) GetTasksByDay(month, day int) ([]Task, error) {
+// GetTasksByDay gets all tasks/subtasks touching a specified month and day, including tasks that start on an earlier day
+func (ts taskSet) GetTasksByDay(month, day int) ([]Task, error) {
 	result := []Task{}
-	byMonth, err := s.GetTasksByMonth(month)
+	byMonth, err := ts.GetTasksByMonth(month)
 	if err != nil {
 		return result, fmt.Errorf("GetTasksByDay: %v", err)
 	}
 	for _, t := range byMonth {
-		if t.GetStartDay() == day {
-			result = append(result, t)
+		for _, d := range t.Days() {
+			if (d/100)%100 == month && d%100 == day {
+				result = append(result, t)
+				break
+			}
 		}
 	}
 	return result, nil
 }
 
-// GetTasksByWeek gets all tasks/subtasks occuring in the week of the specified month and day
-func (s Schedule) GetTasksByWeek(month, day int) ([]Task, error) {
+// getTasksByWeek gets all tasks/subtasks occuring in the week of the specified month and day of any year
+func (ts taskSet) getTasksByWeek(month, day int, weekStart time.Weekday) ([]Task, error) {
 	result := []Task{}
-	byMonth, err := s.GetTasksByMonth(month)
-	if err != nil {
-		return result, fmt.Errorf("GetTasksByWeek: %v", err)
+	inWeek := func(t Task) bool {
+		for _, d := range t.Days() {
+			date, _ := intToDate(d)
+			// The week of the month and day can begin in the year before the day or end in the year after
+			for year := date.Year() - 1; year <= date.Year()+1; year++ {
+				start, end := WeekOf(time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC), weekStart)
+				if !date.Before(start) && date.Before(end) {
+					return true
+				}
+			}
+		}
+		return false
 	}
-	for _, t := range byMonth {
-		date, err := t.GetStartDate()
+	for _, t := range ts.transientTasks {
+		if inWeek(t) {
+			result = append(result, t)
+		}
+	}
+	for _, r := range ts.recurringTasks {
+		subtasks, err := r.GetSubtasks()
 		if err != nil {
-			return result, fmt.Errorf("GetTasksByWeek: %v", err)
+			return []Task{}, fmt.Errorf("GetTasksByWeek: error getting subtasks: %v", err)
 		}
-		targetDate := time.Date(t.GetStartYear(), time.Month(month), day, 0, 0, 0, 0, time.UTC)
-		_, week := date.ISOWeek()
-		_, targetWeek := targetDate.ISOWeek()
-		if week == targetWeek {
+		for _, sub := range subtasks {
+			if inWeek(sub) && !ts.hidden(sub) {
+				result = append(result, sub)
+			}
+		}
+	}
+	for _, o := range ts.overrides {
+		if inWeek(o.Task) {
+			result = append(result, o.Task)
+		}
+	}
+	return result, nil
+}
+
+// GetTasksInRange gets all tasks/subtasks that overlap the span from start up to end in order of start
+// Unlike the month, week and day queries above, the span is a fixed stretch of time so years are respected
+func (s *Schedule) GetTasksInRange(start, end time.Time) ([]Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.taskSet.GetTasksInRange(start, end)
+}
+
+// GetTasksInRange gets all tasks/subtasks that overlap the span from start up to end in order of start
+func (ts taskSet) GetTasksInRange(start, end time.Time) ([]Task, error) {
+	result := []Task{}
+	if !start.Before(end) {
+		return result, nil
+	}
+	// Get the transient tasks on the days of the span
+	first := floorDiv(start.Unix(), SECONDS_PER_DAY)
+	last := floorDiv(end.Add(-time.Nanosecond).Unix(), SECONDS_PER_DAY)
+	for _, n := range ts.transientDays.between(first, last) {
+		if t := ts.transientTasks[n]; t.overlapsSpan(start, end) {
 			result = append(result, t)
 		}
 	}
+	// Get the recurring subtasks
+	for _, r := range ts.recurringTasks {
+		subtasks, err := r.GetSubtasksInRange(start, end)
+		if err != nil {
+			return []Task{}, fmt.Errorf("GetTasksInRange: error getting subtasks: %v", err)
+		}
+		for _, sub := range subtasks {
+			if !ts.hidden(sub) {
+				result = append(result, sub)
+			}
+		}
+	}
+	// Get the subtasks moved or changed by overrides
+	result = append(result, ts.overridesInRange(start, end)...)
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].Before(result[j]) && !result[j].Before(result[i]) {
+			return byName(result[i], result[j])
+		}
+		return result[i].Before(result[j])
+	})
 	return result, nil
 }
 
@@ -325,243 +881,326 @@ func (s Schedule) GetTasksByWeek(month, day int) ([]Task, error) {
 
 // LoadFile loads the contents of the json file at the specified path into the schedule
 // We expect the json file to contain a single list of tasks
+// Tasks without a TimeZone key are given the schedule's default time zone
+// Entries with the type "Blackout" are blackout periods, which are added first so the tasks must keep out of them
 func (s *Schedule) LoadFile(path string) error {
-	transientTaskBuff := []map[string]interface{}{} // Buffer to hold transient tasks read from file
-	antiTaskBuff := []map[string]interface{}{}      // Buffer to hold anti tasks read from file
-	recurTaskBuff := []map[string]interface{}{}     // Buffer to hold recurring tasks read from file
-	subTaskBuff := []map[string]interface{}{}       // Buffer to hold subtasks read from file
-	content, err := os.ReadFile(path)               // Load contents of file as a byte slice
+	content, err := os.ReadFile(path) // Load contents of file as a byte slice
 	if err != nil {
 		return fmt.Errorf("LoadFile: error reading file %q: %v", path, err)
 	}
+	batch, err := parseJSON(content, s.Types())
+	if err != nil {
+		return fmt.Errorf("LoadFile: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.addBatch(batch); err != nil {
+		return fmt.Errorf("LoadFile: error loading tasks: %w", err)
+	}
+	return nil
+}
+
+// LoadJSON loads a json list of tasks in the same format as LoadFile into the schedule
+func (s *Schedule) LoadJSON(content []byte) error {
+	batch, err := parseJSON(content, s.Types())
+	if err != nil {
+		return fmt.Errorf("LoadJSON: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.addBatch(batch); err != nil {
+		return fmt.Errorf("LoadJSON: error loading tasks: %w", err)
+	}
+	return nil
+}
+
+// parseJSON converts a json list of tasks into a batch of tasks, telling the kinds of tasks apart by the types of
+// a registry
+func parseJSON(content []byte, types *TypeRegistry) (taskBatch, error) {
+	var batch taskBatch
 	var tasksRead []interface{}
-	err = json.Unmarshal(content, &tasksRead)
+	err := json.Unmarshal(content, &tasksRead)
 	if err != nil {
-		return fmt.Errorf("LoadFile: error unmarshaling json: %v", err)
+		return batch, invalidf("error unmarshaling json: %v", err)
 	}
 	for _, i := range tasksRead {
 		// Because the json format is pre-determined, we have to discriminate based on number of keys and type field
-		t := i.(map[string]interface{})
-		if len(t) != NUM_TASK_KEYS && len(t) != NUM_RECUR_KEYS {
-			// Wrong number of keys given (ie. bad data)
-			return fmt.Errorf("LoadFile: error parsing tasks: wrong number of keys")
+		t, ok := i.(map[string]interface{})
+		if !ok {
+			return batch, invalidf("error parsing tasks: expected a json object")
 		}
-		if len(t) == NUM_TASK_KEYS {
-			// Either a recurring subtask, an anti task, or a transient task
-			if _, ok := t[TYPE_KEY]; !ok {
-				return fmt.Errorf("LoadFile: error parsing tasks: missing %q field", TYPE_KEY)
+		if typeName, _ := t[TYPE_KEY].(string); typeName == BLACKOUT {
+			b, err := mapToBlackout(t)
+			if err != nil {
+				return batch, fmt.Errorf("error loading tasks: %w", err)
 			}
-			taskType, ok := t[TYPE_KEY].(string)
-			if !ok {
-				return fmt.Errorf("LoadFile: error parsing tasks: could not assert type field to string")
-			}
-			if isTransientType(taskType) {
-				transientTaskBuff = append(transientTaskBuff, t)
-				continue
+			batch.blackouts = append(batch.blackouts, b)
+			continue
+		}
+		zone, err := mapToTimeZone(t)
+		if err != nil {
+			return batch, fmt.Errorf("error loading tasks: %w", err)
+		}
+		id, err := mapToID(t)
+		if err != nil {
+			return batch, fmt.Errorf("error loading tasks: %w", err)
+		}
+		numKeys, hasRule := len(t), false
+		for _, k := range []string{TIME_ZONE_KEY, ID_KEY} {
+			if _, ok := t[k]; ok {
+				numKeys--
 			}
-			if isAntiType(taskType) {
-				antiTaskBuff = append(antiTaskBuff, t)
-				continue
+		}
+		// Range anti tasks have an end date and may name a recurring task, recurring anti tasks have an end date
+		// and a frequency, and anti tasks generated for holidays name the holiday
+		typeName, _ := t[TYPE_KEY].(string)
+		isAnti := types.is(typeName, ANTI)
+		for _, k := range []string{SERIES_KEY, FREQUENCY_KEY, HOLIDAY_KEY} {
+			if _, ok := t[k]; ok && isAnti {
+				numKeys--
 			}
-			if isRecurringType(taskType) {
-				subTaskBuff = append(subTaskBuff, t)
-				continue
+		}
+		for _, k := range ruleKeys {
+			if _, ok := t[k]; ok {
+				numKeys--
+				hasRule = hasRule || k != END_DATE_KEY || !isAnti
 			}
-			return fmt.Errorf("LoadFile: error parsing tasks: bad type found: %q", taskType)
 		}
-		if len(t) == NUM_RECUR_KEYS {
+		if numKeys != NUM_TASK_KEYS && numKeys != NUM_RECUR_KEYS {
+			// Wrong number of keys given (ie. bad data)
+			return batch, invalidf("error parsing tasks: wrong number of keys")
+		}
+		if hasRule && numKeys != NUM_RECUR_KEYS {
+			return batch, invalidf("error parsing tasks: recurrence rule given for a task that does not recur")
+		}
+		if numKeys == NUM_RECUR_KEYS {
 			// A potential recurring task
-			recurTaskBuff = append(recurTaskBuff, t)
+			if err := recurKeysPresent(t); err != nil {
+				return batch, invalidf("error loading tasks: task values missing: %v", err)
+			}
+			name, taskType, date, startTime, duration, endDate, frequency, err := mapToRecurInfo(t)
+			if err != nil {
+				return batch, fmt.Errorf("error loading tasks: %w", err)
+			}
+			count, err := mapToOptionalInt(t, COUNT_KEY)
+			if err != nil {
+				return batch, invalidf("error loading tasks: bad count value")
+			}
+			weekdays, monthDay, monthWeek, err := mapToRule(t)
+			if err != nil {
+				return batch, fmt.Errorf("error loading tasks: %w", err)
+			}
+			if id == "" {
+				// The overrides are tied to the task by its ID, which it needs before it is added
+				id = NewID()
+			}
+			r := RecurringTask{
+				Task:      Task{ID: id, Name: name, Type: taskType, Date: date, StartTime: startTime, Duration: duration, TimeZone: zone},
+				EndDate:   endDate,
+				Count:     count,
+				Frequency: frequency,
+				Weekdays:  weekdays,
+				MonthDay:  monthDay,
+				MonthWeek: monthWeek,
+			}
+			batch.recurring = append(batch.recurring, r)
+			overrides, err := mapToOverrides(t, r.Task)
+			if err != nil {
+				return batch, fmt.Errorf("error loading tasks: %w", err)
+			}
+			batch.overrides = append(batch.overrides, overrides...)
 			continue
 		}
-		return fmt.Errorf("LoadFile: error parsing tasks: bad number of keys found")
-	}
-	transientBackup := make(map[string]Task)
-	antiBackup := make(map[string]AntiTask)
-	recurBackup := make(map[string]RecurringTask)
-	// Add the recurring tasks, then the anti tasks, then the transient and subtasks
-	// Backup recurring tasks
-	for key, val := range s.RecurringTasks {
-		recurBackup[key] = val
-	}
-	for _, m := range recurTaskBuff {
-		if err := recurKeysPresent(m); err != nil {
-			s.RecurringTasks = recurBackup // Revert recurring tasks if there is an error
-			return fmt.Errorf("LoadFile: error loading tasks: task values missing: %v", err)
+		// Either a recurring subtask, an anti task, or a transient task
+		if _, ok := t[TYPE_KEY]; !ok {
+			return batch, invalidf("error parsing tasks: missing %q field", TYPE_KEY)
 		}
-		name, taskType, date, startTime, duration, endDate, frequency, err := mapToRecurInfo(m)
-		if err != nil {
-			s.RecurringTasks = recurBackup
-			return fmt.Errorf("LoadFile: error loading tasks: %v", err)
+		taskType, ok := t[TYPE_KEY].(string)
+		if !ok {
+			return batch, invalidf("error parsing tasks: could not assert type field to string")
 		}
-		err = s.AddRecurringTask(name, taskType, date, startTime, duration, endDate, frequency)
-		if err != nil {
-			s.RecurringTasks = recurBackup
-			return fmt.Errorf("LoadFile: error loading tasks: %v", err)
+		if _, ok := types.Lookup(taskType); !ok {
+			return batch, invalidf("error parsing tasks: bad type found: %q", taskType)
 		}
-	}
-	// Backup anti tasks
-	for key, val := range s.AntiTasks {
-		antiBackup[key] = val
-	}
-	for _, m := range antiTaskBuff {
-		if err := taskKeysPresent(m); err != nil {
-			s.RecurringTasks = recurBackup
-			s.AntiTasks = antiBackup
-			return fmt.Errorf("LoadFile: error loading tasks: task values missing: %v", err)
+		if err := taskKeysPresent(t); err != nil {
+			return batch, invalidf("error loading tasks: task values missing: %v", err)
 		}
-		name, taskType, date, startTime, duration, err := mapToTaskInfo(m)
+		name, taskType, date, startTime, duration, err := mapToTaskInfo(t)
 		if err != nil {
-			s.RecurringTasks = recurBackup
-			s.AntiTasks = antiBackup
-			return fmt.Errorf("LoadFile: error loading tasks: %v", err)
+			return batch, fmt.Errorf("error loading tasks: %w", err)
 		}
-		err = s.AddAntiTask(name, taskType, date, startTime, duration)
-		if err != nil {
-			s.RecurringTasks = recurBackup
-			s.AntiTasks = antiBackup
-			return fmt.Errorf("LoadFile: error loading tasks: %v", err)
+		task := Task{ID: id, Name: name, Type: taskType, Date: date, StartTime: startTime, Duration: duration, TimeZone: zone}
+		switch {
+		case types.is(taskType, TRANSIENT):
+			batch.transient = append(batch.transient, task)
+		case types.is(taskType, ANTI):
+			a, err := mapToAntiRule(t, task)
+			if err != nil {
+				return batch, fmt.Errorf("error loading tasks: %w", err)
+			}
+			batch.anti = append(batch.anti, a)
+		default:
+			batch.subtasks = append(batch.subtasks, task)
 		}
 	}
-	// Backup transient tasks
-	for key, val := range s.TransientTasks {
-		transientBackup[key] = val
-	}
-	for _, m := range transientTaskBuff {
-		if err := taskKeysPresent(m); err != nil {
-			s.RecurringTasks = recurBackup
-			s.AntiTasks = antiBackup
-			s.TransientTasks = transientBackup
-			return fmt.Errorf("LoadFile: error loading tasks: task values missing: %v", err)
+	return batch, nil
+}
+
+// taskBatch holds tasks read from a file that have not yet been added to the schedule
+type taskBatch struct {
+	blackouts []Blackout
+	recurring []RecurringTask
+	anti      []AntiTask
+	overrides []Override
+	transient []Task
+	subtasks  []Task
+}
+
+// addBatch adds the recurring tasks, then the anti tasks, then the overrides, then the transient and
+// subtasks of a batch
+// Either all of the tasks are added or the schedule is left unchanged
+func (s *Schedule) addBatch(batch taskBatch) error {
+	return s.atomically("load tasks", func() error {
+		for _, b := range batch.blackouts {
+			if err := s.addBlackout(b); err != nil {
+				return err
+			}
 		}
-		name, taskType, date, startTime, duration, err := mapToTaskInfo(m)
-		if err != nil {
-			s.RecurringTasks = recurBackup
-			s.AntiTasks = antiBackup
-			s.TransientTasks = transientBackup
-			return fmt.Errorf("LoadFile: error loading tasks: %v", err)
+		for _, r := range batch.recurring {
+			if err := s.addRecurringTask(r); err != nil {
+				return err
+			}
 		}
-		err = s.AddTransientTask(name, taskType, date, startTime, duration)
-		if err != nil {
-			s.RecurringTasks = recurBackup
-			s.AntiTasks = antiBackup
-			s.TransientTasks = transientBackup
-			return fmt.Errorf("LoadFile: error loading tasks: %v", err)
+		for _, a := range batch.anti {
+			if err := s.addAntiTask(a); err != nil {
+				return err
+			}
 		}
-	}
-	for _, m := range subTaskBuff {
-		if err := taskKeysPresent(m); err != nil {
-			s.RecurringTasks = recurBackup
-			s.AntiTasks = antiBackup
-			s.TransientTasks = transientBackup
-			return fmt.Errorf("LoadFile: error loading tasks: task values missing: %v", err)
+		for _, o := range batch.overrides {
+			if err := s.addOverride(o); err != nil {
+				return err
+			}
 		}
-		name, taskType, date, startTime, duration, err := mapToTaskInfo(m)
-		if err != nil {
-			s.RecurringTasks = recurBackup
-			s.AntiTasks = antiBackup
-			s.TransientTasks = transientBackup
-			return fmt.Errorf("LoadFile: error loading tasks: %v", err)
-		}
-		// Append date to disambiguate subtask name
-		name += fmt.Sprintf(" (%4d-%02d-%02d)", date/10000, (date/100)%100, date%100)
-		err = s.AddSubtask(name, taskType, date, startTime, duration)
-		if err != nil {
-			s.RecurringTasks = recurBackup
-			s.AntiTasks = antiBackup
-			s.TransientTasks = transientBackup
-			return fmt.Errorf("LoadFile: error loading tasks: %v", err)
+		for _, t := range batch.transient {
+			if err := s.addTransientTask(t); err != nil {
+				return err
+			}
 		}
+		for _, t := range batch.subtasks {
+			if err := s.addSubtask(t); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// WriteTasks writes all tasks in the schedule to a specified file in JSON format
+func (s *Schedule) WriteTasks(path string) error {
+	content, err := s.MarshalTasks()
+	if err != nil {
+		return fmt.Errorf("WriteTasks: %v", err)
+	}
+	if err := writeFile(path, content); err != nil {
+		return fmt.Errorf("WriteTasks: %v", err)
 	}
 	return nil
 }
 
-// WriteTasks writes all tasks in the schedule to a specified file in JSON format
-func (s Schedule) WriteTasks(path string) error {
+// WriteTaskList writes a list of tasks into a specified file in JSON format
+func (s *Schedule) WriteTaskList(path string, tasks []Task) error {
+	content, err := s.MarshalTaskList(tasks)
+	if err != nil {
+		return fmt.Errorf("WriteTaskList: %v", err)
+	}
+	if err := writeFile(path, content); err != nil {
+		return fmt.Errorf("WriteTaskList: %v", err)
+	}
+	return nil
+}
+
+// MarshalTasks encodes all tasks in the schedule in the JSON format written by WriteTasks
+func (s *Schedule) MarshalTasks() ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.taskSet.MarshalTasks()
+}
+
+// MarshalTasks encodes all tasks in the set in the JSON format written by WriteTasks
+func (ts taskSet) MarshalTasks() ([]byte, error) {
 	allTasks := []interface{}{}
 	// Compile all the tasks
-	for _, t := range s.TransientTasks {
+	for _, t := range ts.transientTasks {
 		allTasks = append(allTasks, taskToContainer(t))
 	}
-	for _, t := range s.AntiTasks {
-		allTasks = append(allTasks, taskToContainer(t.Task))
+	for _, t := range ts.antiTasks {
+		allTasks = append(allTasks, antiToContainer(t))
 	}
-	for _, t := range s.RecurringTasks {
-		allTasks = append(allTasks, recurToContainer(t))
+	for _, t := range ts.recurringTasks {
+		c := recurToContainer(t)
+		for _, o := range ts.overridesOf(t.ID) {
+			c.Overrides = append(c.Overrides, overrideToContainer(o))
+		}
+		sort.Slice(c.Overrides, func(i, j int) bool {
+			return c.Overrides[i].Original < c.Overrides[j].Original
+		})
+		allTasks = append(allTasks, c)
 	}
-	outFile, err := os.Create(path)
-	if err != nil {
-		return fmt.Errorf("WriteTasks: error creating file: %v", err)
+	for _, b := range ts.blackouts {
+		allTasks = append(allTasks, blackoutToContainer(b))
 	}
 	content, err := json.MarshalIndent(allTasks, "", "\t")
 	if err != nil {
-		return fmt.Errorf("WriteTasks: error marshaling json: %v", err)
+		return nil, fmt.Errorf("error marshaling json: %v", err)
 	}
-	_, err = outFile.Write(content)
-	if err != nil {
-		return fmt.Errorf("WriteTasks: error writing to file: %v", err)
-	}
-	err = outFile.Close()
-	if err != nil {
-		return fmt.Errorf("WriteTasks: error closing file: %v", err)
-	}
-	return nil
+	return content, nil
 }
 
-// WriteTaskList writes a list of tasks into a specified file in JSON format
-func (s Schedule) WriteTaskList(path string, tasks []Task) error {
+// MarshalTaskList encodes a list of tasks in the JSON format written by WriteTaskList
+// IDs are left out, as the subtasks of a recurring task share its ID and the list could not be loaded back
+func (s *Schedule) MarshalTaskList(tasks []Task) ([]byte, error) {
 	l := []interface{}{}
 	for _, t := range tasks {
-		l = append(l, taskToContainer(t))
-	}
-	outFile, err := os.Create(path)
-	if err != nil {
-		return fmt.Errorf("WriteTaskList: error creating file: %v", err)
+		c := taskToContainer(t)
+		c.ID = ""
+		l = append(l, c)
 	}
 	content, err := json.MarshalIndent(l, "", "\t")
 	if err != nil {
-		return fmt.Errorf("WriteTaskList: error marshaling json: %v", err)
-	}
-	_, err = outFile.Write(content)
-	if err != nil {
-		return fmt.Errorf("WriteTaskList: error writing to file: %v", err)
-	}
-	err = outFile.Close()
-	if err != nil {
-		return fmt.Errorf("WriteTaskList: error closing file: %v", err)
+		return nil, fmt.Errorf("error marshaling json: %v", err)
 	}
-	return nil
+	return content, nil
 }
 
 // hasAnti checks if an anti task that cancels the specified task exists in the schedule
-func (s Schedule) hasAnti(task Task) bool {
-	for _, anti := range s.AntiTasks {
-		if anti.Cancels(task) {
+func (ts taskSet) hasAnti(task Task) bool {
+	r := ts.recurringTasks[task.ID]
+	for _, anti := range ts.antiNear(task) {
+		if anti.CancelsSubtask(r, task) {
 			return true
 		}
 	}
 	return false
 }
 
-// hasNameConflict checks if a task of the same name already exists in the schedule
-func (s Schedule) hasNameConflict(name string) bool {
-	if _, ok := s.TransientTasks[name]; ok {
-		return true
-	}
-	if _, ok := s.AntiTasks[name]; ok {
-		return true
-	}
-	if _, ok := s.RecurringTasks[name]; ok {
-		return true
-	}
-	return false
+// hasAddConflict checks if a task will produce scheduling conflicts if added
+func (ts taskSet) hasAddConflict(task Task) bool {
+	return ts.hasConflictReplacing(task, "")
 }
 
-// hasAddConflict checks if a task will produce scheduling conflicts if added
-func (s Schedule) hasAddConflict(task Task) bool {
-	// Check against all transient tasks
-	for n, t := range s.TransientTasks {
-		if n == task.Name {
+// hasConflictReplacing checks if a task will produce scheduling conflicts if added in place of the subtask
+// with the override key given, which is left out of the check along with its override
+func (ts taskSet) hasConflictReplacing(task Task, key string) bool {
+	// Check against the blackout periods that reject subtasks, which keep out subtasks brought back by
+	// deleting an anti task or override
+	for _, b := range ts.blackouts {
+		if b.Policy == BLACKOUT_REJECT && b.overlaps(task) {
+			return true
+		}
+	}
+	// Check against the transient tasks on the same days
+	for _, t := range ts.transientNear(task) {
+		if t.ID == task.ID {
 			// Don't check against itself
 			continue
 		}
@@ -569,11 +1208,17 @@ func (s Schedule) hasAddConflict(task Task) bool {
 			return true
 		}
 	}
+	// Check against the overrides on the same days
+	for _, o := range ts.overridesNear(task) {
+		if o.key() != key && task.Overlaps(o.Task) {
+			return true
+		}
+	}
 	// Check against all recurring tasks
-	for _, t := range s.RecurringTasks {
+	for _, t := range ts.recurringTasks {
 		overlaps, _ := t.GetOverlappingSubtasks(task)
 		for _, o := range overlaps {
-			if !s.hasAnti(o) {
+			if overrideKey(o.ID, o.Date) != key && !ts.hidden(o) {
 				return true
 			}
 		}
@@ -582,19 +1227,30 @@ func (s Schedule) hasAddConflict(task Task) bool {
 }
 
 // hasAddConflictRecurring checks if a recurring task will produce scheduling conflicts if added
-func (s Schedule) hasAddConflictRecurring(task RecurringTask) bool {
-	for _, t := range s.TransientTasks {
-		overlaps, _ := task.GetOverlappingSubtasks(t)
+func (ts taskSet) hasAddConflictRecurring(task RecurringTask) bool {
+	// Check the transient tasks in the span of the series against the subtasks they overlap, so that long
+	// and open-ended series are never expanded in full
+	for _, n := range ts.transientDays.between(task.spanDays()) {
+		overlaps, _ := task.GetOverlappingSubtasks(ts.transientTasks[n])
+		for _, o := range overlaps {
+			if !ts.hidden(o) {
+				return true
+			}
+		}
+	}
+	// Likewise for the overrides of other recurring tasks
+	for _, key := range ts.overrideDays.between(task.spanDays()) {
+		overlaps, _ := task.GetOverlappingSubtasks(ts.overrides[key].Task)
 		for _, o := range overlaps {
-			if !s.hasAnti(o) {
+			if !ts.hidden(o) {
 				return true
 			}
 		}
 	}
 	// Check against all recurring tasks
 	// As per the project specs, anti tasks cannot be applied to overlaps between 2 recurring tasks
-	for n, t := range s.RecurringTasks {
-		if n == task.Name {
+	for id, t := range ts.recurringTasks {
+		if id == task.ID {
 			continue
 		}
 		if task.OverlapsRecurring(t) {
@@ -604,18 +1260,14 @@ func (s Schedule) hasAddConflictRecurring(task RecurringTask) bool {
 	return false
 }
 
-// hasDeleteConflict checks if a task will produce a scheduling conflict if deleted
-func (s Schedule) hasDeleteConflict(task Task) bool {
-	// Only have to check deletion conflicts if task is an anti task
-	if !isAntiType(task.Type) {
-		return false
-	}
-	a := AntiTask{task}
-	for _, t := range s.RecurringTasks {
-		// For every cancelled subtask, check if there is an overlap in the schedule with that
-		// subtask
-		if cancelled, ok := a.GetCancelledSubtask(t); ok {
-			if s.hasAddConflict(cancelled) {
+// hasDeleteConflict checks if an anti task that has been removed from the set brought back subtasks that
+// produce a scheduling conflict
+func (ts taskSet) hasDeleteConflict(a AntiTask) bool {
+	for _, t := range ts.recurringTasks {
+		// For every cancelled subtask that is back, check if there is an overlap in the schedule with that
+		// subtask other than the subtask itself
+		for _, cancelled := range a.GetCancelledSubtasks(t) {
+			if !ts.hidden(cancelled) && ts.hasConflictReplacing(cancelled, overrideKey(cancelled.ID, cancelled.Date)) {
 				return true
 			}
 		}
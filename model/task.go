@@ -4,44 +4,118 @@ package model
 
 import (
 	"fmt"
-	"math"
+	"sync"
 	"time"
 )
 
 // Task is the base class and the transient task
 type Task struct {
+	ID        string // Unique and kept through edits, shared by a recurring task and its subtasks, see ids.go
 	Name      string
 	Type      string
 	Date      int
-	StartTime float32
-	Duration  float32
+	StartTime time.Duration // Time of day the task starts, counted from midnight
+	Duration  time.Duration // May run past midnight into the following days
+	TimeZone  string        // IANA name of the time zone of Date and StartTime, UTC if empty
 }
 
-func NewTask(name, taskType string, date int, startTime, duration float32) (Task, error) {
+// locations caches the time zones loaded by loadLocation
+var locations sync.Map
+
+// loadLocation loads a time zone by IANA name, with the empty name standing for UTC
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	locations.Store(name, loc)
+	return loc, nil
+}
+
+// NewTask creates a task with its start time and duration rounded to the nearest DEFAULT_SNAP
+func NewTask(name, taskType string, date int, startTime, duration time.Duration) (Task, error) {
+	return newTask(name, taskType, date, startTime, duration, DEFAULT_SNAP)
+}
+
+// newTask creates a task with its start time and duration rounded to the nearest multiple of snap
+func newTask(name, taskType string, date int, startTime, duration, snap time.Duration) (Task, error) {
 	var result Task
-	if startTime < 0 || startTime > 23.75 {
-		return result, fmt.Errorf("bad start time")
+	startTime, duration = startTime.Round(snap), duration.Round(snap)
+	if startTime < 0 || startTime >= 24*time.Hour {
+		return result, invalidf("bad start time")
 	}
-	if duration < 0 || duration > 23.75 {
-		return result, fmt.Errorf("bad duration")
+	if duration < 0 || duration > MAX_DURATION {
+		return result, invalidf("bad duration")
 	}
 	if _, err := intToDate(date); err != nil {
-		return result, fmt.Errorf("bad date")
+		return result, invalidf("bad date")
 	}
 	result.Name = name
 	result.Date = date
-	// Round time to nearest .25
-	result.StartTime = float32(math.Round(float64(startTime)/.25) * .25)
-	// Round duration to nearest .25
-	duration = float32(math.Round(float64(duration)/.25) * .25)
+	result.StartTime = startTime
 	result.Duration = duration
 	result.Type = taskType
 	return result, nil
 }
 
+// normalize checks the fields of a task built by the caller and rounds its times to the nearest
+// multiple of snap
+// A time zone of "UTC" is stored as the empty name
+func (t Task) normalize(snap time.Duration) (Task, error) {
+	result, err := newTask(t.Name, t.Type, t.Date, t.StartTime, t.Duration, snap)
+	if err != nil {
+		return result, err
+	}
+	loc, err := loadLocation(t.TimeZone)
+	if err != nil {
+		return result, invalidf("bad time zone %q", t.TimeZone)
+	}
+	result.TimeZone = zoneName(loc)
+	result.ID = t.ID
+	return result, nil
+}
+
 func (t Task) String() string {
-	return fmt.Sprintf("Name: %v\nType: %v\nStart Date: %v\nStart Time: %02d:%02d\nDuration: %v",
-		t.Name, t.Type, dateIntToString(t.Date), int(math.Floor(float64(t.StartTime))), int((float64(t.StartTime)-math.Floor(float64(t.StartTime)))*60), t.Duration)
+	s := fmt.Sprintf("Name: %v\nType: %v\nStart Date: %v\nStart Time: %v\nDuration: %v",
+		t.Name, t.Type, dateIntToString(t.Date), formatClock(t.StartTime), formatHours(t.Duration))
+	if t.ID != "" {
+		s = fmt.Sprintf("ID: %v\n", t.ID) + s
+	}
+	if end, err := t.GetEndTime(); err == nil && dateToInt(end) != t.Date {
+		s += fmt.Sprintf("\nEnds: %v %v", dateIntToString(dateToInt(end)), end.Format("15:04"))
+	}
+	if t.TimeZone != "" {
+		s += fmt.Sprintf("\nTime Zone: %v", t.TimeZone)
+	}
+	return s
+}
+
+// Location gets the time zone of the task, falling back to UTC if it cannot be loaded
+func (t Task) Location() *time.Location {
+	loc, err := loadLocation(t.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// In returns the task with its date and start time as seen in another time zone
+func (t Task) In(loc *time.Location) Task {
+	start, err := t.GetStartDate()
+	if err != nil {
+		return t
+	}
+	start = start.In(loc)
+	t.Date = dateToInt(start)
+	t.StartTime = time.Duration(start.Hour())*time.Hour + time.Duration(start.Minute())*time.Minute
+	t.TimeZone = zoneName(loc)
+	return t
 }
 
 func (t Task) GetStartYear() int {
@@ -56,44 +130,108 @@ func (t Task) GetStartDay() int {
 	return t.Date % 100
 }
 
-// GetStartDate gets the start date of the task as a Time struct
+// TasksIn returns a list of tasks as seen in another time zone, see Task.In
+func TasksIn(tasks []Task, loc *time.Location) []Task {
+	result := make([]Task, 0, len(tasks))
+	for _, t := range tasks {
+		result = append(result, t.In(loc))
+	}
+	return result
+}
+
+// GetStartDate gets the start date of the task as a Time struct in the task's time zone
+// The start time is a wall clock time, so it is kept on days when daylight saving time begins or ends
 func (t Task) GetStartDate() (time.Time, error) {
 	date, err := intToDate(t.Date)
 	if err != nil {
 		return date, fmt.Errorf("GetStartDate: %v", err)
 	}
-	// Account for the start time
-	date = date.Add((time.Hour * time.Duration(t.StartTime)) +
-		(time.Minute * 60 * time.Duration(float64(t.StartTime)-math.Floor(float64(t.StartTime)))))
-	return date, nil
+	// Account for the start time, which time.Date carries over from nanoseconds into the clock time
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, int(t.StartTime), t.Location()), nil
+}
+
+// GetEndTime gets the time the task ends in the task's time zone
+func (t Task) GetEndTime() (time.Time, error) {
+	start, err := t.GetStartDate()
+	if err != nil {
+		return start, fmt.Errorf("GetEndTime: %v", err)
+	}
+	return start.Add(t.Duration), nil
+}
+
+// Days gets the dates of every day the task touches in its own time zone, from its start date to the
+// date it ends on
+// A task ending exactly at midnight does not touch the day after
+func (t Task) Days() []int {
+	result := []int{}
+	start, err := t.GetStartDate()
+	if err != nil {
+		return result
+	}
+	last := start.Add(t.Duration)
+	if t.Duration > 0 {
+		last = last.Add(-time.Nanosecond)
+	}
+	y, m, d := start.Date()
+	for day := time.Date(y, m, d, 0, 0, 0, 0, start.Location()); !day.After(last); day = day.AddDate(0, 0, 1) {
+		result = append(result, dateToInt(day))
+	}
+	return result
 }
 
 // GetStartDateWithouttime gets the start date as a Time struct without accounting for start time
 func (t Task) GetStartDateWithoutTime() (time.Time, error) {
-	return intToDate(t.Date)
+	date, err := intToDate(t.Date)
+	if err != nil {
+		return date, err
+	}
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, t.Location()), nil
 }
 
 // Overlaps returns true if this task overlaps with the duration of another task
 func (t Task) Overlaps(op Task) bool {
 	time1, _ := t.GetStartDate()
 	time2, _ := op.GetStartDate()
-	// Difference in start date in hours
-	timeDelta := math.Abs(float64(time1.Unix()-time2.Unix())) / (60 * 60)
+	// Difference in start date
+	timeDelta := time1.Sub(time2)
+	if timeDelta == 0 {
+		// Tasks starting together overlap unless neither takes any time
+		return t.Duration > 0 || op.Duration > 0
+	}
+	if timeDelta < 0 {
+		timeDelta = -timeDelta
+	}
 	var earlierTask Task
 	if time1.Before(time2) {
 		earlierTask = t
 	} else {
 		earlierTask = op
 	}
-	return timeDelta < float64(earlierTask.Duration)
+	return timeDelta < earlierTask.Duration
+}
+
+// overlapsSpan returns true if this task overlaps the span from start up to end
+// A task with no duration overlaps the span if it starts within it
+func (t Task) overlapsSpan(start, end time.Time) bool {
+	tStart, _ := t.GetStartDate()
+	tEnd := tStart.Add(t.Duration)
+	if !tStart.Before(end) {
+		return false
+	}
+	return tEnd.After(start) || (tEnd.Equal(tStart) && !tStart.Before(start))
 }
 
 // Before returns true if this task occurs strictly before another task
 func (t Task) Before(op Task) bool {
-	if t.Date == op.Date {
-		return t.StartTime < op.StartTime
+	if t.TimeZone == op.TimeZone {
+		if t.Date == op.Date {
+			return t.StartTime < op.StartTime
+		}
+		return t.Date < op.Date
 	}
-	return t.Date < op.Date
+	time1, _ := t.GetStartDate()
+	time2, _ := op.GetStartDate()
+	return time1.Before(time2)
 }
 
 //!--
@@ -0,0 +1,277 @@
+// Package model provides functionality for creating and managing a schedule of tasks
+// task_set.go provides lookups of the tasks in a schedule and read-only snapshots of a schedule
+package model
+
+import (
+	"sort"
+	"time"
+)
+
+// taskSet holds the tasks of a schedule
+// Only the journaled methods of Schedule change a taskSet, through the set and delete methods below
+// Tasks are stored under their IDs, see ids.go
+type taskSet struct {
+	transientTasks map[string]Task
+	antiTasks      map[string]AntiTask
+	recurringTasks map[string]RecurringTask
+	names          map[string][]string // IDs of the tasks with each name
+	overrides      map[string]Override // Overrides of single subtasks, see override.go
+	blackouts      map[string]Blackout // Periods in which nothing may be scheduled, see blackout.go
+	transientDays  *dayIndex           // Days touched by the transient tasks, see index.go
+	antiDays       *dayIndex           // Days touched by the anti tasks
+	overrideDays   *dayIndex           // Days touched by the overrides
+}
+
+// newTaskSet creates an empty task set
+func newTaskSet() taskSet {
+	return taskSet{
+		transientTasks: map[string]Task{},
+		antiTasks:      map[string]AntiTask{},
+		recurringTasks: map[string]RecurringTask{},
+		names:          map[string][]string{},
+		overrides:      map[string]Override{},
+		blackouts:      map[string]Blackout{},
+		transientDays:  newDayIndex(),
+		antiDays:       newDayIndex(),
+		overrideDays:   newDayIndex(),
+	}
+}
+
+// copy returns a task set holding the same tasks that is not changed by changes to ts
+func (ts taskSet) copy() taskSet {
+	c := taskSet{
+		transientTasks: make(map[string]Task, len(ts.transientTasks)),
+		antiTasks:      make(map[string]AntiTask, len(ts.antiTasks)),
+		recurringTasks: make(map[string]RecurringTask, len(ts.recurringTasks)),
+		names:          make(map[string][]string, len(ts.names)),
+		overrides:      make(map[string]Override, len(ts.overrides)),
+		blackouts:      make(map[string]Blackout, len(ts.blackouts)),
+		transientDays:  ts.transientDays.copy(),
+		antiDays:       ts.antiDays.copy(),
+		overrideDays:   ts.overrideDays.copy(),
+	}
+	for n, t := range ts.transientTasks {
+		c.transientTasks[n] = t
+	}
+	for n, t := range ts.antiTasks {
+		c.antiTasks[n] = t
+	}
+	for n, t := range ts.recurringTasks {
+		c.recurringTasks[n] = t
+	}
+	for n, ids := range ts.names {
+		c.names[n] = append([]string(nil), ids...)
+	}
+	for k, o := range ts.overrides {
+		c.overrides[k] = o
+	}
+	for n, b := range ts.blackouts {
+		c.blackouts[n] = b
+	}
+	return c
+}
+
+// The following methods keep the day indexes in step with the task maps
+
+// setTransient adds or replaces a transient task
+func (ts taskSet) setTransient(t Task) {
+	ts.deleteTransient(t.ID)
+	ts.transientTasks[t.ID] = t
+	ts.transientDays.add(t)
+	ts.addName(t.Name, t.ID)
+}
+
+// deleteTransient removes a transient task
+func (ts taskSet) deleteTransient(id string) {
+	if old, ok := ts.transientTasks[id]; ok {
+		ts.transientDays.remove(old)
+		ts.removeName(old.Name, id)
+		delete(ts.transientTasks, id)
+	}
+}
+
+// setAnti adds or replaces an anti task
+func (ts taskSet) setAnti(a AntiTask) {
+	ts.deleteAnti(a.ID)
+	ts.antiTasks[a.ID] = a
+	first, last := a.days()
+	ts.antiDays.addDays(a.ID, first, last)
+	ts.addName(a.Name, a.ID)
+}
+
+// deleteAnti removes an anti task
+func (ts taskSet) deleteAnti(id string) {
+	if old, ok := ts.antiTasks[id]; ok {
+		first, last := old.days()
+		ts.antiDays.removeDays(id, first, last)
+		ts.removeName(old.Name, id)
+		delete(ts.antiTasks, id)
+	}
+}
+
+// setRecurring adds or replaces a recurring task
+func (ts taskSet) setRecurring(r RecurringTask) {
+	ts.deleteRecurring(r.ID)
+	ts.recurringTasks[r.ID] = r
+	ts.addName(r.Name, r.ID)
+}
+
+// deleteRecurring removes a recurring task
+func (ts taskSet) deleteRecurring(id string) {
+	if old, ok := ts.recurringTasks[id]; ok {
+		ts.removeName(old.Name, id)
+		delete(ts.recurringTasks, id)
+	}
+}
+
+// transientNear gets the transient tasks touching any of the days touched by a task
+// These are the only transient tasks that can overlap it
+func (ts taskSet) transientNear(t Task) []Task {
+	result := []Task{}
+	for _, n := range ts.transientDays.between(taskDays(t)) {
+		result = append(result, ts.transientTasks[n])
+	}
+	return result
+}
+
+// antiNear gets the anti tasks touching any of the days touched by a task
+func (ts taskSet) antiNear(t Task) []AntiTask {
+	result := []AntiTask{}
+	for _, n := range ts.antiDays.between(taskDays(t)) {
+		result = append(result, ts.antiTasks[n])
+	}
+	return result
+}
+
+// TransientTask gets a transient task or recurring subtask by ID or by a name no other one has
+func (ts taskSet) TransientTask(ref string) (Task, bool) {
+	id, err := ts.resolve(ref, ts.isTransient)
+	return ts.transientTasks[id], err == nil
+}
+
+// AntiTask gets an anti task by ID or by a name no other one has
+func (ts taskSet) AntiTask(ref string) (AntiTask, bool) {
+	id, err := ts.resolve(ref, ts.isAnti)
+	return ts.antiTasks[id], err == nil
+}
+
+// RecurringTask gets a recurring task by ID or by a name no other one has
+func (ts taskSet) RecurringTask(ref string) (RecurringTask, bool) {
+	id, err := ts.resolve(ref, ts.isRecurring)
+	return ts.recurringTasks[id], err == nil
+}
+
+// byName orders tasks by name, and tasks sharing a name by ID
+func byName(a, b Task) bool {
+	if a.Name != b.Name {
+		return a.Name < b.Name
+	}
+	return a.ID < b.ID
+}
+
+// TransientTasks gets all transient tasks and recurring subtasks sorted by name
+func (ts taskSet) TransientTasks() []Task {
+	result := make([]Task, 0, len(ts.transientTasks))
+	for _, t := range ts.transientTasks {
+		result = append(result, t)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return byName(result[i], result[j])
+	})
+	return result
+}
+
+// AntiTasks gets all anti tasks sorted by name
+func (ts taskSet) AntiTasks() []AntiTask {
+	result := make([]AntiTask, 0, len(ts.antiTasks))
+	for _, t := range ts.antiTasks {
+		result = append(result, t)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return byName(result[i].Task, result[j].Task)
+	})
+	return result
+}
+
+// RecurringTasks gets all recurring tasks sorted by name
+func (ts taskSet) RecurringTasks() []RecurringTask {
+	result := make([]RecurringTask, 0, len(ts.recurringTasks))
+	for _, t := range ts.recurringTasks {
+		result = append(result, t)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return byName(result[i].Task, result[j].Task)
+	})
+	return result
+}
+
+// Snapshot is a read-only copy of the tasks in a schedule at one point in time
+// Queries on a snapshot never wait on, or see, later changes to the schedule
+type Snapshot struct {
+	taskSet
+	weekStart time.Weekday
+}
+
+// Snapshot takes a read-only copy of the tasks in the schedule
+func (s *Schedule) Snapshot() *Snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return &Snapshot{s.taskSet.copy(), s.weekStart}
+}
+
+// GetTasksByWeek gets all tasks/subtasks occuring in the week of the specified month and day
+func (snap *Snapshot) GetTasksByWeek(month, day int) ([]Task, error) {
+	return snap.getTasksByWeek(month, day, snap.weekStart)
+}
+
+// GetTasksInWeek gets all tasks/subtasks in the seven days of the week containing a date
+func (snap *Snapshot) GetTasksInWeek(date time.Time) ([]Task, error) {
+	start, end := WeekOf(date, snap.weekStart)
+	return snap.GetTasksInRange(start, end)
+}
+
+// The following methods are safe to call from multiple goroutines
+
+// TransientTask gets a transient task or recurring subtask by ID or by a name no other one has
+func (s *Schedule) TransientTask(ref string) (Task, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.taskSet.TransientTask(ref)
+}
+
+// AntiTask gets an anti task by ID or by a name no other one has
+func (s *Schedule) AntiTask(ref string) (AntiTask, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.taskSet.AntiTask(ref)
+}
+
+// RecurringTask gets a recurring task by ID or by a name no other one has
+func (s *Schedule) RecurringTask(ref string) (RecurringTask, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.taskSet.RecurringTask(ref)
+}
+
+// TransientTasks gets all transient tasks and recurring subtasks sorted by name
+func (s *Schedule) TransientTasks() []Task {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.taskSet.TransientTasks()
+}
+
+// AntiTasks gets all anti tasks sorted by name
+func (s *Schedule) AntiTasks() []AntiTask {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.taskSet.AntiTasks()
+}
+
+// RecurringTasks gets all recurring tasks sorted by name
+func (s *Schedule) RecurringTasks() []RecurringTask {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.taskSet.RecurringTasks()
+}
+
+//!--
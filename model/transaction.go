@@ -0,0 +1,471 @@
+// Package model provides functionality for creating and managing a schedule of tasks
+// transaction.go provides transactions that apply a batch of changes to a schedule all-or-nothing
+package model
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ErrTxDone is returned when a transaction is used after it has been committed or rolled back
+var ErrTxDone = errors.New("transaction has already been committed or rolled back")
+
+// change is a reversible modification of one of the task maps
+type change struct {
+	undo func()
+	redo func()
+}
+
+// Tx is a transaction on a schedule
+// Changes made through the transaction are kept by Commit or all reverted by Rollback
+// An open transaction holds the schedule's write lock, so other goroutines wait for it to end and
+// the goroutine that began it must use the transaction, not the schedule, until then
+type Tx struct {
+	s     *Schedule
+	start int // Index of the first change of the transaction in the journal
+	done  bool
+}
+
+// Begin starts a transaction on the schedule
+// Begin waits for any open transaction or operation on the schedule to end
+func (s *Schedule) Begin() (*Tx, error) {
+	s.mu.Lock()
+	s.tx = &Tx{s: s, start: len(s.journal)}
+	return s.tx, nil
+}
+
+// Commit keeps every change made in the transaction
+// The transaction is recorded in the history as a single operation
+func (tx *Tx) Commit() error {
+	if tx.done {
+		return fmt.Errorf("Commit: %w", ErrTxDone)
+	}
+	tx.done = true
+	tx.s.record("transaction", tx.s.journal[tx.start:])
+	tx.s.tx = nil
+	tx.s.journal = nil
+	tx.s.mu.Unlock()
+	return nil
+}
+
+// Rollback reverts every change made in the transaction
+func (tx *Tx) Rollback() error {
+	if tx.done {
+		return fmt.Errorf("Rollback: %w", ErrTxDone)
+	}
+	tx.done = true
+	tx.s.rollbackTo(tx.start)
+	tx.s.tx = nil
+	tx.s.journal = nil
+	tx.s.mu.Unlock()
+	return nil
+}
+
+// Atomically runs fn in a transaction
+// The transaction is committed if fn returns nil and rolled back if it returns an error or panics
+func (s *Schedule) Atomically(fn func(tx *Tx) error) (err error) {
+	tx, err := s.Begin()
+	if err != nil {
+		return fmt.Errorf("Atomically: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// TransientTask gets a transient task or recurring subtask by ID or name, including changes made in the transaction
+func (tx *Tx) TransientTask(ref string) (Task, bool) {
+	return tx.s.taskSet.TransientTask(ref)
+}
+
+// AntiTask gets an anti task by ID or name, including changes made in the transaction
+func (tx *Tx) AntiTask(ref string) (AntiTask, bool) {
+	return tx.s.taskSet.AntiTask(ref)
+}
+
+// RecurringTask gets a recurring task by ID or name, including changes made in the transaction
+func (tx *Tx) RecurringTask(ref string) (RecurringTask, bool) {
+	return tx.s.taskSet.RecurringTask(ref)
+}
+
+// TaskID gets the ID of the task a reference names, including changes made in the transaction
+func (tx *Tx) TaskID(ref string) (string, error) {
+	id, err := tx.s.resolve(ref, tx.s.hasID)
+	if err != nil {
+		return "", fmt.Errorf("TaskID: %w", err)
+	}
+	return id, nil
+}
+
+// Override gets the override of the subtask of a recurring task on a date, including changes made in the transaction
+func (tx *Tx) Override(ref string, date int) (Override, bool) {
+	return tx.s.taskSet.Override(ref, date)
+}
+
+// The following methods make changes to the schedule as part of the transaction
+// A change that fails is reverted on its own and does not end the transaction
+
+// AddTask adds a task built by the caller as a transient task, recurring subtask or anti task
+func (tx *Tx) AddTask(t Task) error {
+	return tx.apply("AddTask", func() error {
+		return tx.s.addTask(t)
+	})
+}
+
+// AddTransientTask creates and adds a transient task to the schedule
+func (tx *Tx) AddTransientTask(name, taskType string, date int, startTime, duration time.Duration) error {
+	return tx.apply("AddTransientTask", func() error {
+		return tx.s.addTransientTask(Task{Name: name, Type: taskType, Date: date, StartTime: startTime, Duration: duration})
+	})
+}
+
+// AddSubtask creates and adds a recurring subtask to the schedule
+func (tx *Tx) AddSubtask(name, taskType string, date int, startTime, duration time.Duration) error {
+	return tx.apply("AddSubtask", func() error {
+		return tx.s.addSubtask(Task{Name: name, Type: taskType, Date: date, StartTime: startTime, Duration: duration})
+	})
+}
+
+// AddAntiTask creates and adds an anti task to the schedule
+func (tx *Tx) AddAntiTask(name, taskType string, date int, startTime, duration time.Duration) error {
+	return tx.apply("AddAntiTask", func() error {
+		return tx.s.addAntiTask(AntiTask{Task: Task{Name: name, Type: taskType, Date: date, StartTime: startTime, Duration: duration}})
+	})
+}
+
+// AddRecurring adds a recurring task built by the caller
+func (tx *Tx) AddRecurring(r RecurringTask) error {
+	return tx.apply("AddRecurring", func() error {
+		return tx.s.addRecurringTask(r)
+	})
+}
+
+// AddRecurringTask creates and adds a recurring task to the schedule
+func (tx *Tx) AddRecurringTask(name, taskType string, date int, startTime, duration time.Duration, endDate, frequency int) error {
+	return tx.apply("AddRecurringTask", func() error {
+		return tx.s.addRecurringTask(RecurringTask{
+			Task:      Task{Name: name, Type: taskType, Date: date, StartTime: startTime, Duration: duration},
+			EndDate:   endDate,
+			Frequency: frequency,
+		})
+	})
+}
+
+// AddOverride moves or changes a subtask of a recurring task
+func (tx *Tx) AddOverride(o Override) error {
+	return tx.apply("AddOverride", func() error {
+		return tx.s.addOverride(o)
+	})
+}
+
+// DeleteOverride restores the subtask of a recurring task on a date that an override replaced
+func (tx *Tx) DeleteOverride(ref string, date int) error {
+	return tx.apply("DeleteOverride", func() error {
+		return tx.s.deleteOverrideOf(ref, date)
+	})
+}
+
+// DeleteTask deletes a task in the schedule by ID or name
+func (tx *Tx) DeleteTask(ref string) error {
+	return tx.apply("DeleteTask", func() error {
+		return tx.s.deleteTask(ref)
+	})
+}
+
+// EditTask replaces the details of an existing transient task, subtask or anti task with a task built by the caller
+func (tx *Tx) EditTask(taskName string, t Task) error {
+	return tx.apply("EditTask", func() error {
+		return tx.s.editTask(taskName, t)
+	})
+}
+
+// EditTransientTask edits the details of an existing transient task in the schedule
+func (tx *Tx) EditTransientTask(taskName, newName, newType string, newDate int, newStartTime, newDuration time.Duration) error {
+	return tx.apply("EditTransientTask", func() error {
+		return tx.s.editTransientTask(taskName, Task{Name: newName, Type: newType, Date: newDate, StartTime: newStartTime, Duration: newDuration})
+	})
+}
+
+// EditAntiTask edits the details of an existing anti task in the schedule
+// A range or recurring anti task keeps its end date, recurring task and frequency, and a generated anti task
+// keeps its holiday
+func (tx *Tx) EditAntiTask(taskName, newName string, newDate int, newStartTime, newDuration time.Duration) error {
+	return tx.apply("EditAntiTask", func() error {
+		a, _ := tx.s.taskSet.AntiTask(taskName)
+		return tx.s.editAntiTask(taskName, a.withDetails(Task{Name: newName, Date: newDate, StartTime: newStartTime, Duration: newDuration}))
+	})
+}
+
+// EditRecurring replaces the details of an existing recurring task with a recurring task built by the caller
+func (tx *Tx) EditRecurring(taskName string, r RecurringTask) error {
+	return tx.apply("EditRecurring", func() error {
+		return tx.s.editRecurringTask(taskName, r)
+	})
+}
+
+// EditRecurringTask edits the details of an existing recurring task in the schedule
+func (tx *Tx) EditRecurringTask(taskName, newName, newType string, newDate int, newStartTime, newDuration time.Duration, newEndDate, newFrequency int) error {
+	return tx.apply("EditRecurringTask", func() error {
+		return tx.s.editRecurringTask(taskName, RecurringTask{
+			Task:      Task{Name: newName, Type: newType, Date: newDate, StartTime: newStartTime, Duration: newDuration},
+			EndDate:   newEndDate,
+			Frequency: newFrequency,
+		})
+	})
+}
+
+// SplitRecurring changes a recurring task from a date onward by cutting it short and starting a new task there
+func (tx *Tx) SplitRecurring(taskName string, from int, newTask RecurringTask) error {
+	return tx.apply("SplitRecurring", func() error {
+		return tx.s.splitRecurringTask(taskName, from, newTask)
+	})
+}
+
+// AddBlackout adds a blackout period
+func (tx *Tx) AddBlackout(b Blackout) error {
+	return tx.apply("AddBlackout", func() error {
+		return tx.s.addBlackout(b)
+	})
+}
+
+// DeleteBlackout deletes a blackout period
+func (tx *Tx) DeleteBlackout(name string) error {
+	return tx.apply("DeleteBlackout", func() error {
+		return tx.s.deleteBlackout(name)
+	})
+}
+
+// CancelHolidays replaces the anti tasks generated for holidays with ones for the given holidays and types
+func (tx *Tx) CancelHolidays(holidays []Holiday, types []string) error {
+	return tx.apply("CancelHolidays", func() error {
+		return tx.s.cancelHolidays(holidays, types)
+	})
+}
+
+// RemoveHolidayCancellations deletes every anti task generated for holidays
+func (tx *Tx) RemoveHolidayCancellations() error {
+	return tx.apply("RemoveHolidayCancellations", func() error {
+		return tx.s.cancelHolidays(nil, nil)
+	})
+}
+
+// LoadFile loads the contents of the json file at the specified path into the schedule
+func (tx *Tx) LoadFile(path string) error {
+	return tx.apply("LoadFile", func() error {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("LoadFile: error reading file %q: %v", path, err)
+		}
+		batch, err := parseJSON(content, tx.s.types)
+		if err != nil {
+			return fmt.Errorf("LoadFile: %w", err)
+		}
+		if err := tx.s.addBatch(batch); err != nil {
+			return fmt.Errorf("LoadFile: error loading tasks: %w", err)
+		}
+		return nil
+	})
+}
+
+// apply makes a change to the schedule as part of the transaction
+// method names the Tx method making the change, for the error returned once the transaction is done
+func (tx *Tx) apply(method string, fn func() error) error {
+	if tx.done {
+		return fmt.Errorf("%s: %w", method, ErrTxDone)
+	}
+	// Operations inside a transaction are not recorded on their own, so they need no description
+	return tx.s.atomically("", fn)
+}
+
+// atomically runs an operation on the schedule
+// Every change the operation made is reverted if it returns an error or panics
+// Otherwise, if it is not part of a transaction, the operation is recorded in the history under desc
+func (s *Schedule) atomically(desc string, fn func() error) (err error) {
+	savepoint := len(s.journal)
+	s.depth++
+	defer func() {
+		s.depth--
+		if p := recover(); p != nil {
+			s.rollbackTo(savepoint)
+			panic(p)
+		}
+		if err != nil {
+			s.rollbackTo(savepoint)
+			return
+		}
+		if s.depth == 0 && s.tx == nil {
+			// The operation is complete and is not part of a transaction
+			s.record(desc, s.journal)
+			s.journal = nil
+		}
+	}()
+	return fn()
+}
+
+// rollbackTo reverts the changes in the journal after the savepoint, most recent first
+func (s *Schedule) rollbackTo(savepoint int) {
+	for i := len(s.journal) - 1; i >= savepoint; i-- {
+		s.journal[i].undo()
+	}
+	s.journal = s.journal[:savepoint]
+}
+
+// The following methods are the only ones that modify the task maps
+// Each change is recorded in the journal so that it can be reverted
+
+// putTransient adds or replaces a transient task
+func (s *Schedule) putTransient(t Task) {
+	old, existed := s.transientTasks[t.ID]
+	s.setTransient(t)
+	s.journal = append(s.journal, change{
+		undo: func() {
+			if existed {
+				s.setTransient(old)
+			} else {
+				s.deleteTransient(t.ID)
+			}
+		},
+		redo: func() { s.setTransient(t) },
+	})
+}
+
+// removeTransient removes a transient task
+func (s *Schedule) removeTransient(id string) {
+	old, existed := s.transientTasks[id]
+	if !existed {
+		return
+	}
+	s.deleteTransient(id)
+	s.journal = append(s.journal, change{
+		undo: func() { s.setTransient(old) },
+		redo: func() { s.deleteTransient(id) },
+	})
+}
+
+// putAnti adds or replaces an anti task
+func (s *Schedule) putAnti(a AntiTask) {
+	old, existed := s.antiTasks[a.ID]
+	s.setAnti(a)
+	s.journal = append(s.journal, change{
+		undo: func() {
+			if existed {
+				s.setAnti(old)
+			} else {
+				s.deleteAnti(a.ID)
+			}
+		},
+		redo: func() { s.setAnti(a) },
+	})
+}
+
+// removeAnti removes an anti task
+func (s *Schedule) removeAnti(id string) {
+	old, existed := s.antiTasks[id]
+	if !existed {
+		return
+	}
+	s.deleteAnti(id)
+	s.journal = append(s.journal, change{
+		undo: func() { s.setAnti(old) },
+		redo: func() { s.deleteAnti(id) },
+	})
+}
+
+// putRecurring adds or replaces a recurring task
+func (s *Schedule) putRecurring(r RecurringTask) {
+	old, existed := s.recurringTasks[r.ID]
+	s.setRecurring(r)
+	s.journal = append(s.journal, change{
+		undo: func() {
+			if existed {
+				s.setRecurring(old)
+			} else {
+				s.deleteRecurring(r.ID)
+			}
+		},
+		redo: func() { s.setRecurring(r) },
+	})
+}
+
+// removeRecurring removes a recurring task
+func (s *Schedule) removeRecurring(id string) {
+	old, existed := s.recurringTasks[id]
+	if !existed {
+		return
+	}
+	s.deleteRecurring(id)
+	s.journal = append(s.journal, change{
+		undo: func() { s.setRecurring(old) },
+		redo: func() { s.deleteRecurring(id) },
+	})
+}
+
+// putOverride adds or replaces an override
+func (s *Schedule) putOverride(o Override) {
+	old, existed := s.overrides[o.key()]
+	s.setOverride(o)
+	s.journal = append(s.journal, change{
+		undo: func() {
+			if existed {
+				s.setOverride(old)
+			} else {
+				s.deleteOverride(o.key())
+			}
+		},
+		redo: func() { s.setOverride(o) },
+	})
+}
+
+// putBlackout adds or replaces a blackout period
+func (s *Schedule) putBlackout(b Blackout) {
+	old, existed := s.blackouts[b.Name]
+	s.blackouts[b.Name] = b
+	s.journal = append(s.journal, change{
+		undo: func() {
+			if existed {
+				s.blackouts[b.Name] = old
+			} else {
+				delete(s.blackouts, b.Name)
+			}
+		},
+		redo: func() { s.blackouts[b.Name] = b },
+	})
+}
+
+// removeBlackout removes a blackout period
+func (s *Schedule) removeBlackout(name string) {
+	old, existed := s.blackouts[name]
+	if !existed {
+		return
+	}
+	delete(s.blackouts, name)
+	s.journal = append(s.journal, change{
+		undo: func() { s.blackouts[name] = old },
+		redo: func() { delete(s.blackouts, name) },
+	})
+}
+
+// removeOverride removes an override
+func (s *Schedule) removeOverride(key string) {
+	old, existed := s.overrides[key]
+	if !existed {
+		return
+	}
+	s.deleteOverride(key)
+	s.journal = append(s.journal, change{
+		undo: func() { s.setOverride(old) },
+		redo: func() { s.deleteOverride(key) },
+	})
+}
+
+//!--
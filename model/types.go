@@ -0,0 +1,166 @@
+// Package model provides functionality for creating and managing a schedule of tasks
+// types.go provides the registry of task types a schedule accepts
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	// Categories of task types
+	TRANSIENT = "Transient"
+	RECURRING = "Recurring"
+	ANTI      = "Anti"
+)
+
+// TaskType is a task type, the category of tasks it is for and other names it may be given by
+type TaskType struct {
+	Name     string
+	Category string
+	Aliases  []string `json:",omitempty"`
+}
+
+// TypeRegistry holds the task types a schedule accepts
+// Names and aliases are matched regardless of case, and a registry is never changed once made so it may be shared
+type TypeRegistry struct {
+	types  []TaskType
+	lookup map[string]TaskType // Keyed by the lower case names and aliases
+}
+
+// DefaultTypes returns a registry of the built in task types
+func DefaultTypes() *TypeRegistry {
+	reg, _ := NewTypeRegistry([]TaskType{
+		{Name: VISIT, Category: TRANSIENT},
+		{Name: SHOPPING, Category: TRANSIENT},
+		{Name: APPOINTMENT, Category: TRANSIENT},
+		{Name: CANCEL, Category: ANTI},
+		{Name: CLASS, Category: RECURRING},
+		{Name: STUDY, Category: RECURRING},
+		{Name: SLEEP, Category: RECURRING},
+		{Name: EXERCISE, Category: RECURRING},
+		{Name: WORK, Category: RECURRING},
+		{Name: MEAL, Category: RECURRING},
+	})
+	return reg
+}
+
+// NewTypeRegistry creates a registry of task types
+// Cancellation is added as an anti type if it is not declared, since the cancellations made for holidays and
+// iCalendar exceptions use it
+func NewTypeRegistry(types []TaskType) (*TypeRegistry, error) {
+	reg := &TypeRegistry{lookup: map[string]TaskType{}}
+	if !declares(types, CANCEL) {
+		types = append(types[:len(types):len(types)], TaskType{Name: CANCEL, Category: ANTI})
+	}
+	for _, t := range types {
+		t.Name = strings.TrimSpace(t.Name)
+		if t.Name == "" {
+			return nil, invalidf("task type name cannot be empty")
+		}
+		if strings.EqualFold(t.Name, BLACKOUT) {
+			return nil, invalidf("%q is reserved for blackout periods", t.Name)
+		}
+		if t.Category != TRANSIENT && t.Category != RECURRING && t.Category != ANTI {
+			return nil, invalidf("task type %q: category must be %q, %q or %q", t.Name, TRANSIENT, RECURRING, ANTI)
+		}
+		if strings.EqualFold(t.Name, CANCEL) && t.Category != ANTI {
+			return nil, invalidf("task type %q must be an anti type", t.Name)
+		}
+		t.Aliases = append([]string(nil), t.Aliases...)
+		for _, name := range append([]string{t.Name}, t.Aliases...) {
+			key := strings.ToLower(strings.TrimSpace(name))
+			if key == "" {
+				return nil, invalidf("task type %q has an empty alias", t.Name)
+			}
+			if other, ok := reg.lookup[key]; ok {
+				return nil, invalidf("%q names both %q and %q", name, other.Name, t.Name)
+			}
+			reg.lookup[key] = t
+		}
+		reg.types = append(reg.types, t)
+	}
+	return reg, nil
+}
+
+// declares checks if a list of task types declares a type by name
+func declares(types []TaskType, name string) bool {
+	for _, t := range types {
+		if strings.EqualFold(strings.TrimSpace(t.Name), name) {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadTypes reads a registry of task types from a json list of types with a Name, a Category and optional Aliases
+func ReadTypes(path string) (*TypeRegistry, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ReadTypes: error reading file %q: %v", path, err)
+	}
+	var types []TaskType
+	if err := json.Unmarshal(content, &types); err != nil {
+		return nil, fmt.Errorf("ReadTypes: %w", invalidf("error parsing json: %v", err))
+	}
+	reg, err := NewTypeRegistry(types)
+	if err != nil {
+		return nil, fmt.Errorf("ReadTypes: %w", err)
+	}
+	return reg, nil
+}
+
+// Lookup gets the task type with a name or alias
+func (reg *TypeRegistry) Lookup(name string) (TaskType, bool) {
+	t, ok := reg.lookup[strings.ToLower(strings.TrimSpace(name))]
+	return t, ok
+}
+
+// Types gets the task types of a category in the order they were declared, or every type if category is empty
+func (reg *TypeRegistry) Types(category string) []TaskType {
+	result := []TaskType{}
+	for _, t := range reg.types {
+		if category == "" || t.Category == category {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// resolve gets the name of the task type of a category with a name or alias and a bool to indicate if one was found
+func (reg *TypeRegistry) resolve(name, category string) (string, bool) {
+	t, ok := reg.Lookup(name)
+	if !ok || t.Category != category {
+		return name, false
+	}
+	return t.Name, true
+}
+
+// is checks if a name or alias is of a task type of a category
+func (reg *TypeRegistry) is(name, category string) bool {
+	_, ok := reg.resolve(name, category)
+	return ok
+}
+
+// Types gets the registry of task types the schedule accepts
+func (s *Schedule) Types() *TypeRegistry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.types
+}
+
+// SetTypes sets the registry of task types the schedule accepts
+// Tasks already in the schedule keep their types
+func (s *Schedule) SetTypes(reg *TypeRegistry) error {
+	if reg == nil {
+		return invalidf("SetTypes: no type registry given")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.types = reg
+	return nil
+}
+
+//!--
@@ -5,11 +5,13 @@ package model
 import (
 	"fmt"
 	"math"
+	"os"
+	"sync"
 	"time"
 )
 
 const (
-	// Transient types
+	// Built in transient types, see types.go
 	VISIT       = "Visit"
 	SHOPPING    = "Shopping"
 	APPOINTMENT = "Appointment"
@@ -22,9 +24,9 @@ const (
 	EXERCISE = "Exercise"
 	WORK     = "Work"
 	MEAL     = "Meal"
-	// Number of keys in transient/anti tasks
+	// Number of keys in transient/anti tasks, not counting the optional keys below
 	NUM_TASK_KEYS  = 5
-	NUM_RECUR_KEYS = 7
+	NUM_RECUR_KEYS = 6
 	// Key names for JSON marshaling
 	NAME_KEY       = "Name"
 	TYPE_KEY       = "Type"
@@ -34,23 +36,28 @@ const (
 	DURATION_KEY   = "Duration"
 	END_DATE_KEY   = "EndDate"
 	FREQUENCY_KEY  = "Frequency"
+	TIME_ZONE_KEY  = "TimeZone"
+	ID_KEY         = "ID"
+	// Optional keys of the end and recurrence rule of recurring tasks, along with END_DATE_KEY
+	COUNT_KEY      = "Count"
+	WEEKDAYS_KEY   = "Weekdays"
+	MONTH_DAY_KEY  = "MonthDay"
+	MONTH_WEEK_KEY = "MonthWeek"
+	OVERRIDES_KEY  = "Overrides"
+	ORIGINAL_KEY   = "Original"
+	// Optional key of the recurring task a range anti task cancels, along with END_DATE_KEY
+	SERIES_KEY = "Series"
+	// Optional key of the holiday an anti task was generated for
+	HOLIDAY_KEY = "Holiday"
+	// Keys of the end and policy of blackout periods
+	END_TIME_KEY = "EndTime"
+	POLICY_KEY   = "Policy"
+	// Granularity that start times and durations are rounded to unless a schedule sets another
+	DEFAULT_SNAP = 15 * time.Minute
+	// Longest a task may last
+	MAX_DURATION = 28 * 24 * time.Hour
 )
 
-// isTransientType checks if the type is a valid transient type
-func isTransientType(s string) bool {
-	return s == VISIT || s == SHOPPING || s == APPOINTMENT
-}
-
-// isAntiType checks if the type is a valid anti type
-func isAntiType(s string) bool {
-	return s == CANCEL
-}
-
-// isRecurringType checks if the type is a valid recurring type
-func isRecurringType(s string) bool {
-	return s == CLASS || s == STUDY || s == SLEEP || s == EXERCISE || s == WORK || s == MEAL
-}
-
 // intToDate converts integer date format to a time.Time struct
 func intToDate(date int) (time.Time, error) {
 	year := date / 10000
@@ -68,6 +75,135 @@ func dateToInt(date time.Time) int {
 	return (date.Year() * 10000) + (int(date.Month()) * 100) + date.Day()
 }
 
+// zoneName gets the name a task stores for a time zone, which is empty for UTC
+func zoneName(loc *time.Location) string {
+	if loc == time.UTC {
+		return ""
+	}
+	return loc.String()
+}
+
+// epochDay returns the number of days from the Unix epoch to the calendar date of a time in its own time zone
+func epochDay(t time.Time) int64 {
+	y, m, d := t.Date()
+	return floorDiv(time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Unix(), SECONDS_PER_DAY)
+}
+
+// wallClock returns a time with the same date and clock reading in UTC, which drops any daylight saving
+// time shift between two times in the same time zone
+func wallClock(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
+
+// zoneYear names a year of a time zone
+type zoneYear struct {
+	zone string
+	year int
+}
+
+// zoneChanges caches the days found by zoneChangesIn
+var zoneChanges sync.Map
+
+// eachZoneChange calls fn with each day, counted from the Unix epoch, from day from to day to on which the UTC
+// offset of a time zone changes, stopping when fn returns false
+func eachZoneChange(loc *time.Location, from, to int64, fn func(int64) bool) {
+	for year := epochDate(from).Year(); year <= epochDate(to).Year(); year++ {
+		for _, day := range zoneChangesIn(loc, year) {
+			if day >= from && day <= to && !fn(day) {
+				return
+			}
+		}
+	}
+}
+
+// zoneChangesIn returns the days of a year, counted from the Unix epoch, on which the UTC offset of a time zone
+// changes
+// The zone is looked up a week at a time, so changes less than a week apart that undo each other are missed
+func zoneChangesIn(loc *time.Location, year int) []int64 {
+	key := zoneYear{loc.String(), year}
+	if days, ok := zoneChanges.Load(key); ok {
+		return days.([]int64)
+	}
+	offset := func(day int64) int {
+		_, off := epochDate(day).In(loc).Zone()
+		return off
+	}
+	days := []int64{}
+	from, to := epochDay(time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)), epochDay(time.Date(year+1, 1, 1, 0, 0, 0, 0, time.UTC))
+	prev := offset(from)
+	for day := from; day < to; day += 7 {
+		next := minInt64(day+7, to)
+		off := offset(next)
+		if off == prev {
+			continue
+		}
+		// The offset at the start of lo is the old one and at the start of hi the new one
+		lo, hi := day, next
+		for hi-lo > 1 {
+			if mid := lo + (hi-lo)/2; offset(mid) == prev {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		days = append(days, lo)
+		prev = off
+	}
+	zoneChanges.Store(key, days)
+	return days
+}
+
+// floatHours converts a float time in hours, as written by older versions, to a duration rounded to the minute
+func floatHours(hours float64) time.Duration {
+	return time.Duration(math.Round(hours*60)) * time.Minute
+}
+
+// floorDiv divides a by b rounding towards negative infinity
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
+
+// ceilDiv divides a by b rounding towards positive infinity
+func ceilDiv(a, b int64) int64 {
+	return -floorDiv(-a, b)
+}
+
+// minInt64 returns the smaller of a and b
+func minInt64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// maxInt64 returns the larger of a and b
+func maxInt64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// extendedGCD returns the greatest common divisor g of a and b along with x and y such that a*x + b*y = g
+func extendedGCD(a, b int64) (int64, int64, int64) {
+	if b == 0 {
+		return a, 1, 0
+	}
+	g, x, y := extendedGCD(b, a%b)
+	return g, y, x - (a/b)*y
+}
+
+// lcm returns the least common multiple of a and b
+func lcm(a, b int64) int64 {
+	g, _, _ := extendedGCD(a, b)
+	return a / g * b
+}
+
 // dateIntToString converts a integer date format to a more readable string
 func dateIntToString(date int) string {
 	return fmt.Sprintf("%04d-%02d-%02d", date/10000, (date/100)%100, date%100)
@@ -96,19 +232,19 @@ func indexSubtasks(tasks []Task) {
 // taskKeysPresent checks if the necessary keys for a task are present in a generic string map
 func taskKeysPresent(m map[string]interface{}) error {
 	if _, ok := m[NAME_KEY]; !ok {
-		return fmt.Errorf("missing %q key", NAME_KEY)
+		return invalidf("missing %q key", NAME_KEY)
 	}
 	if _, ok := m[TYPE_KEY]; !ok {
-		return fmt.Errorf("missing %q key", TYPE_KEY)
+		return invalidf("missing %q key", TYPE_KEY)
 	}
 	if _, ok := m[DATE_KEY]; !ok {
-		return fmt.Errorf("missing %q key", DATE_KEY)
+		return invalidf("missing %q key", DATE_KEY)
 	}
 	if _, ok := m[START_TIME_KEY]; !ok {
-		return fmt.Errorf("missing %q key", START_TIME_KEY)
+		return invalidf("missing %q key", START_TIME_KEY)
 	}
 	if _, ok := m[DURATION_KEY]; !ok {
-		return fmt.Errorf("missing %q key", DURATION_KEY)
+		return invalidf("missing %q key", DURATION_KEY)
 	}
 	return nil
 }
@@ -118,86 +254,281 @@ func recurKeysPresent(m map[string]interface{}) error {
 	// Because of the Date being known as StartDate, we cannot reuse the logic of taskKeysPresent
 	// and must explicitly repeat it here
 	if _, ok := m[NAME_KEY]; !ok {
-		return fmt.Errorf("missing %q key", NAME_KEY)
+		return invalidf("missing %q key", NAME_KEY)
 	}
 	if _, ok := m[TYPE_KEY]; !ok {
-		return fmt.Errorf("missing %q key", TYPE_KEY)
+		return invalidf("missing %q key", TYPE_KEY)
 	}
 	if _, ok := m[START_DATE_KEY]; !ok {
-		return fmt.Errorf("missing %q key", START_DATE_KEY)
+		return invalidf("missing %q key", START_DATE_KEY)
 	}
 	if _, ok := m[START_TIME_KEY]; !ok {
-		return fmt.Errorf("missing %q key", START_TIME_KEY)
+		return invalidf("missing %q key", START_TIME_KEY)
 	}
 	if _, ok := m[DURATION_KEY]; !ok {
-		return fmt.Errorf("missing %q key", DURATION_KEY)
-	}
-	if _, ok := m[END_DATE_KEY]; !ok {
-		return fmt.Errorf("missing %q key", END_DATE_KEY)
+		return invalidf("missing %q key", DURATION_KEY)
 	}
 	if _, ok := m[FREQUENCY_KEY]; !ok {
-		return fmt.Errorf("missing %q key", FREQUENCY_KEY)
+		return invalidf("missing %q key", FREQUENCY_KEY)
 	}
 	return nil
 }
 
 // mapToTaskInfo extracts task information from a generic map
-func mapToTaskInfo(m map[string]interface{}) (string, string, int, float32, float32, error) {
+func mapToTaskInfo(m map[string]interface{}) (string, string, int, time.Duration, time.Duration, error) {
 	name, ok := m[NAME_KEY].(string)
 	if !ok {
-		return "", "", 0, 0, 0, fmt.Errorf("bad name value")
+		return "", "", 0, 0, 0, invalidf("bad name value")
 	}
 	taskType, ok := m[TYPE_KEY].(string)
 	if !ok {
-		return "", "", 0, 0, 0, fmt.Errorf("bad type value")
+		return "", "", 0, 0, 0, invalidf("bad type value")
 	}
 	date, ok := m[DATE_KEY].(float64)
 	if !ok {
-		return "", "", 0, 0, 0, fmt.Errorf("bad date value")
+		return "", "", 0, 0, 0, invalidf("bad date value")
 	}
-	startTime, ok := m[START_TIME_KEY].(float64)
-	if !ok {
-		return "", "", 0, 0, 0, fmt.Errorf("bad start time value")
+	startTime, err := mapToClock(m, START_TIME_KEY, parseClock)
+	if err != nil {
+		return "", "", 0, 0, 0, invalidf("bad start time value")
 	}
-	duration, ok := m[DURATION_KEY].(float64)
-	if !ok {
-		return "", "", 0, 0, 0, fmt.Errorf("bad duration value")
+	duration, err := mapToClock(m, DURATION_KEY, parseHours)
+	if err != nil {
+		return "", "", 0, 0, 0, invalidf("bad duration value")
 	}
-	return name, taskType, int(date), float32(startTime), float32(duration), nil
+	return name, taskType, int(date), startTime, duration, nil
 }
 
 // mapToRecurInfo extracts recurring task information from a generic map
-func mapToRecurInfo(m map[string]interface{}) (string, string, int, float32, float32, int, int, error) {
+func mapToRecurInfo(m map[string]interface{}) (string, string, int, time.Duration, time.Duration, int, int, error) {
 	// Again we cannot reuse mapToTaskInfo due to the unfortunate discrepency in Date versus StartDate
 	name, ok := m[NAME_KEY].(string)
 	if !ok {
-		return "", "", 0, 0, 0, 0, 0, fmt.Errorf("bad name value")
+		return "", "", 0, 0, 0, 0, 0, invalidf("bad name value")
 	}
 	taskType, ok := m[TYPE_KEY].(string)
 	if !ok {
-		return "", "", 0, 0, 0, 0, 0, fmt.Errorf("bad type value")
+		return "", "", 0, 0, 0, 0, 0, invalidf("bad type value")
 	}
 	date, ok := m[START_DATE_KEY].(float64)
 	if !ok {
-		return "", "", 0, 0, 0, 0, 0, fmt.Errorf("bad date value")
+		return "", "", 0, 0, 0, 0, 0, invalidf("bad date value")
 	}
-	startTime, ok := m[START_TIME_KEY].(float64)
+	startTime, err := mapToClock(m, START_TIME_KEY, parseClock)
+	if err != nil {
+		return "", "", 0, 0, 0, 0, 0, invalidf("bad start time value")
+	}
+	duration, err := mapToClock(m, DURATION_KEY, parseHours)
+	if err != nil {
+		return "", "", 0, 0, 0, 0, 0, invalidf("bad duration value")
+	}
+	// Series ending after a count or never have no end date
+	endDate, err := mapToOptionalInt(m, END_DATE_KEY)
+	if err != nil {
+		return "", "", 0, 0, 0, 0, 0, invalidf("bad end date value")
+	}
+	frequency, ok := m[FREQUENCY_KEY].(float64)
 	if !ok {
-		return "", "", 0, 0, 0, 0, 0, fmt.Errorf("bad start time value")
+		return "", "", 0, 0, 0, 0, 0, invalidf("bad frequency value")
 	}
-	duration, ok := m[DURATION_KEY].(float64)
+	return name, taskType, int(date), startTime, duration, endDate, int(frequency), nil
+}
+
+// mapToClock extracts a start time or duration from a generic map
+// Times are written as strings such as "17:30", or as float hours by older versions of the scheduler
+func mapToClock(m map[string]interface{}, key string, parse func(string) (time.Duration, error)) (time.Duration, error) {
+	switch v := m[key].(type) {
+	case float64:
+		return floatHours(v), nil
+	case string:
+		return parse(v)
+	}
+	return 0, fmt.Errorf("missing %s", key)
+}
+
+// ruleKeys are the optional keys of the end and recurrence rule of a recurring task
+var ruleKeys = []string{END_DATE_KEY, COUNT_KEY, WEEKDAYS_KEY, MONTH_DAY_KEY, MONTH_WEEK_KEY, OVERRIDES_KEY}
+
+// mapToOptionalInt extracts an optional integer from a generic map, which is 0 if the key is missing
+func mapToOptionalInt(m map[string]interface{}, key string) (int, error) {
+	v, ok := m[key]
+	if !ok {
+		return 0, nil
+	}
+	n, ok := v.(float64)
+	if !ok {
+		return 0, fmt.Errorf("bad %s", key)
+	}
+	return int(n), nil
+}
+
+// mapToRule extracts the optional weekly or monthly recurrence rule of a recurring task from a generic map
+func mapToRule(m map[string]interface{}) (Weekdays, int, int, error) {
+	var weekdays Weekdays
+	if v, ok := m[WEEKDAYS_KEY]; ok {
+		s, ok := v.(string)
+		if !ok {
+			return 0, 0, 0, invalidf("bad weekdays value")
+		}
+		if err := weekdays.UnmarshalText([]byte(s)); err != nil {
+			return 0, 0, 0, invalidf("bad weekdays value: %v", err)
+		}
+	}
+	monthDay, err := mapToOptionalInt(m, MONTH_DAY_KEY)
+	if err != nil {
+		return 0, 0, 0, invalidf("bad month day value")
+	}
+	monthWeek, err := mapToOptionalInt(m, MONTH_WEEK_KEY)
+	if err != nil {
+		return 0, 0, 0, invalidf("bad month week value")
+	}
+	return weekdays, monthDay, monthWeek, nil
+}
+
+// mapToOverrides extracts the optional overrides of a recurring task from a generic map
+func mapToOverrides(m map[string]interface{}, r Task) ([]Override, error) {
+	v, ok := m[OVERRIDES_KEY]
+	if !ok {
+		return nil, nil
+	}
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, invalidf("bad overrides value")
+	}
+	result := []Override{}
+	for _, i := range list {
+		o, ok := i.(map[string]interface{})
+		if !ok {
+			return nil, invalidf("bad override: expected a json object")
+		}
+		original, ok := o[ORIGINAL_KEY].(float64)
+		if !ok {
+			return nil, invalidf("bad override original date value")
+		}
+		date, ok := o[DATE_KEY].(float64)
+		if !ok {
+			return nil, invalidf("bad override date value")
+		}
+		startTime, err := mapToClock(o, START_TIME_KEY, parseClock)
+		if err != nil {
+			return nil, invalidf("bad override start time value")
+		}
+		duration, err := mapToClock(o, DURATION_KEY, parseHours)
+		if err != nil {
+			return nil, invalidf("bad override duration value")
+		}
+		zone, err := mapToTimeZone(o)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, Override{
+			Task:     Task{ID: r.ID, Name: r.Name, Type: r.Type, Date: int(date), StartTime: startTime, Duration: duration, TimeZone: zone},
+			Original: int(original),
+		})
+	}
+	return result, nil
+}
+
+// mapToAntiRule extracts the optional range or recurrence of an anti task from a generic map
+func mapToAntiRule(m map[string]interface{}, task Task) (AntiTask, error) {
+	a := AntiTask{Task: task}
+	endDate, err := mapToOptionalInt(m, END_DATE_KEY)
+	if err != nil {
+		return a, invalidf("bad end date value")
+	}
+	a.EndDate = endDate
+	if a.Frequency, err = mapToOptionalInt(m, FREQUENCY_KEY); err != nil || (a.Frequency != 0 && endDate == 0) {
+		return a, invalidf("bad frequency value")
+	}
+	if v, ok := m[HOLIDAY_KEY]; ok {
+		if a.Holiday, ok = v.(string); !ok {
+			return a, invalidf("bad holiday value")
+		}
+	}
+	if v, ok := m[SERIES_KEY]; ok {
+		if a.Series, ok = v.(string); !ok || endDate == 0 {
+			return a, invalidf("bad series value")
+		}
+	}
+	return a, nil
+}
+
+// mapToBlackout extracts a blackout period from a generic map
+func mapToBlackout(m map[string]interface{}) (Blackout, error) {
+	var b Blackout
+	var ok bool
+	if b.Name, ok = m[NAME_KEY].(string); !ok {
+		return b, invalidf("bad name value")
+	}
+	date, ok := m[DATE_KEY].(float64)
 	if !ok {
-		return "", "", 0, 0, 0, 0, 0, fmt.Errorf("bad duration value")
+		return b, invalidf("bad date value")
 	}
 	endDate, ok := m[END_DATE_KEY].(float64)
 	if !ok {
-		return "", "", 0, 0, 0, 0, 0, fmt.Errorf("bad end date value")
+		return b, invalidf("bad end date value")
 	}
-	frequency, ok := m[FREQUENCY_KEY].(float64)
+	b.Date, b.EndDate = int(date), int(endDate)
+	var err error
+	if b.StartTime, err = mapToClock(m, START_TIME_KEY, parseClock); err != nil {
+		return b, invalidf("bad start time value")
+	}
+	if b.EndTime, err = mapToClock(m, END_TIME_KEY, parseClock); err != nil {
+		return b, invalidf("bad end time value")
+	}
+	if b.TimeZone, err = mapToTimeZone(m); err != nil {
+		return b, err
+	}
+	if v, ok := m[POLICY_KEY]; ok {
+		if b.Policy, ok = v.(string); !ok {
+			return b, invalidf("bad policy value")
+		}
+	}
+	return b, nil
+}
+
+// mapToID extracts the optional ID of a task from a generic map
+func mapToID(m map[string]interface{}) (string, error) {
+	v, ok := m[ID_KEY]
 	if !ok {
-		return "", "", 0, 0, 0, 0, 0, fmt.Errorf("bad frequency value")
+		return "", nil
+	}
+	id, ok := v.(string)
+	if !ok || id == "" {
+		return "", invalidf("bad ID value")
 	}
-	return name, taskType, int(date), float32(startTime), float32(duration), int(endDate), int(frequency), nil
+	return id, nil
+}
+
+// mapToTimeZone extracts the optional time zone of a task from a generic map
+func mapToTimeZone(m map[string]interface{}) (string, error) {
+	v, ok := m[TIME_ZONE_KEY]
+	if !ok {
+		return "", nil
+	}
+	zone, ok := v.(string)
+	if !ok {
+		return "", invalidf("bad time zone value")
+	}
+	return zone, nil
+}
+
+// writeFile creates the file at path and writes content to it
+func writeFile(path string, content []byte) error {
+	outFile, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating file: %v", err)
+	}
+	_, err = outFile.Write(content)
+	if err != nil {
+		outFile.Close()
+		return fmt.Errorf("error writing to file: %v", err)
+	}
+	err = outFile.Close()
+	if err != nil {
+		return fmt.Errorf("error closing file: %v", err)
+	}
+	return nil
 }
 
 //!--
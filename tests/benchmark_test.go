@@ -0,0 +1,89 @@
+// Package tests contains unit tests
+// benchmark_test.go contains benchmarks of loading and conflict checking large schedules
+// Run with go test -bench . ./tests
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hlin91/CS3560_Scheduler_Backup/model"
+)
+
+const (
+	// Number of tasks in the large schedules
+	NUM_BENCH_TASKS = 100000
+	// Number of one hour tasks on each day of the large schedules
+	BENCH_TASKS_PER_DAY = 20
+)
+
+// writeLargeSchedule writes a json task list of n transient tasks that do not conflict
+func writeLargeSchedule(b *testing.B, n int) string {
+	b.Helper()
+	start := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	tasks := []map[string]interface{}{}
+	for i := 0; i < n; i++ {
+		date := start.AddDate(0, 0, i/BENCH_TASKS_PER_DAY)
+		tasks = append(tasks, map[string]interface{}{
+			"Name":      fmt.Sprintf("Task %d", i),
+			"Type":      "Visit",
+			"Date":      date.Year()*10000 + int(date.Month())*100 + date.Day(),
+			"StartTime": i % BENCH_TASKS_PER_DAY,
+			"Duration":  1,
+		})
+	}
+	content, err := json.Marshal(tasks)
+	if err != nil {
+		b.Fatalf("Failed to marshal tasks: %v", err)
+	}
+	path := filepath.Join(b.TempDir(), "large.json")
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		b.Fatalf("Failed to write tasks: %v", err)
+	}
+	return path
+}
+
+func BenchmarkLoadFile100k(b *testing.B) {
+	path := writeLargeSchedule(b, NUM_BENCH_TASKS)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s := model.NewSchedule()
+		if err := s.LoadFile(path); err != nil {
+			b.Fatalf("Failed to load tasks: %v", err)
+		}
+	}
+}
+
+func BenchmarkConflictCheck100k(b *testing.B) {
+	s := model.NewSchedule()
+	if err := s.LoadFile(writeLargeSchedule(b, NUM_BENCH_TASKS)); err != nil {
+		b.Fatalf("Failed to load tasks: %v", err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// Conflicts with the tasks at 05:00 and 06:00 on that day and is rolled back
+		if err := s.AddTransientTask("Clash", "Visit", 20010910, 5*time.Hour+30*time.Minute, time.Hour); err == nil {
+			b.Fatalf("Conflicting task was added")
+		}
+	}
+}
+
+func BenchmarkAddTransientTask100k(b *testing.B) {
+	s := model.NewSchedule()
+	if err := s.LoadFile(writeLargeSchedule(b, NUM_BENCH_TASKS)); err != nil {
+		b.Fatalf("Failed to load tasks: %v", err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// The hours after the last task of each day are free
+		date := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, i)
+		name := fmt.Sprintf("Evening %d", i)
+		if err := s.AddTransientTask(name, "Visit", date.Year()*10000+int(date.Month())*100+date.Day(), 21*time.Hour, time.Hour); err != nil {
+			b.Fatalf("Failed to add %q: %v", name, err)
+		}
+	}
+}
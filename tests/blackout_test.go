@@ -0,0 +1,133 @@
+// Package tests contains unit tests
+// blackout_test.go contains tests for blackout periods in which nothing may be scheduled
+package tests
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hlin91/CS3560_Scheduler_Backup/model"
+)
+
+// closure is a campus closure from the start of April 21 2020 to noon on April 22
+var closure = model.Blackout{Name: "Closure", Date: 20200421, EndDate: 20200422, EndTime: 12 * time.Hour}
+
+func TestBlackoutReject(t *testing.T) {
+	s := scheduleWith(t, tuesdayClass, dailyGym)
+	if err := s.AddBlackout(closure); err == nil {
+		t.Errorf("Added blackout period over the subtasks of recurring tasks")
+	}
+	s = model.NewSchedule()
+	if err := s.AddBlackout(closure); err != nil {
+		t.Fatalf("Failed to add blackout period: %v", err)
+	}
+	if b, _ := s.Blackout("Closure"); b.Policy != model.BLACKOUT_REJECT || b.TimeZone != "" {
+		t.Errorf("Blackout period added with policy %q and time zone %q", b.Policy, b.TimeZone)
+	}
+	if err := s.AddTransientTask("Lunch", model.VISIT, 20200422, 11*time.Hour, time.Hour); !errors.Is(err, model.ErrBlackout) {
+		t.Errorf("Adding a task in a blackout period returned %v, want ErrBlackout", err)
+	}
+	if err := s.AddTransientTask("Lunch", model.VISIT, 20200422, 12*time.Hour, time.Hour); err != nil {
+		t.Errorf("Failed to add task after a blackout period ends: %v", err)
+	}
+	if err := s.AddRecurringTask("Gym", model.EXERCISE, 20200415, 7*time.Hour, time.Hour, 20200430, 1); !errors.Is(err, model.ErrBlackout) {
+		t.Errorf("Adding a recurring task with a subtask in a blackout period returned %v, want ErrBlackout", err)
+	}
+	if err := s.AddRecurringTask("Gym", model.EXERCISE, 20200415, 13*time.Hour, time.Hour, 20200430, 7); err != nil {
+		t.Errorf("Failed to add recurring task that steps over a blackout period: %v", err)
+	}
+	if err := s.DeleteBlackout("Closure"); err != nil {
+		t.Fatalf("Failed to delete blackout period: %v", err)
+	}
+	if _, err := s.Undo(); err != nil {
+		t.Fatalf("Failed to undo: %v", err)
+	}
+	if _, ok := s.Blackout("Closure"); !ok {
+		t.Errorf("Undo did not bring back the blackout period")
+	}
+}
+
+func TestBlackoutSkip(t *testing.T) {
+	s := scheduleWith(t, tuesdayClass, dailyGym)
+	skip := closure
+	skip.Policy = model.BLACKOUT_SKIP
+	if err := s.AddBlackout(skip); err != nil {
+		t.Fatalf("Failed to add blackout period: %v", err)
+	}
+	if got := tasksOn(t, s, 20200421); len(got) != 0 {
+		t.Errorf("Got %v in a blackout period, want nothing", got)
+	}
+	if got, want := tasksOn(t, s, 20200423), map[string]time.Duration{"Gym": 7 * time.Hour}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Got %v after the blackout period, want %v", got, want)
+	}
+	if err := s.AddTransientTask("Movers", model.APPOINTMENT, 20200421, 19*time.Hour, time.Hour); err == nil {
+		t.Errorf("Added task in a blackout period that skips subtasks")
+	}
+	if err := s.AddRecurringTask("Choir", model.CLASS, 20200413, 17*time.Hour, time.Hour, 20200430, 1); err != nil {
+		t.Errorf("Failed to add recurring task with a subtask in a blackout period that skips subtasks: %v", err)
+	}
+	if err := s.AddOverride(model.Override{Task: model.Task{Name: "CS3560-Tu", Type: model.CLASS, Date: 20200421, StartTime: 8 * time.Hour, Duration: time.Hour}, Original: 20200414}); !errors.Is(err, model.ErrBlackout) {
+		t.Errorf("Overriding a skipped subtask into the blackout period returned %v, want ErrBlackout", err)
+	}
+	if err := s.DeleteBlackout("Closure"); err != nil {
+		t.Fatalf("Failed to delete blackout period: %v", err)
+	}
+	if got := tasksOn(t, s, 20200421); len(got) != 3 {
+		t.Errorf("Got %v after deleting the blackout period, want 3 subtasks", got)
+	}
+}
+
+func TestBadBlackouts(t *testing.T) {
+	s := model.NewSchedule()
+	if err := s.AddTransientTask("Dentist", model.APPOINTMENT, 20200421, 9*time.Hour, time.Hour); err != nil {
+		t.Fatalf("Failed to add transient task: %v", err)
+	}
+	bad := map[string]model.Blackout{
+		"over a task":      closure,
+		"no name":          {Date: 20200501, EndDate: 20200502},
+		"end before start": {Name: "Backwards", Date: 20200502, EndDate: 20200501},
+		"bad date":         {Name: "Bad", Date: 20200231, EndDate: 20200301},
+		"bad policy":       {Name: "Maybe", Date: 20200501, EndDate: 20200502, Policy: "Maybe"},
+		"bad zone":         {Name: "Nowhere", Date: 20200501, EndDate: 20200502, TimeZone: "Mars/Olympus"},
+	}
+	for name, b := range bad {
+		if err := s.AddBlackout(b); err == nil {
+			t.Errorf("%s: added bad blackout period", name)
+		}
+	}
+}
+
+func TestBlackoutLoadFile(t *testing.T) {
+	s := scheduleWith(t, tuesdayClass, dailyGym)
+	skip := closure
+	skip.Policy = model.BLACKOUT_SKIP
+	if err := s.AddBlackout(skip); err != nil {
+		t.Fatalf("Failed to add blackout period: %v", err)
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "blackout.json")
+	if err := s.WriteTasks(path); err != nil {
+		t.Fatalf("Failed to write schedule: %v", err)
+	}
+	loaded := model.NewSchedule()
+	if err := loaded.LoadFile(path); err != nil {
+		t.Fatalf("Failed to load schedule: %v", err)
+	}
+	if got, want := loaded.Blackouts(), s.Blackouts(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Blackout periods loaded as %v, want %v", got, want)
+	}
+	// Files are held to the blackout periods they contain
+	bad := filepath.Join(dir, "bad.json")
+	content := `[{"Name": "Closure", "Type": "Blackout", "Date": 20200421, "StartTime": "0", "EndDate": 20200422, "EndTime": "0", "Policy": "Reject"},
+		{"Name": "Lunch", "Type": "Visit", "Date": 20200421, "StartTime": 12, "Duration": 1}]`
+	if err := os.WriteFile(bad, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write bad.json: %v", err)
+	}
+	if err := model.NewSchedule().LoadFile(bad); !errors.Is(err, model.ErrBlackout) {
+		t.Errorf("Loading a task in a blackout period returned %v, want ErrBlackout", err)
+	}
+}
@@ -0,0 +1,128 @@
+// Package tests contains unit tests
+// cancel_range_test.go contains tests for anti tasks that cancel a range of dates
+package tests
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hlin91/CS3560_Scheduler_Backup/model"
+)
+
+func TestCancelRange(t *testing.T) {
+	s := scheduleWith(t, tuesdayClass, dailyGym)
+	springBreak := model.AntiTask{Task: model.Task{Name: "Spring Break", Type: model.CANCEL, Date: 20200420}, EndDate: 20200426, Series: "CS3560-Tu"}
+	if err := s.AddAnti(springBreak); err != nil {
+		t.Fatalf("Failed to add range anti task: %v", err)
+	}
+	if got, want := tasksOn(t, s, 20200421), map[string]time.Duration{"Gym": 7 * time.Hour}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Got %v during the range, want %v", got, want)
+	}
+	if err := s.AddTransientTask("Trip", model.VISIT, 20200421, 18*time.Hour, 3*time.Hour); err != nil {
+		t.Errorf("Failed to add task in place of a cancelled subtask: %v", err)
+	}
+	if err := s.DeleteTask("Spring Break"); err == nil {
+		t.Errorf("Deleted range anti task bringing back a subtask that conflicts")
+	}
+	// A range without a recurring task cancels the subtasks of every recurring task
+	closed := model.AntiTask{Task: model.Task{Name: "Gym Closed", Type: model.CANCEL, Date: 20200427}, EndDate: 20200428}
+	if err := s.AddAnti(closed); err != nil {
+		t.Fatalf("Failed to add range anti task: %v", err)
+	}
+	if got := tasksOn(t, s, 20200428); len(got) != 0 {
+		t.Errorf("Got %v during a range of every recurring task, want nothing", got)
+	}
+	if err := s.DeleteTask("Gym Closed"); err != nil {
+		t.Errorf("Failed to delete range anti task: %v", err)
+	}
+	// The range follows its recurring task when it is renamed and goes with it when it is deleted
+	if err := s.EditRecurringTask("CS3560-Tu", "CS3560", model.CLASS, 20200407, 19*time.Hour, 75*time.Minute, 20200505, 7); err != nil {
+		t.Fatalf("Failed to rename recurring task: %v", err)
+	}
+	if a, _ := s.AntiTask("Spring Break"); a.Series != recurringID(t, s, "CS3560") {
+		t.Errorf("Range anti task cancels %q after renaming its recurring task", a.Series)
+	}
+	if err := s.DeleteTask("CS3560"); err != nil {
+		t.Fatalf("Failed to delete recurring task: %v", err)
+	}
+	if _, ok := s.AntiTask("Spring Break"); ok {
+		t.Errorf("Range anti task left after deleting its recurring task")
+	}
+}
+
+func TestEditCancelRangeInTransaction(t *testing.T) {
+	s := scheduleWith(t, tuesdayClass, dailyGym)
+	if err := s.AddAnti(model.AntiTask{Task: model.Task{Name: "Break", Type: model.CANCEL, Date: 20200420}, EndDate: 20200426, Series: "CS3560-Tu"}); err != nil {
+		t.Fatalf("Failed to add range anti task: %v", err)
+	}
+	err := s.Atomically(func(tx *model.Tx) error {
+		return tx.EditAntiTask("Break", "Spring Break", 20200420, 0, 0)
+	})
+	if err != nil {
+		t.Fatalf("Failed to edit range anti task in a transaction: %v", err)
+	}
+	a, ok := s.AntiTask("Spring Break")
+	if !ok || a.EndDate != 20200426 || a.Series != recurringID(t, s, "CS3560-Tu") {
+		t.Errorf("Range anti task edited in a transaction became %+v", a)
+	}
+	if got, want := tasksOn(t, s, 20200421), map[string]time.Duration{"Gym": 7 * time.Hour}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Got %v during the edited range, want %v", got, want)
+	}
+}
+
+func TestBadCancelRanges(t *testing.T) {
+	s := scheduleWith(t, tuesdayClass, dailyGym)
+	bad := map[string]model.AntiTask{
+		"end before start": {Task: model.Task{Name: "Backwards", Type: model.CANCEL, Date: 20200426}, EndDate: 20200420},
+		"bad end date":     {Task: model.Task{Name: "Bad End", Type: model.CANCEL, Date: 20200420}, EndDate: 20200231},
+		"unknown task":     {Task: model.Task{Name: "Unknown", Type: model.CANCEL, Date: 20200420}, EndDate: 20200426, Series: "Choir"},
+	}
+	for name, a := range bad {
+		if err := s.AddAnti(a); err == nil {
+			t.Errorf("%s: added bad range anti task", name)
+		}
+	}
+}
+
+func TestCancelRangeRoundTrip(t *testing.T) {
+	s := scheduleWith(t, tuesdayClass, dailyGym)
+	for _, a := range []model.AntiTask{
+		{Task: model.Task{Name: "Spring Break", Type: model.CANCEL, Date: 20200420}, EndDate: 20200426, Series: "CS3560-Tu"},
+		{Task: model.Task{Name: "Gym Closed", Type: model.CANCEL, Date: 20200427}, EndDate: 20200428},
+		{Task: model.Task{Name: "Skip", Type: model.CANCEL, Date: 20200505, StartTime: 19 * time.Hour, Duration: 75 * time.Minute}},
+	} {
+		if err := s.AddAnti(a); err != nil {
+			t.Fatalf("Failed to add anti task %q: %v", a.Name, err)
+		}
+	}
+	dir := t.TempDir()
+	formats := map[string]func(*model.Schedule, string) error{
+		"json": (*model.Schedule).WriteTasks,
+		"csv":  (*model.Schedule).WriteCSV,
+	}
+	for ext, write := range formats {
+		path := filepath.Join(dir, "ranges."+ext)
+		if err := write(s, path); err != nil {
+			t.Fatalf("Failed to write %s: %v", ext, err)
+		}
+		loaded := model.NewSchedule()
+		var err error
+		if ext == "json" {
+			err = loaded.LoadFile(path)
+		} else {
+			err = loaded.LoadCSV(path)
+		}
+		if err != nil {
+			t.Fatalf("Failed to load %s: %v", ext, err)
+		}
+		got, want := loaded.AntiTasks(), s.AntiTasks()
+		if ext == "csv" {
+			got, want = portableAntiTasks(loaded, got), portableAntiTasks(s, want)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: anti tasks loaded as %v, want %v", ext, got, want)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+// Package tests contains unit tests
+// commands_test.go contains tests for the non-interactive subcommands
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hlin91/CS3560_Scheduler_Backup/controller"
+	"github.com/hlin91/CS3560_Scheduler_Backup/model"
+)
+
+func TestCommands(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "sched.json")
+	run := func(want int, args ...string) {
+		t.Helper()
+		if got := controller.RunCommand(args); got != want {
+			t.Errorf("%v: got exit code %d, want %d", args, got, want)
+		}
+	}
+	run(controller.EXIT_OK, "import", "--from", "../data/Set1.json", "--file", file)
+	run(controller.EXIT_OK, "add", "transient", "--name", "Dinner", "--type", "Visit", "--date", "2020-04-28",
+		"--start", "21:00", "--duration", "1", "--file", file)
+	run(controller.EXIT_FAILURE, "add", "transient", "--name", "Movie", "--type", "Visit", "--date", "2020-04-30",
+		"--start", "18:30", "--duration", "2", "--file", file)
+	run(controller.EXIT_USAGE, "add", "transient", "--name", "Movie", "--date", "tomorrow", "--file", file)
+	run(controller.EXIT_OK, "add", "anti", "--name", "Spring Break", "--date", "2020-05-04", "--end", "2020-05-10",
+		"--series", "CS3560-Tu", "--file", file)
+	run(controller.EXIT_OK, "add", "anti", "--name", "Skip Labs", "--date", "2020-04-14", "--start", "19:00", "--duration", "1:15",
+		"--end", "2020-04-28", "--frequency", "2", "--file", file)
+	run(controller.EXIT_USAGE, "frobnicate")
+	run(controller.EXIT_FILE, "list", "--file", filepath.Join(t.TempDir(), "missing.json"))
+	run(controller.EXIT_OK, "delete", "--name", "Intern Interview", "--file", file)
+	run(controller.EXIT_FAILURE, "delete", "--name", "Intern Interview", "--file", file)
+	run(controller.EXIT_OK, "override", "--name", "CS3560-Th", "--on", "2020-04-30", "--start", "17:00", "--file", file)
+	run(controller.EXIT_USAGE, "override", "--name", "CS3560-Th", "--on", "soon", "--file", file)
+	run(controller.EXIT_OK, "split", "--name", "CS3560-Th", "--from", "2020-05-01", "--new-name", "CS3560-Th (morning)",
+		"--start", "8:00", "--file", file)
+	run(controller.EXIT_USAGE, "split", "--name", "CS3560-Th", "--file", file)
+	run(controller.EXIT_FAILURE, "split", "--name", "CS3560-Th (morning)", "--from", "2021-01-01", "--file", file)
+	holidays := filepath.Join(t.TempDir(), "holidays.json")
+	if err := os.WriteFile(holidays, []byte(`[{"Name": "Spring Holiday", "Date": 20200421}]`), 0644); err != nil {
+		t.Fatalf("Failed to write holidays: %v", err)
+	}
+	run(controller.EXIT_OK, "holidays", "--from", holidays, "--types", "Class", "--file", file)
+	run(controller.EXIT_USAGE, "holidays", "--from", holidays, "--file", file)
+	run(controller.EXIT_OK, "types")
+	os.Setenv(controller.TYPES_ENV, filepath.Join(t.TempDir(), "missing.json"))
+	run(controller.EXIT_FILE, "types")
+	run(controller.EXIT_FILE, "list", "--file", file)
+	os.Unsetenv(controller.TYPES_ENV)
+	run(controller.EXIT_FAILURE, "blackout", "--name", "Closure", "--from", "2020-04-28", "--to", "2020-04-28", "--file", file)
+	run(controller.EXIT_OK, "blackout", "--name", "Closure", "--from", "2020-05-12", "--to", "2020-05-13", "--skip", "--file", file)
+	run(controller.EXIT_USAGE, "blackout", "--name", "Storm", "--from", "2020-05-20", "--to", "later", "--file", file)
+	s := model.NewSchedule()
+	if err := s.LoadFile(file); err != nil {
+		t.Fatalf("Failed to reload schedule: %v", err)
+	}
+	if b, ok := s.Blackout("Closure"); !ok || b.Policy != model.BLACKOUT_SKIP {
+		t.Errorf("Blackout period was not saved to the schedule file: %v", b)
+	}
+	if _, ok := s.TransientTask("Dinner"); !ok {
+		t.Errorf("Added task was not saved to the schedule file")
+	}
+	if _, ok := s.TransientTask("Intern Interview"); ok {
+		t.Errorf("Deleted task was saved to the schedule file")
+	}
+	if r, ok := s.RecurringTask("CS3560-Th (morning)"); !ok || r.Date != 20200507 || r.StartTime != 8*time.Hour {
+		t.Errorf("Split task was not saved to the schedule file: %v", r)
+	}
+	class, _ := s.RecurringTask("CS3560-Tu")
+	if a, ok := s.AntiTask("Spring Break"); !ok || a.EndDate != 20200510 || a.Series != class.ID {
+		t.Errorf("Range anti task was not saved to the schedule file: %v", a)
+	}
+	if a, ok := s.AntiTask("Skip Labs"); !ok || a.EndDate != 20200428 || a.Frequency != 2 {
+		t.Errorf("Recurring anti task was not saved to the schedule file: %v", a)
+	}
+	if a, ok := s.AntiTask("Spring Holiday: CS3560-Tu (2020-04-21)"); !ok || a.Holiday != "Spring Holiday" {
+		t.Errorf("Holiday cancellation was not saved to the schedule file: %v", a)
+	}
+	if o, ok := s.Override("CS3560-Th", 20200430); !ok || o.Date != 20200430 || o.Duration != 75*time.Minute {
+		t.Errorf("Override was not saved to the schedule file: %v", o)
+	}
+}
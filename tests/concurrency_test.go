@@ -0,0 +1,138 @@
+// Package tests contains unit tests
+// concurrency_test.go contains tests for using a schedule from multiple goroutines
+// Run with go test -race to check for data races
+package tests
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hlin91/CS3560_Scheduler_Backup/model"
+)
+
+func TestConcurrentAddsAndQueries(t *testing.T) {
+	const writers, tasksEach, readers = 8, 20, 4
+	s := model.NewSchedule()
+	if err := s.AddRecurringTask("Standup", "Work", 20200501, 8*time.Hour, 30*time.Minute, 20200531, 1); err != nil {
+		t.Fatalf("Failed to add recurring task: %v", err)
+	}
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < tasksEach; i++ {
+				// Every writer has its own two hour slot on each day
+				name := fmt.Sprintf("Task %d-%d", w, i)
+				if err := s.AddTransientTask(name, "Visit", 20200501+i, time.Duration(9+w*2)*time.Hour, time.Hour); err != nil {
+					t.Errorf("Failed to add %q: %v", name, err)
+				}
+			}
+		}(w)
+	}
+	var readerWg sync.WaitGroup
+	for r := 0; r < readers; r++ {
+		readerWg.Add(1)
+		go func() {
+			defer readerWg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				if _, err := s.GetTasksByMonth(5); err != nil {
+					t.Errorf("Failed to get tasks by month: %v", err)
+				}
+				if _, err := s.MarshalTasks(); err != nil {
+					t.Errorf("Failed to marshal tasks: %v", err)
+				}
+				snap := s.Snapshot()
+				if _, err := snap.GetTasksByDay(5, 1); err != nil {
+					t.Errorf("Failed to get tasks by day from snapshot: %v", err)
+				}
+				s.TransientTasks()
+			}
+		}()
+	}
+	wg.Wait()
+	close(done)
+	readerWg.Wait()
+	if got := len(s.TransientTasks()); got != writers*tasksEach {
+		t.Errorf("Got %d transient tasks, want %d", got, writers*tasksEach)
+	}
+}
+
+func TestConcurrentConflicts(t *testing.T) {
+	const clients = 16
+	s := model.NewSchedule()
+	var wg sync.WaitGroup
+	errs := make(chan error, clients)
+	for c := 0; c < clients; c++ {
+		wg.Add(1)
+		go func(c int) {
+			defer wg.Done()
+			// Every client tries to book the same slot
+			errs <- s.AddTransientTask(fmt.Sprintf("Booking %d", c), "Appointment", 20200601, 9*time.Hour, time.Hour)
+		}(c)
+	}
+	wg.Wait()
+	close(errs)
+	added := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			added++
+		case !errors.Is(err, model.ErrConflict):
+			t.Errorf("Got error %v, want a scheduling conflict", err)
+		}
+	}
+	if added != 1 || len(s.TransientTasks()) != 1 {
+		t.Errorf("%d clients booked the same slot, want 1", added)
+	}
+}
+
+func TestTransactionBlocksWriters(t *testing.T) {
+	s := model.NewSchedule()
+	tx, err := s.Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	result := make(chan error)
+	go func() {
+		// Waits for the transaction to end, then conflicts with the task it added
+		result <- s.AddTransientTask("Late booking", "Appointment", 20200601, 9*time.Hour, time.Hour)
+	}()
+	if err := tx.AddTransientTask("Early booking", "Appointment", 20200601, 9*time.Hour, time.Hour); err != nil {
+		t.Fatalf("Failed to add task in transaction: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+	if err := <-result; !errors.Is(err, model.ErrConflict) {
+		t.Errorf("Got error %v, want a scheduling conflict with the committed task", err)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	s := model.NewSchedule()
+	if err := s.LoadFile("../data/Set1.json"); err != nil {
+		t.Fatalf("Failed to load Set1: %v", err)
+	}
+	snap := s.Snapshot()
+	if err := s.DeleteTask("Intern Interview"); err != nil {
+		t.Fatalf("Failed to delete task: %v", err)
+	}
+	if _, ok := snap.TransientTask("Intern Interview"); !ok {
+		t.Errorf("Snapshot changed after the schedule changed")
+	}
+	before, _ := snap.GetTasksByMonth(4)
+	after, _ := s.GetTasksByMonth(4)
+	if len(before) != len(after)+1 {
+		t.Errorf("Got %d tasks in the snapshot and %d in the schedule, want one more in the snapshot", len(before), len(after))
+	}
+}
@@ -0,0 +1,53 @@
+// Package tests contains unit tests
+// csv_test.go contains tests for CSV import and export
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hlin91/CS3560_Scheduler_Backup/model"
+)
+
+func TestCSV(t *testing.T) {
+	s := model.NewSchedule()
+	if err := s.LoadCSV("../data/Timetable.csv"); err != nil {
+		t.Fatalf("Failed to load Timetable.csv: %v", err)
+	}
+	set1 := model.NewSchedule()
+	if err := set1.LoadFile("../data/Set1.json"); err != nil {
+		t.Fatalf("Failed to load Set1: %v", err)
+	}
+	for _, r := range set1.RecurringTasks() {
+		if got, _ := s.RecurringTask(r.Name); withoutID(got) != withoutID(r) {
+			t.Errorf("Recurring task %q loaded as %+v, want %+v", r.Name, got, r)
+		}
+	}
+	if len(s.AntiTasks()) != 1 || len(s.TransientTasks()) != 1 {
+		t.Errorf("Timetable.csv loaded the wrong number of tasks")
+	}
+	if err := s.LoadCSV("../data/Timetable.csv"); err == nil {
+		t.Errorf("Loaded csv file with conflicting tasks")
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.csv")
+	if err := s.WriteCSV(path); err != nil {
+		t.Fatalf("Failed to write csv: %v", err)
+	}
+	loaded := model.NewSchedule()
+	if err := loaded.LoadCSV(path); err != nil {
+		t.Fatalf("Failed to load written csv: %v", err)
+	}
+	if len(loaded.RecurringTasks()) != 2 || len(loaded.AntiTasks()) != 1 || len(loaded.TransientTasks()) != 1 {
+		t.Errorf("Tasks were lost after writing and loading csv")
+	}
+	bad := filepath.Join(dir, "bad.csv")
+	os.WriteFile(bad, []byte("Name,Type,Date,StartTime,Duration\nNo conflict,Visit,2020-04-13,12:00,1\nNap,Visit,2020-13-01,12:00,1\n"), 0644)
+	if err := loaded.LoadCSV(bad); err == nil {
+		t.Errorf("Loaded csv file with a bad date")
+	}
+	if _, ok := loaded.TransientTask("No conflict"); ok {
+		t.Errorf("Schedule failed to revert after bad row")
+	}
+}
@@ -0,0 +1,98 @@
+// Package tests contains unit tests
+// helpers_test.go contains the recurring tasks and helpers shared by the tests
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hlin91/CS3560_Scheduler_Backup/model"
+)
+
+var (
+	// A class every Tuesday from 2020-04-07 to 2020-05-05 from 19:00 to 20:15
+	tuesdayClass = model.RecurringTask{Task: model.Task{Name: "CS3560-Tu", Type: model.CLASS, Date: 20200407, StartTime: 19 * time.Hour, Duration: 75 * time.Minute}, EndDate: 20200505, Frequency: 7}
+	// A class every Thursday of April 2020 from 19:00 to 20:15
+	thursdayClass = model.RecurringTask{Task: model.Task{Name: "CS3560-Th", Type: model.CLASS, Date: 20200402, StartTime: 19 * time.Hour, Duration: 75 * time.Minute}, EndDate: 20200430, Frequency: 7}
+	// A workout every day from 2020-04-15 to 2020-04-30 from 7:00 to 8:00
+	dailyGym = model.RecurringTask{Task: model.Task{Name: "Gym", Type: model.EXERCISE, Date: 20200415, StartTime: 7 * time.Hour, Duration: time.Hour}, EndDate: 20200430, Frequency: 1}
+)
+
+// scheduleWith returns a schedule with the recurring tasks given
+func scheduleWith(t *testing.T, tasks ...model.RecurringTask) *model.Schedule {
+	t.Helper()
+	s := model.NewSchedule()
+	for _, r := range tasks {
+		if err := s.AddRecurring(r); err != nil {
+			t.Fatalf("Failed to add recurring task %q: %v", r.Name, err)
+		}
+	}
+	return s
+}
+
+// tasksOn returns the start times of the tasks on a day keyed by name
+func tasksOn(t *testing.T, s *model.Schedule, date int) map[string]time.Duration {
+	day := time.Date(date/10000, time.Month(date/100%100), date%100, 0, 0, 0, 0, time.UTC)
+	tasks, err := s.GetTasksInRange(day, day.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatalf("Failed to get tasks: %v", err)
+	}
+	result := map[string]time.Duration{}
+	for _, task := range tasks {
+		result[task.Name] = task.StartTime
+	}
+	return result
+}
+
+// subtaskDates returns the dates of the subtasks of a recurring task
+func subtaskDates(t *testing.T, r model.RecurringTask) []int {
+	subtasks, err := r.GetSubtasks()
+	if err != nil {
+		t.Fatalf("Failed to get subtasks: %v", err)
+	}
+	dates := []int{}
+	for _, task := range subtasks {
+		dates = append(dates, task.Date)
+	}
+	return dates
+}
+
+// recurringID returns the ID of a recurring task
+func recurringID(t *testing.T, s *model.Schedule, ref string) string {
+	t.Helper()
+	r, ok := s.RecurringTask(ref)
+	if !ok {
+		t.Fatalf("No recurring task %q", ref)
+	}
+	return r.ID
+}
+
+// withoutID returns a recurring task without its ID, as it is read back from the formats that do not keep IDs
+func withoutID(r model.RecurringTask) model.RecurringTask {
+	r.ID = ""
+	return r
+}
+
+// portableAntiTasks returns anti tasks of a schedule as they are read back from the formats that do not keep IDs,
+// which name the recurring task a range cancels by name
+func portableAntiTasks(s *model.Schedule, tasks []model.AntiTask) []model.AntiTask {
+	result := []model.AntiTask{}
+	for _, a := range tasks {
+		if r, ok := s.RecurringTask(a.Series); ok {
+			a.Series = r.Name
+		}
+		a.ID = ""
+		result = append(result, a)
+	}
+	return result
+}
+
+// portableOverrides returns overrides without the IDs of their recurring tasks
+func portableOverrides(overrides []model.Override) []model.Override {
+	result := []model.Override{}
+	for _, o := range overrides {
+		o.ID = ""
+		result = append(result, o)
+	}
+	return result
+}
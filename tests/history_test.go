@@ -0,0 +1,58 @@
+// Package tests contains unit tests
+// history_test.go contains tests for undoing and redoing changes to the schedule
+package tests
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hlin91/CS3560_Scheduler_Backup/model"
+)
+
+func TestUndoRedo(t *testing.T) {
+	s := model.NewSchedule()
+	if _, err := s.Undo(); !errors.Is(err, model.ErrNothingToUndo) {
+		t.Errorf("Undid with an empty history")
+	}
+	if err := s.LoadFile("../data/Set1.json"); err != nil {
+		t.Fatalf("Failed to load Set1: %v", err)
+	}
+	if err := s.DeleteTask("CS3560-Tu"); err != nil {
+		t.Fatalf("Failed to delete task: %v", err)
+	}
+	if _, ok := s.AntiTask("Skip For Visit"); ok {
+		t.Fatalf("Deleting a recurring task kept its anti task")
+	}
+	// A failed operation is not recorded
+	if err := s.AddTransientTask("Watch a movie", "Movie", 20200429, 21*time.Hour+30*time.Minute, 2*time.Hour); err == nil {
+		t.Fatalf("Added invalid task")
+	}
+	if _, err := s.Undo(); err != nil {
+		t.Fatalf("Failed to undo: %v", err)
+	}
+	if _, ok := s.RecurringTask("CS3560-Tu"); !ok {
+		t.Errorf("Undo did not restore the recurring task")
+	}
+	if _, ok := s.AntiTask("Skip For Visit"); !ok {
+		t.Errorf("Undo did not restore the anti task of the recurring task")
+	}
+	if _, err := s.Redo(); err != nil {
+		t.Fatalf("Failed to redo: %v", err)
+	}
+	if len(s.RecurringTasks()) != 1 || len(s.AntiTasks()) != 0 {
+		t.Errorf("Redo did not delete the recurring task and its anti task")
+	}
+	s.Undo()
+	// Undoing the load removes every task it added
+	if _, err := s.Undo(); err != nil {
+		t.Fatalf("Failed to undo load: %v", err)
+	}
+	if len(s.RecurringTasks()) != 0 || len(s.AntiTasks()) != 0 || len(s.TransientTasks()) != 0 {
+		t.Errorf("Undoing the load left tasks in the schedule")
+	}
+	s.AddTransientTask("Dentist", "Appointment", 20200501, 9*time.Hour, time.Hour)
+	if _, err := s.Redo(); !errors.Is(err, model.ErrNothingToRedo) {
+		t.Errorf("Redid after a new change")
+	}
+}
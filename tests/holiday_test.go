@@ -0,0 +1,183 @@
+// Package tests contains unit tests
+// holiday_test.go contains tests for cancelling the occurrences of recurring tasks on holidays
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hlin91/CS3560_Scheduler_Backup/controller"
+	"github.com/hlin91/CS3560_Scheduler_Backup/model"
+)
+
+// holidayNames returns the names of the anti tasks generated for holidays
+func holidayNames(s *model.Schedule) []string {
+	names := []string{}
+	for _, a := range s.HolidayCancellations() {
+		names = append(names, a.Name)
+	}
+	return names
+}
+
+func TestCancelHolidays(t *testing.T) {
+	s := scheduleWith(t, tuesdayClass, dailyGym)
+	if err := s.AddAntiTask("Skip", model.CANCEL, 20200414, 19*time.Hour, 75*time.Minute); err != nil {
+		t.Fatalf("Failed to add anti task: %v", err)
+	}
+	holidays := []model.Holiday{{Name: "Spring Holiday", Date: 20200421}, {Name: "Founders Day", Date: 20200414}, {Name: "Picnic", Date: 20200419}}
+	if err := s.CancelHolidays(holidays, []string{model.CLASS}); err != nil {
+		t.Fatalf("Failed to cancel holidays: %v", err)
+	}
+	if got, want := holidayNames(s), []string{"Spring Holiday: CS3560-Tu (2020-04-21)"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Got holiday cancellations %v, want %v", got, want)
+	}
+	if got, want := tasksOn(t, s, 20200421), map[string]time.Duration{"Gym": 7 * time.Hour}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Got %v on the holiday, want %v", got, want)
+	}
+	// Regenerating replaces the cancellations and leaves the hand-made anti task alone
+	if err := s.CancelHolidays(holidays, []string{model.CLASS, model.EXERCISE}); err != nil {
+		t.Fatalf("Failed to cancel holidays again: %v", err)
+	}
+	if got := holidayNames(s); len(got) != 3 {
+		t.Errorf("Got holiday cancellations %v, want 3", got)
+	}
+	if _, ok := s.AntiTask("Skip"); !ok || len(s.AntiTasks()) != 4 {
+		t.Errorf("Hand-made anti task changed by regenerating: %v", s.AntiTasks())
+	}
+	if err := s.AddTransientTask("Dinner", model.VISIT, 20200421, 19*time.Hour, time.Hour); err != nil {
+		t.Fatalf("Failed to add task in place of a cancelled occurrence: %v", err)
+	}
+	if err := s.RemoveHolidayCancellations(); err == nil {
+		t.Errorf("Removed holiday cancellations bringing back an occurrence that conflicts")
+	}
+	if got := holidayNames(s); len(got) != 3 {
+		t.Errorf("Failed removal changed the holiday cancellations to %v", got)
+	}
+	if err := s.DeleteTask("Dinner"); err != nil {
+		t.Fatalf("Failed to delete task: %v", err)
+	}
+	if err := s.RemoveHolidayCancellations(); err != nil {
+		t.Fatalf("Failed to remove holiday cancellations: %v", err)
+	}
+	if got := holidayNames(s); len(got) != 0 {
+		t.Errorf("Holiday cancellations left after removing them: %v", got)
+	}
+	if _, ok := s.AntiTask("Skip"); !ok {
+		t.Errorf("Hand-made anti task removed with the holiday cancellations")
+	}
+	if err := s.CancelHolidays(holidays, []string{model.VISIT}); err == nil {
+		t.Errorf("Cancelled holidays for a type that does not recur")
+	}
+}
+
+func TestEditHolidayCancellation(t *testing.T) {
+	s := scheduleWith(t, tuesdayClass, dailyGym)
+	holidays := []model.Holiday{{Name: "Spring Holiday", Date: 20200421}}
+	if err := s.CancelHolidays(holidays, []string{model.CLASS}); err != nil {
+		t.Fatalf("Failed to cancel holidays: %v", err)
+	}
+	a := s.HolidayCancellations()[0]
+	err := s.Atomically(func(tx *model.Tx) error {
+		return tx.EditAntiTask(a.ID, "No Class", a.Date, a.StartTime, a.Duration)
+	})
+	if err != nil {
+		t.Fatalf("Failed to edit holiday cancellation in a transaction: %v", err)
+	}
+	if got, want := holidayNames(s), []string{"No Class"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Got holiday cancellations %v after an edit in a transaction, want %v", got, want)
+	}
+	srv := httptest.NewServer(controller.NewServer(s, ""))
+	defer srv.Close()
+	req, err := http.NewRequest("PUT", srv.URL+"/tasks/"+a.ID, strings.NewReader(`{"Name": "Day Off", "Type": "Cancellation", "Date": 20200421, "StartTime": 19, "Duration": 1.25}`))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("PUT failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("PUT: got status %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if got, want := holidayNames(s), []string{"Day Off"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Got holiday cancellations %v after an edit through the API, want %v", got, want)
+	}
+	// The edited cancellation is replaced when regenerating rather than left beside a new one
+	if err := s.CancelHolidays(holidays, []string{model.CLASS}); err != nil {
+		t.Fatalf("Failed to cancel holidays again: %v", err)
+	}
+	if got := s.AntiTasks(); len(got) != 1 {
+		t.Errorf("Got anti tasks %v after regenerating, want 1", got)
+	}
+}
+
+func TestReadHolidays(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"holidays.json": `[{"Name": "Memorial Day", "Date": 20200525}, {"Name": "Thanksgiving Break", "Date": 20201126}, {"Name": "Thanksgiving Break", "Date": 20201127}]`,
+		"holidays.ics": "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VEVENT\r\nSUMMARY:Memorial Day\r\nDTSTART;VALUE=DATE:20200525\r\nEND:VEVENT\r\n" +
+			"BEGIN:VEVENT\r\nSUMMARY:Thanksgiving Break\r\nDTSTART;VALUE=DATE:20201126\r\nDTEND;VALUE=DATE:20201128\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n",
+	}
+	want := []model.Holiday{{Name: "Memorial Day", Date: 20200525}, {Name: "Thanksgiving Break", Date: 20201126}, {Name: "Thanksgiving Break", Date: 20201127}}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		got, err := model.ReadHolidays(path)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", name, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got holidays %v, want %v", name, got, want)
+		}
+	}
+	bad := filepath.Join(dir, "bad.json")
+	if err := os.WriteFile(bad, []byte(`[{"Name": "Leap Day", "Date": 20210229}]`), 0644); err != nil {
+		t.Fatalf("Failed to write bad.json: %v", err)
+	}
+	if _, err := model.ReadHolidays(bad); err == nil {
+		t.Errorf("Read holiday with a bad date")
+	}
+}
+
+func TestHolidayRoundTrip(t *testing.T) {
+	s := scheduleWith(t, tuesdayClass, dailyGym)
+	if err := s.CancelHolidays([]model.Holiday{{Name: "Spring Holiday", Date: 20200421}}, []string{model.CLASS, model.EXERCISE}); err != nil {
+		t.Fatalf("Failed to cancel holidays: %v", err)
+	}
+	dir := t.TempDir()
+	for ext, write := range map[string]func(*model.Schedule, string) error{
+		"json": (*model.Schedule).WriteTasks,
+		"csv":  (*model.Schedule).WriteCSV,
+	} {
+		path := filepath.Join(dir, "holidays."+ext)
+		if err := write(s, path); err != nil {
+			t.Fatalf("Failed to write %s: %v", ext, err)
+		}
+		loaded := model.NewSchedule()
+		var err error
+		if ext == "json" {
+			err = loaded.LoadFile(path)
+		} else {
+			err = loaded.LoadCSV(path)
+		}
+		if err != nil {
+			t.Fatalf("Failed to load %s: %v", ext, err)
+		}
+		got, want := loaded.HolidayCancellations(), s.HolidayCancellations()
+		if ext == "csv" {
+			got, want = portableAntiTasks(loaded, got), portableAntiTasks(s, want)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: holiday cancellations loaded as %v, want %v", ext, got, want)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+// Package tests contains unit tests
+// ical_test.go contains tests for iCalendar export and import
+package tests
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hlin91/CS3560_Scheduler_Backup/model"
+)
+
+func TestICalExport(t *testing.T) {
+	s := model.NewSchedule()
+	if err := s.LoadFile("../data/Set1.json"); err != nil {
+		t.Fatalf("Failed to load Set1: %v", err)
+	}
+	cal := string(s.MarshalICal())
+	if n := strings.Count(cal, "BEGIN:VEVENT"); n != 3 {
+		t.Errorf("Got %d events, want 3 (anti tasks should not be events)", n)
+	}
+	if !strings.Contains(cal, "RRULE:FREQ=DAILY;INTERVAL=7;UNTIL=20200505T190000Z\r\n") {
+		t.Errorf("Missing RRULE for CS3560-Tu")
+	}
+	if !strings.Contains(cal, "EXDATE:20200428T190000Z\r\n") {
+		t.Errorf("Missing EXDATE for Skip For Visit")
+	}
+	if !strings.Contains(cal, "DTEND:20200428T193000Z\r\n") {
+		t.Errorf("Intern Interview has the wrong end time")
+	}
+}
+
+func TestICalImport(t *testing.T) {
+	s := model.NewSchedule()
+	err := s.LoadICal("../data/Term.ics", nil)
+	if err == nil {
+		t.Fatalf("Loaded events with unmapped categories")
+	}
+	if !strings.Contains(err.Error(), `"Lecture"`) || !strings.Contains(err.Error(), `"Meeting"`) {
+		t.Errorf("Error does not name every unmapped category: %v", err)
+	}
+	categories := map[string]string{"lecture": model.CLASS, "Meeting": model.APPOINTMENT}
+	if err := s.LoadICal("../data/Term.ics", categories); err != nil {
+		t.Fatalf("Failed to load Term.ics: %v", err)
+	}
+	r, ok := s.RecurringTask("CS3560 Lecture")
+	if !ok {
+		t.Fatalf("Repeating event was not loaded as a recurring task")
+	}
+	// The lecture keeps the time zone of its TZID
+	if r.Date != 20200414 || r.StartTime != 12*time.Hour || r.TimeZone != "America/Los_Angeles" || r.Duration != time.Hour+15*time.Minute || r.EndDate != 20200505 || r.Frequency != 7 {
+		t.Errorf("Recurring task loaded with wrong details: %+v", r)
+	}
+	if len(s.AntiTasks()) != 1 {
+		t.Errorf("Got %d anti tasks, want 1 for the EXDATE", len(s.AntiTasks()))
+	}
+	if a, ok := s.TransientTask("Advising, spring term"); !ok || a.StartTime != 17*time.Hour || a.Duration != 2*time.Hour+30*time.Minute {
+		t.Errorf("Event was not loaded as a transient task")
+	}
+	if err := s.LoadICal("../data/Term.ics", categories); err == nil {
+		t.Errorf("Loaded conflicting events")
+	}
+	if len(s.RecurringTasks()) != 1 || len(s.AntiTasks()) != 1 || len(s.TransientTasks()) != 1 {
+		t.Errorf("Schedule failed to revert after conflict in Term.ics")
+	}
+}
+
+func TestICalRoundTrip(t *testing.T) {
+	s := model.NewSchedule()
+	if err := s.LoadFile("../data/Set1.json"); err != nil {
+		t.Fatalf("Failed to load Set1: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "set1.ics")
+	if err := s.WriteICal(path); err != nil {
+		t.Fatalf("Failed to write calendar: %v", err)
+	}
+	loaded := model.NewSchedule()
+	if err := loaded.LoadICal(path, nil); err != nil {
+		t.Fatalf("Failed to load exported calendar: %v", err)
+	}
+	for _, r := range s.RecurringTasks() {
+		if got, _ := loaded.RecurringTask(r.Name); withoutID(got) != withoutID(r) {
+			t.Errorf("Recurring task %q changed after round trip: %+v", r.Name, got)
+		}
+	}
+	for _, task := range s.TransientTasks() {
+		// Calendars do not keep IDs
+		got, _ := loaded.TransientTask(task.Name)
+		got.ID, task.ID = "", ""
+		if got != task {
+			t.Errorf("Task %q changed after round trip: %+v", task.Name, got)
+		}
+	}
+	if len(loaded.AntiTasks()) != len(s.AntiTasks()) {
+		t.Errorf("Got %d anti tasks after round trip, want %d", len(loaded.AntiTasks()), len(s.AntiTasks()))
+	}
+}
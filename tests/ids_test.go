@@ -0,0 +1,259 @@
+// Package tests contains unit tests
+// ids_test.go contains tests for task IDs and looking up tasks by ID or name
+package tests
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hlin91/CS3560_Scheduler_Backup/controller"
+	"github.com/hlin91/CS3560_Scheduler_Backup/model"
+)
+
+func TestIDsKeptThroughEdits(t *testing.T) {
+	s := scheduleWith(t, thursdayClass)
+	id := recurringID(t, s, "CS3560-Th")
+	if err := s.AddOverride(model.Override{Task: model.Task{ID: id, Date: 20200424, StartTime: 18 * time.Hour, Duration: 2 * time.Hour}, Original: 20200423}); err != nil {
+		t.Fatalf("Failed to add override: %v", err)
+	}
+	if err := s.AddAntiTask("Skip", model.CANCEL, 20200416, 19*time.Hour, 75*time.Minute); err != nil {
+		t.Fatalf("Failed to add anti task: %v", err)
+	}
+	if err := s.EditRecurringTask(id, "CS3560", model.CLASS, 20200402, 19*time.Hour, 75*time.Minute, 20200430, 7); err != nil {
+		t.Fatalf("Failed to rename recurring task: %v", err)
+	}
+	if r, ok := s.RecurringTask(id); !ok || r.Name != "CS3560" {
+		t.Errorf("Renamed recurring task not found by its ID: %v", r)
+	}
+	if o, ok := s.Override("CS3560", 20200423); !ok || o.ID != id || o.Name != "CS3560" {
+		t.Errorf("Override did not follow its recurring task: %v", o)
+	}
+	if _, ok := tasksOn(t, s, 20200416)["CS3560"]; ok {
+		t.Errorf("Anti task no longer cancels the renamed recurring task")
+	}
+	if err := s.AddTransientTask("Dinner", model.VISIT, 20200420, 18*time.Hour, time.Hour); err != nil {
+		t.Fatalf("Failed to add transient task: %v", err)
+	}
+	dinner, _ := s.TransientTask("Dinner")
+	if err := s.EditTransientTask("Dinner", "Supper", model.VISIT, 20200421, 18*time.Hour, time.Hour); err != nil {
+		t.Fatalf("Failed to edit transient task: %v", err)
+	}
+	if got, ok := s.TransientTask(dinner.ID); !ok || got.Name != "Supper" || got.Date != 20200421 {
+		t.Errorf("Edited task not found by its ID: %v", got)
+	}
+	if _, err := s.Undo(); err != nil {
+		t.Fatalf("Failed to undo: %v", err)
+	}
+	if got, ok := s.TransientTask(dinner.ID); !ok || got != dinner {
+		t.Errorf("Undo restored %v, want %v", got, dinner)
+	}
+}
+
+func TestDuplicateNames(t *testing.T) {
+	s := model.NewSchedule()
+	for _, date := range []int{20200420, 20200421} {
+		if err := s.AddTransientTask("Lunch", model.VISIT, date, 12*time.Hour, time.Hour); err != nil {
+			t.Fatalf("Failed to add task with a name in use: %v", err)
+		}
+	}
+	if err := s.AddRecurringTask("Gym", model.EXERCISE, 20200420, 7*time.Hour, time.Hour, 20200424, 1); err != nil {
+		t.Fatalf("Failed to add recurring task: %v", err)
+	}
+	if err := s.AddTransientTask("Gym", model.APPOINTMENT, 20200425, 7*time.Hour, time.Hour); err != nil {
+		t.Fatalf("Failed to add task named after a recurring task: %v", err)
+	}
+	if _, ok := s.TransientTask("Lunch"); ok {
+		t.Errorf("Got a task by a name two tasks share")
+	}
+	if err := s.DeleteTask("Lunch"); !errors.Is(err, model.ErrAmbiguous) {
+		t.Errorf("Deleting by a shared name gave %v, want %v", err, model.ErrAmbiguous)
+	}
+	if _, err := s.TaskID("Gym"); !errors.Is(err, model.ErrAmbiguous) {
+		t.Errorf("Looking up a name shared across kinds gave %v, want %v", err, model.ErrAmbiguous)
+	}
+	if _, ok := s.RecurringTask("Gym"); !ok {
+		t.Errorf("Recurring task not found by a name no other recurring task has")
+	}
+	lunches := s.TransientTasks()[1:]
+	if err := s.DeleteTask(lunches[0].ID); err != nil {
+		t.Fatalf("Failed to delete task by ID: %v", err)
+	}
+	if got, ok := s.TransientTask("Lunch"); !ok || got != lunches[1] {
+		t.Errorf("Got %v by the name left to one task, want %v", got, lunches[1])
+	}
+	if err := s.AddTask(model.Task{ID: lunches[1].ID, Name: "Brunch", Type: model.VISIT, Date: 20200422, StartTime: 11 * time.Hour, Duration: time.Hour}); !errors.Is(err, model.ErrIDTaken) {
+		t.Errorf("Adding a task with an ID in use gave %v, want %v", err, model.ErrIDTaken)
+	}
+	// A split may keep the name of the task it splits, leaving the name to two recurring tasks
+	later := model.RecurringTask{Task: model.Task{Name: "Gym", Type: model.EXERCISE, Date: 20200422, StartTime: 8 * time.Hour, Duration: time.Hour}, EndDate: 20200424, Frequency: 1}
+	if err := s.SplitRecurring("Gym", 20200422, later); err != nil {
+		t.Fatalf("Failed to split recurring task keeping its name: %v", err)
+	}
+	if _, ok := s.RecurringTask("Gym"); ok {
+		t.Errorf("Got a recurring task by a name two recurring tasks share")
+	}
+	if got := tasksOn(t, s, 20200423); got["Gym"] != 8*time.Hour {
+		t.Errorf("Got %v after the split, want Gym at 8:00", got)
+	}
+}
+
+func TestIDsRoundTrip(t *testing.T) {
+	s := scheduleWith(t, tuesdayClass, dailyGym)
+	class := recurringID(t, s, "CS3560-Tu")
+	steps := []error{
+		s.AddAnti(model.AntiTask{Task: model.Task{Name: "Spring Break", Type: model.CANCEL, Date: 20200420}, EndDate: 20200426, Series: "CS3560-Tu"}),
+		s.AddOverride(model.Override{Task: model.Task{Name: "CS3560-Tu", Date: 20200429, StartTime: 19 * time.Hour, Duration: 75 * time.Minute}, Original: 20200428}),
+		s.AddTransientTask("Dentist", model.APPOINTMENT, 20200414, 9*time.Hour, time.Hour),
+		s.AddTransientTask("Dentist", model.APPOINTMENT, 20200417, 9*time.Hour, time.Hour),
+	}
+	for _, err := range steps {
+		if err != nil {
+			t.Fatalf("Failed to build schedule: %v", err)
+		}
+	}
+	if a, _ := s.AntiTask("Spring Break"); a.Series != class {
+		t.Errorf("Range anti task names %q, want the ID %q", a.Series, class)
+	}
+	path := filepath.Join(t.TempDir(), "ids.json")
+	if err := s.WriteTasks(path); err != nil {
+		t.Fatalf("Failed to write tasks: %v", err)
+	}
+	loaded := model.NewSchedule()
+	if err := loaded.LoadFile(path); err != nil {
+		t.Fatalf("Failed to load tasks: %v", err)
+	}
+	if got, want := loaded.TransientTasks(), s.TransientTasks(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Transient tasks loaded as %v, want %v", got, want)
+	}
+	if got, want := loaded.AntiTasks(), s.AntiTasks(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Anti tasks loaded as %v, want %v", got, want)
+	}
+	if got, want := loaded.RecurringTasks(), s.RecurringTasks(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Recurring tasks loaded as %v, want %v", got, want)
+	}
+	if got, want := loaded.Overrides(), s.Overrides(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Overrides loaded as %v, want %v", got, want)
+	}
+	if err := loaded.LoadFile(path); !errors.Is(err, model.ErrIDTaken) {
+		t.Errorf("Loading the same tasks again gave %v, want %v", err, model.ErrIDTaken)
+	}
+	// Tasks without IDs are given new ones, and subtasks keep their names
+	set1 := model.NewSchedule()
+	if err := set1.LoadFile("../data/Set1.json"); err != nil {
+		t.Fatalf("Failed to load Set1: %v", err)
+	}
+	for _, task := range set1.TransientTasks() {
+		if task.ID == "" || strings.HasSuffix(task.Name, ")") {
+			t.Errorf("Task loaded as %+v, want an ID and the name from the file", task)
+		}
+	}
+}
+
+func TestICalDuplicateNames(t *testing.T) {
+	s := model.NewSchedule()
+	steps := []error{
+		s.AddTransientTask("Gym", model.VISIT, 20200420, 7*time.Hour, time.Hour),
+		s.AddTransientTask("Gym", model.VISIT, 20200421, 7*time.Hour, time.Hour),
+		s.AddRecurringTask("Class", model.CLASS, 20200406, 9*time.Hour, time.Hour, 20200430, 7),
+		s.AddRecurringTask("Class", model.CLASS, 20200407, 9*time.Hour, time.Hour, 20200430, 7),
+	}
+	for _, err := range steps {
+		if err != nil {
+			t.Fatalf("Failed to build schedule: %v", err)
+		}
+	}
+	var tuesday model.RecurringTask
+	for _, r := range s.RecurringTasks() {
+		if r.Date == 20200407 {
+			tuesday = r
+		}
+	}
+	if err := s.AddOverride(model.Override{Task: model.Task{ID: tuesday.ID, Date: 20200415, StartTime: 9 * time.Hour, Duration: time.Hour}, Original: 20200414}); err != nil {
+		t.Fatalf("Failed to add override: %v", err)
+	}
+	cal := string(s.MarshalICal())
+	for _, task := range s.TransientTasks() {
+		if n := strings.Count(cal, "UID:"+task.ID+"@pss\r\n"); n != 1 {
+			t.Errorf("Got %d events with the UID of %v, want 1", n, task)
+		}
+	}
+	path := filepath.Join(t.TempDir(), "ids.ics")
+	if err := s.WriteICal(path); err != nil {
+		t.Fatalf("Failed to write iCalendar: %v", err)
+	}
+	loaded := model.NewSchedule()
+	if err := loaded.LoadICal(path, nil); err != nil {
+		t.Fatalf("Failed to load iCalendar: %v", err)
+	}
+	overrides := loaded.Overrides()
+	if len(overrides) != 1 {
+		t.Fatalf("Got overrides %v, want 1", overrides)
+	}
+	if r, ok := loaded.RecurringTask(overrides[0].ID); !ok || r.Date != 20200407 {
+		t.Errorf("Override loaded onto %v, want the series starting 2020-04-07", r)
+	}
+}
+
+func TestCSVDuplicateNames(t *testing.T) {
+	s := model.NewSchedule()
+	steps := []error{
+		s.AddRecurringTask("Class", model.CLASS, 20200406, 9*time.Hour, time.Hour, 20200430, 7),
+		s.AddRecurringTask("Class", model.CLASS, 20200407, 9*time.Hour, time.Hour, 20200430, 7),
+	}
+	for _, err := range steps {
+		if err != nil {
+			t.Fatalf("Failed to build schedule: %v", err)
+		}
+	}
+	series := s.RecurringTasks()[0].ID
+	if err := s.AddAnti(model.AntiTask{Task: model.Task{Name: "Break", Type: model.CANCEL, Date: 20200413}, EndDate: 20200419, Series: series}); err != nil {
+		t.Fatalf("Failed to add range anti task: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "ids.csv")
+	if err := s.WriteCSV(path); !errors.Is(err, model.ErrAmbiguous) {
+		t.Errorf("Writing a range of a series whose name is shared gave %v, want %v", err, model.ErrAmbiguous)
+	}
+	if err := s.EditRecurringTask(series, "Class (Mon)", model.CLASS, s.RecurringTasks()[0].Date, 9*time.Hour, time.Hour, 20200430, 7); err != nil {
+		t.Fatalf("Failed to rename recurring task: %v", err)
+	}
+	if err := s.WriteCSV(path); err != nil {
+		t.Errorf("Failed to write once the name is no longer shared: %v", err)
+	}
+}
+
+func TestServerIDs(t *testing.T) {
+	s := model.NewSchedule()
+	srv := httptest.NewServer(controller.NewServer(s, ""))
+	defer srv.Close()
+	do := func(method, path, body string, want int) {
+		t.Helper()
+		req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s failed: %v", method, path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("%s %s: got status %d, want %d", method, path, resp.StatusCode, want)
+		}
+	}
+	do("POST", "/tasks/transient", `{"Name": "Lunch", "Type": "Visit", "Date": 20200420, "StartTime": 12, "Duration": 1}`, http.StatusCreated)
+	do("POST", "/tasks/transient", `{"Name": "Lunch", "Type": "Visit", "Date": 20200421, "StartTime": 12, "Duration": 1}`, http.StatusCreated)
+	do("GET", "/tasks/Lunch", "", http.StatusConflict)
+	lunch := s.TransientTasks()[0]
+	do("PUT", "/tasks/"+lunch.ID, `{"Name": "Brunch", "Type": "Visit", "Date": 20200420, "StartTime": 11, "Duration": 1}`, http.StatusOK)
+	if got, ok := s.TransientTask(lunch.ID); !ok || got.Name != "Brunch" {
+		t.Errorf("Task edited through the API changed ID or was not renamed: %v", got)
+	}
+	do("GET", "/tasks/Lunch", "", http.StatusOK)
+	do("DELETE", "/tasks/"+lunch.ID, "", http.StatusOK)
+}
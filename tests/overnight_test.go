@@ -0,0 +1,85 @@
+// Package tests contains unit tests
+// overnight_test.go contains tests for tasks that run past midnight or over several days
+package tests
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hlin91/CS3560_Scheduler_Backup/model"
+)
+
+func TestSleepAcrossMidnight(t *testing.T) {
+	s := model.NewSchedule()
+	if err := s.AddRecurringTask("Sleep", model.SLEEP, 20200401, 23*time.Hour, 8*time.Hour, 20200407, 1); err != nil {
+		t.Fatalf("Failed to add recurring task: %v", err)
+	}
+	if err := s.AddTransientTask("Early Flight", model.VISIT, 20200403, 6*time.Hour, time.Hour); err == nil {
+		t.Errorf("Added task during the sleep that began the night before")
+	}
+	if err := s.AddTransientTask("Breakfast", model.VISIT, 20200403, 7*time.Hour, time.Hour); err != nil {
+		t.Errorf("Failed to add task after the sleep ends: %v", err)
+	}
+	// Cancelling the sleep that begins on the 4th frees the morning of the 5th
+	if err := s.AddAntiTask("Night Out", model.CANCEL, 20200404, 23*time.Hour, 8*time.Hour); err != nil {
+		t.Fatalf("Failed to add anti task: %v", err)
+	}
+	if err := s.AddTransientTask("Early Flight", model.VISIT, 20200405, 6*time.Hour, time.Hour); err != nil {
+		t.Errorf("Failed to add task in the cancelled sleep: %v", err)
+	}
+	tasks, err := s.GetTasksByDay(4, 3)
+	if err != nil {
+		t.Fatalf("Failed to get tasks: %v", err)
+	}
+	var sleeps []int
+	for _, task := range tasks {
+		if task.Type != model.SLEEP {
+			continue
+		}
+		sleeps = append(sleeps, task.Date)
+		if task.Date == 20200402 && !strings.Contains(task.String(), "Ends: 2020-04-03 07:00") {
+			t.Errorf("Task printed without the day it ends:\n%v", task)
+		}
+	}
+	if len(sleeps) != 2 {
+		t.Errorf("Got sleeps starting on %v, want the nights starting on the 2nd and 3rd", sleeps)
+	}
+	day := time.Date(2020, time.April, 3, 0, 0, 0, 0, time.UTC)
+	if tasks, _ := s.GetTasksInRange(day, day.AddDate(0, 0, 1)); len(tasks) != 3 {
+		t.Errorf("Got %d tasks on the day, want both sleeps and breakfast", len(tasks))
+	}
+	if err := s.AddRecurringTask("Hibernate", model.SLEEP, 20200501, 23*time.Hour, 25*time.Hour, 20200507, 1); err == nil {
+		t.Errorf("Added recurring task lasting longer than its frequency")
+	}
+}
+
+func TestMultiDayTask(t *testing.T) {
+	s := model.NewSchedule()
+	if err := s.AddTransientTask("Conference", model.VISIT, 20200429, 9*time.Hour, 72*time.Hour); err != nil {
+		t.Fatalf("Failed to add task lasting three days: %v", err)
+	}
+	task, _ := s.TransientTask("Conference")
+	if days := task.Days(); len(days) != 4 || days[0] != 20200429 || days[3] != 20200502 {
+		t.Errorf("Task touches %v, want 2020-04-29 to 2020-05-02", days)
+	}
+	if err := s.AddTransientTask("Dinner", model.VISIT, 20200501, 19*time.Hour, time.Hour); err == nil {
+		t.Errorf("Added task on the third day of another")
+	}
+	if err := s.AddRecurringTask("Standup", model.WORK, 20200427, 8*time.Hour, 30*time.Minute, 20200505, 1); err == nil {
+		t.Errorf("Added recurring task during the days of another task")
+	}
+	// Shown in May as well as April
+	if tasks, _ := s.GetTasksByMonth(5); len(tasks) != 1 {
+		t.Errorf("Got %d tasks in May, want the task that began in April", len(tasks))
+	}
+	if tasks, _ := s.GetTasksByDay(5, 2); len(tasks) != 1 {
+		t.Errorf("Got %d tasks on the last day of the task, want 1", len(tasks))
+	}
+	if tasks, _ := s.GetTasksByDay(5, 3); len(tasks) != 0 {
+		t.Errorf("Got %d tasks on the day after the task ends, want none", len(tasks))
+	}
+	if err := s.AddTransientTask("Sabbatical", model.VISIT, 20200601, 9*time.Hour, 60*24*time.Hour); err == nil {
+		t.Errorf("Added task lasting longer than %v", model.MAX_DURATION)
+	}
+}
@@ -0,0 +1,143 @@
+// Package tests contains unit tests
+// override_test.go contains tests for moving or changing single occurrences of recurring tasks
+package tests
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hlin91/CS3560_Scheduler_Backup/model"
+)
+
+func TestOverride(t *testing.T) {
+	s := scheduleWith(t, thursdayClass)
+	moved := model.Override{
+		Task:     model.Task{Name: "CS3560-Th", Date: 20200423, StartTime: 18 * time.Hour, Duration: 2 * time.Hour},
+		Original: 20200423,
+	}
+	if err := s.AddOverride(moved); err != nil {
+		t.Fatalf("Failed to add override: %v", err)
+	}
+	if got, want := tasksOn(t, s, 20200423), map[string]time.Duration{"CS3560-Th": 18 * time.Hour}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Got %v on the day of the override, want %v", got, want)
+	}
+	if err := s.AddTransientTask("Dentist", model.APPOINTMENT, 20200423, 17*time.Hour+30*time.Minute, time.Hour); err == nil {
+		t.Errorf("Added task overlapping the override")
+	}
+	// Moving the occurrence to another day frees its own slot
+	moved.Date, moved.StartTime = 20200424, 10*time.Hour
+	if err := s.AddOverride(moved); err != nil {
+		t.Fatalf("Failed to move override: %v", err)
+	}
+	if err := s.AddTransientTask("Dentist", model.APPOINTMENT, 20200423, 19*time.Hour, time.Hour); err != nil {
+		t.Errorf("Failed to add task in the freed slot: %v", err)
+	}
+	if got := tasksOn(t, s, 20200424); got["CS3560-Th"] != 10*time.Hour {
+		t.Errorf("Got %v on the day the occurrence moved to", got)
+	}
+	if tasks, _ := s.GetTasksByMonth(4); len(tasks) != 6 {
+		t.Errorf("Got %d tasks in April, want 6", len(tasks))
+	}
+	// The original cannot come back while the dentist has its slot
+	if err := s.DeleteOverride("CS3560-Th", 20200423); err == nil {
+		t.Errorf("Restored occurrence overlapping another task")
+	}
+	if _, ok := s.Override("CS3560-Th", 20200423); !ok {
+		t.Errorf("Override lost after failing to restore the occurrence")
+	}
+	if _, err := s.Undo(); err != nil {
+		t.Fatalf("Failed to undo: %v", err)
+	}
+	if err := s.DeleteOverride("CS3560-Th", 20200423); err != nil {
+		t.Fatalf("Failed to restore occurrence: %v", err)
+	}
+	if got := tasksOn(t, s, 20200423); got["CS3560-Th"] != 19*time.Hour {
+		t.Errorf("Got %v after restoring the occurrence", got)
+	}
+	if _, err := s.Undo(); err != nil {
+		t.Fatalf("Failed to undo: %v", err)
+	}
+	if _, ok := s.Override("CS3560-Th", 20200423); !ok {
+		t.Errorf("Undo did not bring back the override")
+	}
+}
+
+func TestBadOverrides(t *testing.T) {
+	s := scheduleWith(t, thursdayClass)
+	if err := s.AddTransientTask("Dentist", model.APPOINTMENT, 20200424, 10*time.Hour, time.Hour); err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	if err := s.AddTask(model.Task{Name: "Skip", Type: model.CANCEL, Date: 20200416, StartTime: 19 * time.Hour, Duration: 75 * time.Minute}); err != nil {
+		t.Fatalf("Failed to add anti task: %v", err)
+	}
+	bad := map[string]model.Override{
+		"unknown task":     {Task: model.Task{Name: "Gym", Date: 20200423, StartTime: 18 * time.Hour, Duration: time.Hour}, Original: 20200423},
+		"no occurrence":    {Task: model.Task{Name: "CS3560-Th", Date: 20200422, StartTime: 18 * time.Hour, Duration: time.Hour}, Original: 20200422},
+		"cancelled":        {Task: model.Task{Name: "CS3560-Th", Date: 20200416, StartTime: 18 * time.Hour, Duration: time.Hour}, Original: 20200416},
+		"conflict":         {Task: model.Task{Name: "CS3560-Th", Date: 20200424, StartTime: 10 * time.Hour, Duration: time.Hour}, Original: 20200423},
+		"other occurrence": {Task: model.Task{Name: "CS3560-Th", Date: 20200430, StartTime: 19 * time.Hour, Duration: time.Hour}, Original: 20200423},
+	}
+	for name, o := range bad {
+		if err := s.AddOverride(o); err == nil {
+			t.Errorf("%s: added bad override", name)
+		}
+	}
+	if err := s.AddOverride(model.Override{Task: model.Task{Name: "CS3560-Th", Date: 20200409, StartTime: 8 * time.Hour, Duration: time.Hour}, Original: 20200409}); err != nil {
+		t.Fatalf("Failed to add override: %v", err)
+	}
+	if err := s.AddTask(model.Task{Name: "Skip Moved", Type: model.CANCEL, Date: 20200409, StartTime: 19 * time.Hour, Duration: 75 * time.Minute}); err == nil {
+		t.Errorf("Cancelled an occurrence that has an override")
+	}
+	// Editing the series keeps the override, deleting it removes it
+	if err := s.EditRecurringTask("CS3560-Th", "CS3560", model.CLASS, 20200402, 19*time.Hour, 75*time.Minute, 20200430, 7); err != nil {
+		t.Fatalf("Failed to edit recurring task: %v", err)
+	}
+	if _, ok := s.Override("CS3560", 20200409); !ok {
+		t.Errorf("Override lost when its recurring task was renamed")
+	}
+	if err := s.DeleteTask("CS3560"); err != nil {
+		t.Fatalf("Failed to delete recurring task: %v", err)
+	}
+	if len(s.Overrides()) != 0 {
+		t.Errorf("Overrides left after deleting their recurring task: %v", s.Overrides())
+	}
+}
+
+func TestOverrideRoundTrip(t *testing.T) {
+	s := scheduleWith(t, thursdayClass)
+	if err := s.AddOverride(model.Override{
+		Task:     model.Task{Name: "CS3560-Th", Date: 20200424, StartTime: 18 * time.Hour, Duration: 2 * time.Hour, TimeZone: "America/Los_Angeles"},
+		Original: 20200423,
+	}); err != nil {
+		t.Fatalf("Failed to add override: %v", err)
+	}
+	dir := t.TempDir()
+	for ext, write := range map[string]func(*model.Schedule, string) error{
+		"json": (*model.Schedule).WriteTasks,
+		"ics":  (*model.Schedule).WriteICal,
+	} {
+		path := filepath.Join(dir, "overrides."+ext)
+		if err := write(s, path); err != nil {
+			t.Fatalf("Failed to write %s: %v", ext, err)
+		}
+		loaded := model.NewSchedule()
+		var err error
+		if ext == "json" {
+			err = loaded.LoadFile(path)
+		} else {
+			err = loaded.LoadICal(path, nil)
+		}
+		if err != nil {
+			t.Fatalf("Failed to load %s: %v", ext, err)
+		}
+		got, want := loaded.Overrides(), s.Overrides()
+		if ext == "ics" {
+			got, want = portableOverrides(got), portableOverrides(want)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: overrides loaded as %v, want %v", ext, got, want)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+// Package tests contains unit tests
+// precision_test.go contains tests for the minute precision of start times and durations
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hlin91/CS3560_Scheduler_Backup/model"
+)
+
+func TestSnap(t *testing.T) {
+	s := model.NewSchedule()
+	if s.Snap() != model.DEFAULT_SNAP {
+		t.Errorf("Got snap %v, want the default %v", s.Snap(), model.DEFAULT_SNAP)
+	}
+	start, duration := 17*time.Hour+7*time.Minute, 53*time.Minute
+	if err := s.AddTransientTask("Quarter", model.VISIT, 20200428, start, duration); err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	if task, _ := s.TransientTask("Quarter"); task.StartTime != 17*time.Hour || task.Duration != time.Hour {
+		t.Errorf("Task rounded to %v for %v, want 17:00 for an hour", task.StartTime, task.Duration)
+	}
+	if err := s.SetSnap(5 * time.Minute); err != nil {
+		t.Fatalf("Failed to set snap: %v", err)
+	}
+	if err := s.AddTransientTask("Five", model.VISIT, 20200429, start, duration); err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	if task, _ := s.TransientTask("Five"); task.StartTime != 17*time.Hour+5*time.Minute || task.Duration != 55*time.Minute {
+		t.Errorf("Task rounded to %v for %v, want 17:05 for 55 minutes", task.StartTime, task.Duration)
+	}
+	if err := s.SetSnap(10 * time.Minute); err == nil {
+		t.Errorf("Set a snap other than 1, 5 or 15 minutes")
+	}
+	// A start time that rounds up to midnight is not on the same day
+	if err := s.AddTransientTask("Midnight", model.VISIT, 20200430, 23*time.Hour+58*time.Minute, time.Hour); err == nil {
+		t.Errorf("Added task rounded to the end of the day")
+	}
+}
+
+func TestMinutePrecision(t *testing.T) {
+	s := model.NewSchedule()
+	if err := s.SetSnap(time.Minute); err != nil {
+		t.Fatalf("Failed to set snap: %v", err)
+	}
+	if err := s.AddTransientTask("Train", model.VISIT, 20200428, 17*time.Hour+7*time.Minute, 53*time.Minute); err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	// Ends at 18:00, so a task starting then does not conflict
+	if err := s.AddTransientTask("Dinner", model.VISIT, 20200428, 18*time.Hour, time.Hour); err != nil {
+		t.Errorf("Failed to add task after the end of another: %v", err)
+	}
+	if err := s.AddTransientTask("Taxi", model.VISIT, 20200428, 17*time.Hour+59*time.Minute, time.Minute); err == nil {
+		t.Errorf("Added task in the last minute of another")
+	}
+	task, _ := s.TransientTask("Train")
+	if start, _ := task.GetStartDate(); start.Hour() != 17 || start.Minute() != 7 {
+		t.Errorf("Task starts at %v, want 17:07", start)
+	}
+	if !strings.Contains(task.String(), "Start Time: 17:07") || !strings.Contains(task.String(), "Duration: 0:53") {
+		t.Errorf("Task printed without its minutes:\n%v", task)
+	}
+	path := filepath.Join(t.TempDir(), "minutes.json")
+	if err := s.WriteTasks(path); err != nil {
+		t.Fatalf("Failed to write tasks: %v", err)
+	}
+	loaded := model.NewSchedule()
+	loaded.SetSnap(time.Minute)
+	if err := loaded.LoadFile(path); err != nil {
+		t.Fatalf("Failed to load tasks: %v", err)
+	}
+	if got, _ := loaded.TransientTask("Train"); got != task {
+		t.Errorf("Task changed after round trip: %+v", got)
+	}
+}
+
+func TestLegacyFloatTimes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "legacy.json")
+	content := `[{"Name": "Interview", "Type": "Appointment", "Date": 20200429, "StartTime": 17.5, "Duration": 2.25},
+		{"Name": "Lecture", "Type": "Class", "StartDate": 20200414, "StartTime": 19, "Duration": 1.25, "EndDate": 20200505, "Frequency": 7}]`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	s := model.NewSchedule()
+	if err := s.LoadFile(path); err != nil {
+		t.Fatalf("Failed to load tasks written in hours: %v", err)
+	}
+	if task, _ := s.TransientTask("Interview"); task.StartTime != 17*time.Hour+30*time.Minute || task.Duration != 2*time.Hour+15*time.Minute {
+		t.Errorf("Task loaded as %v for %v, want 17:30 for 2:15", task.StartTime, task.Duration)
+	}
+	if r, _ := s.RecurringTask("Lecture"); r.StartTime != 19*time.Hour || r.Duration != time.Hour+15*time.Minute {
+		t.Errorf("Recurring task loaded as %v for %v, want 19:00 for 1:15", r.StartTime, r.Duration)
+	}
+}
@@ -0,0 +1,80 @@
+// Package tests contains unit tests
+// range_test.go contains tests for querying the tasks in a span of time
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hlin91/CS3560_Scheduler_Backup/model"
+)
+
+func TestGetTasksInRange(t *testing.T) {
+	s := model.NewSchedule()
+	if err := s.LoadFile("../data/Set1.json"); err != nil {
+		t.Fatalf("Failed to load Set1: %v", err)
+	}
+	if err := s.AddTransientTask("Next Interview", "Appointment", 20210428, 17*time.Hour, 2*time.Hour+30*time.Minute); err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	april := time.Date(2020, time.April, 1, 0, 0, 0, 0, time.UTC)
+	tasks, err := s.GetTasksInRange(april, april.AddDate(0, 1, 0))
+	if err != nil {
+		t.Fatalf("Failed to get tasks: %v", err)
+	}
+	// Tuesday and Thursday classes from April 14, less the cancelled class on April 28, and the interview
+	want := []struct {
+		name string
+		date int
+	}{
+		{"CS3560-Tu", 20200414}, {"CS3560-Th", 20200416}, {"CS3560-Tu", 20200421}, {"CS3560-Th", 20200423},
+		{"Intern Interview", 20200428}, {"CS3560-Th", 20200430},
+	}
+	if len(tasks) != len(want) {
+		t.Fatalf("Got %d tasks in April 2020, want %d: %v", len(tasks), len(want), tasks)
+	}
+	for i, w := range want {
+		if tasks[i].Name != w.name || tasks[i].Date != w.date {
+			t.Errorf("Task %d is %s on %d, want %s on %d", i, tasks[i].Name, tasks[i].Date, w.name, w.date)
+		}
+	}
+	// A span ending part way through a task still includes it
+	tasks, _ = s.GetTasksInRange(time.Date(2021, time.April, 28, 18, 0, 0, 0, time.UTC), time.Date(2021, time.April, 28, 18, 30, 0, 0, time.UTC))
+	if len(tasks) != 1 || tasks[0].Name != "Next Interview" {
+		t.Errorf("Got %v, want only the interview in 2021", tasks)
+	}
+	tasks, _ = s.GetTasksInRange(april.AddDate(0, 1, 1), april.AddDate(0, 1, 1))
+	if len(tasks) != 0 {
+		t.Errorf("Got %d tasks in an empty span", len(tasks))
+	}
+}
+
+func TestWeekAcrossYears(t *testing.T) {
+	s := model.NewSchedule()
+	for _, task := range []struct {
+		name string
+		date int
+	}{{"Monday", 20191230}, {"Saturday", 20200104}, {"Sunday", 20200105}} {
+		if err := s.AddTransientTask(task.name, "Visit", task.date, 12*time.Hour, time.Hour); err != nil {
+			t.Fatalf("Failed to add task: %v", err)
+		}
+	}
+	newYear := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	tasks, _ := s.GetTasksInWeek(newYear)
+	if len(tasks) != 3 {
+		t.Errorf("Got %d tasks in the week from Monday December 30, want 3", len(tasks))
+	}
+	if tasks, _ := s.GetTasksByWeek(1, 1); len(tasks) != 3 {
+		t.Errorf("Got %d tasks in the week of January 1, want 3", len(tasks))
+	}
+	if err := s.SetWeekStart(time.Saturday); err == nil {
+		t.Errorf("Weeks began on Saturday")
+	}
+	if err := s.SetWeekStart(time.Sunday); err != nil {
+		t.Fatalf("Failed to set week start: %v", err)
+	}
+	tasks, _ = s.GetTasksInWeek(newYear)
+	if len(tasks) != 2 || tasks[0].Name != "Monday" || tasks[1].Name != "Saturday" {
+		t.Errorf("Got %v in the week from Sunday December 29, want Monday and Saturday", tasks)
+	}
+}
@@ -0,0 +1,149 @@
+// Package tests contains unit tests
+// recurrence_test.go contains tests for weekly and monthly recurrence rules
+package tests
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hlin91/CS3560_Scheduler_Backup/model"
+)
+
+func TestRecurrenceRules(t *testing.T) {
+	gym := model.Task{Name: "Gym", Type: model.EXERCISE, Date: 20200406, StartTime: 7 * time.Hour, Duration: time.Hour}
+	tests := []struct {
+		name string
+		r    model.RecurringTask
+		want []int
+	}{
+		{"Mon/Wed/Fri", model.RecurringTask{Task: gym, EndDate: 20200417, Frequency: 1, Weekdays: model.NewWeekdays(time.Monday, time.Wednesday, time.Friday)},
+			[]int{20200406, 20200408, 20200410, 20200413, 20200415, 20200417}},
+		{"every 2 weeks", model.RecurringTask{Task: gym, EndDate: 20200503, Frequency: 2, Weekdays: model.NewWeekdays(time.Monday, time.Sunday)},
+			[]int{20200406, 20200412, 20200420, 20200426}},
+		{"day 31", model.RecurringTask{Task: model.Task{Name: "Rent", Type: model.WORK, Date: 20200131, StartTime: 9 * time.Hour, Duration: time.Hour}, EndDate: 20200630, Frequency: 1, MonthDay: 31},
+			[]int{20200131, 20200331, 20200531}},
+		{"2nd Tuesday", model.RecurringTask{Task: model.Task{Name: "Book Club", Type: model.STUDY, Date: 20200414, StartTime: 19 * time.Hour, Duration: 2 * time.Hour}, EndDate: 20200731, Frequency: 1, MonthWeek: 2, Weekdays: model.NewWeekdays(time.Tuesday)},
+			[]int{20200414, 20200512, 20200609, 20200714}},
+		{"last Friday", model.RecurringTask{Task: model.Task{Name: "Payday", Type: model.WORK, Date: 20200131, StartTime: 9 * time.Hour, Duration: time.Hour}, EndDate: 20200531, Frequency: 2, MonthWeek: model.LAST_WEEK, Weekdays: model.NewWeekdays(time.Friday)},
+			[]int{20200131, 20200327, 20200529}},
+	}
+	for _, test := range tests {
+		s := model.NewSchedule()
+		if err := s.AddRecurring(test.r); err != nil {
+			t.Errorf("%s: failed to add recurring task: %v", test.name, err)
+			continue
+		}
+		r, _ := s.RecurringTask(test.r.Name)
+		if got := subtaskDates(t, r); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got subtasks on %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestBadRecurrenceRules(t *testing.T) {
+	gym := model.Task{Name: "Gym", Type: model.EXERCISE, Date: 20200406, StartTime: 7 * time.Hour, Duration: time.Hour}
+	bad := map[string]model.RecurringTask{
+		"start date off the rule": {Task: gym, EndDate: 20200430, Frequency: 1, Weekdays: model.NewWeekdays(time.Tuesday)},
+		"too many weeks":          {Task: gym, EndDate: 20200430, Frequency: 53, Weekdays: model.NewWeekdays(time.Monday)},
+		"bad day of the month":    {Task: gym, EndDate: 20200430, Frequency: 1, MonthDay: 32},
+		"two days in a week":      {Task: gym, EndDate: 20200430, Frequency: 1, MonthWeek: 1, Weekdays: model.NewWeekdays(time.Monday, time.Friday)},
+		"longer than the gap":     {Task: model.Task{Name: "Gym", Type: model.EXERCISE, Date: 20200406, StartTime: 7 * time.Hour, Duration: 25 * time.Hour}, EndDate: 20200430, Frequency: 1, Weekdays: model.NewWeekdays(time.Monday, time.Tuesday)},
+	}
+	for name, r := range bad {
+		if err := model.NewSchedule().AddRecurring(r); err == nil {
+			t.Errorf("Added recurring task with %s", name)
+		}
+	}
+}
+
+func TestWeekdayConflicts(t *testing.T) {
+	s := model.NewSchedule()
+	gym := model.RecurringTask{
+		Task:      model.Task{Name: "Gym", Type: model.EXERCISE, Date: 20200406, StartTime: 7 * time.Hour, Duration: time.Hour},
+		EndDate:   20200430,
+		Frequency: 1,
+		Weekdays:  model.NewWeekdays(time.Monday, time.Wednesday, time.Friday),
+	}
+	if err := s.AddRecurring(gym); err != nil {
+		t.Fatalf("Failed to add recurring task: %v", err)
+	}
+	if err := s.AddTransientTask("Dentist", model.APPOINTMENT, 20200415, 7*time.Hour, time.Hour); err == nil {
+		t.Errorf("Added task during a Wednesday of the rule")
+	}
+	if err := s.AddTransientTask("Dentist", model.APPOINTMENT, 20200414, 7*time.Hour, time.Hour); err != nil {
+		t.Errorf("Failed to add task on a Tuesday: %v", err)
+	}
+	// Tuesdays and Thursdays do not meet Mondays, Wednesdays and Fridays
+	swim := model.RecurringTask{
+		Task:      model.Task{Name: "Swim", Type: model.EXERCISE, Date: 20200407, StartTime: 7 * time.Hour, Duration: time.Hour},
+		EndDate:   20200430,
+		Frequency: 1,
+		Weekdays:  model.NewWeekdays(time.Tuesday, time.Thursday),
+	}
+	if err := s.AddRecurring(swim); err == nil {
+		t.Errorf("Added recurring task during the Dentist")
+	}
+	swim.Date = 20200416
+	if err := s.AddRecurring(swim); err != nil {
+		t.Errorf("Failed to add recurring task on other days of the week: %v", err)
+	}
+	if err := s.AddRecurringTask("Run", model.EXERCISE, 20200418, 7*time.Hour, time.Hour, 20200430, 2); err == nil {
+		t.Errorf("Added daily recurring task meeting a Monday of the rule")
+	}
+	// Cancel the gym on Friday the 17th
+	if err := s.AddAntiTask("Holiday", model.CANCEL, 20200417, 7*time.Hour, time.Hour); err != nil {
+		t.Fatalf("Failed to cancel subtask of the rule: %v", err)
+	}
+	if err := s.AddAntiTask("Not Gym", model.CANCEL, 20200418, 7*time.Hour, time.Hour); err == nil {
+		t.Errorf("Added anti task on a day off the rule")
+	}
+	if err := s.AddTransientTask("Errands", model.SHOPPING, 20200417, 7*time.Hour, time.Hour); err != nil {
+		t.Errorf("Failed to add task in the cancelled subtask: %v", err)
+	}
+}
+
+func TestRecurrenceRoundTrip(t *testing.T) {
+	s := model.NewSchedule()
+	rules := []model.RecurringTask{
+		{Task: model.Task{Name: "Gym", Type: model.EXERCISE, Date: 20200406, StartTime: 7 * time.Hour, Duration: time.Hour}, EndDate: 20200630, Frequency: 2, Weekdays: model.NewWeekdays(time.Monday, time.Friday)},
+		{Task: model.Task{Name: "Rent", Type: model.WORK, Date: 20200131, StartTime: 9 * time.Hour, Duration: time.Hour}, EndDate: 20201231, Frequency: 1, MonthDay: 31},
+		{Task: model.Task{Name: "Book Club", Type: model.STUDY, Date: 20200414, StartTime: 19 * time.Hour, Duration: 2 * time.Hour}, EndDate: 20201231, Frequency: 1, MonthWeek: 2, Weekdays: model.NewWeekdays(time.Tuesday)},
+	}
+	for _, r := range rules {
+		if err := s.AddRecurring(r); err != nil {
+			t.Fatalf("Failed to add recurring task %q: %v", r.Name, err)
+		}
+	}
+	dir := t.TempDir()
+	formats := map[string]func(*model.Schedule, string) error{
+		"json": (*model.Schedule).WriteTasks,
+		"csv":  (*model.Schedule).WriteCSV,
+		"ics":  (*model.Schedule).WriteICal,
+	}
+	for ext, write := range formats {
+		path := filepath.Join(dir, "rules."+ext)
+		if err := write(s, path); err != nil {
+			t.Fatalf("Failed to write %s: %v", ext, err)
+		}
+		loaded := model.NewSchedule()
+		var err error
+		switch ext {
+		case "json":
+			err = loaded.LoadFile(path)
+		case "csv":
+			err = loaded.LoadCSV(path)
+		case "ics":
+			err = loaded.LoadICal(path, nil)
+		}
+		if err != nil {
+			t.Fatalf("Failed to load %s: %v", ext, err)
+		}
+		for _, r := range rules {
+			if got, _ := loaded.RecurringTask(r.Name); withoutID(got) != r {
+				t.Errorf("%s: recurring task %q loaded as %+v, want %+v", ext, r.Name, got, r)
+			}
+		}
+	}
+}
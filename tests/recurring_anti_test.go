@@ -0,0 +1,193 @@
+// Package tests contains unit tests
+// recurring_anti_test.go contains tests for anti tasks that cancel every few occurrences of a recurring task
+package tests
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hlin91/CS3560_Scheduler_Backup/model"
+)
+
+// standup is a meeting every Monday and Wednesday of April 2020 from 9:00 to 9:30
+var standup = model.RecurringTask{
+	Task:      model.Task{Name: "Standup", Type: model.WORK, Date: 20200406, StartTime: 9 * time.Hour, Duration: 30 * time.Minute},
+	EndDate:   20200430,
+	Frequency: 1,
+	Weekdays:  model.Weekdays(1<<time.Monday | 1<<time.Wednesday),
+}
+
+func TestRecurringAnti(t *testing.T) {
+	s := scheduleWith(t, standup)
+	skip := model.AntiTask{
+		Task:      model.Task{Name: "Skip Standup", Type: model.CANCEL, Date: 20200408, StartTime: 9 * time.Hour, Duration: 30 * time.Minute},
+		EndDate:   20200429,
+		Frequency: 2,
+	}
+	if err := s.AddAnti(skip); err != nil {
+		t.Fatalf("Failed to add recurring anti task: %v", err)
+	}
+	for date, want := range map[int]bool{20200406: true, 20200408: false, 20200413: true, 20200415: false, 20200427: true, 20200429: false} {
+		if _, ok := tasksOn(t, s, date)["Standup"]; ok != want {
+			t.Errorf("Standup on %d: got %v, want %v", date, ok, want)
+		}
+	}
+	if tasks, _ := s.GetTasksByMonth(4); len(tasks) != 4 {
+		t.Errorf("Got %d tasks in April, want 4", len(tasks))
+	}
+	if err := s.AddTransientTask("Dentist", model.APPOINTMENT, 20200415, 9*time.Hour, time.Hour); err != nil {
+		t.Errorf("Failed to add task in place of a cancelled occurrence: %v", err)
+	}
+	if err := s.DeleteTask("Skip Standup"); err == nil {
+		t.Errorf("Deleted recurring anti task bringing back an occurrence that conflicts")
+	}
+	// Moving the series goes with removing the anti tasks that no longer line up
+	if err := s.EditRecurringTask("Standup", "Standup", model.WORK, 20200406, 8*time.Hour, 30*time.Minute, 20200430, 7); err != nil {
+		t.Fatalf("Failed to edit recurring task: %v", err)
+	}
+	if _, ok := s.AntiTask("Skip Standup"); ok {
+		t.Errorf("Recurring anti task kept after its recurring task moved")
+	}
+}
+
+func TestRecurringAntiKeptThroughEdits(t *testing.T) {
+	for _, name := range []string{"edit", "split"} {
+		s := scheduleWith(t, standup)
+		skip := model.AntiTask{
+			Task:      model.Task{Name: "Skip", Type: model.CANCEL, Date: 20200413, StartTime: 9 * time.Hour, Duration: 30 * time.Minute},
+			EndDate:   20200430,
+			Frequency: 2,
+		}
+		if err := s.AddAnti(skip); err != nil {
+			t.Fatalf("Failed to add recurring anti task: %v", err)
+		}
+		if err := s.AddTransientTask("Dentist", model.APPOINTMENT, 20200413, 9*time.Hour, time.Hour); err != nil {
+			t.Fatalf("Failed to add task in place of a cancelled occurrence: %v", err)
+		}
+		r, _ := s.RecurringTask("Standup")
+		var err error
+		if name == "edit" {
+			r.EndDate = 20200507
+			err = s.EditRecurring("Standup", r)
+		} else {
+			r.ID, r.Date = "", 20200413
+			err = s.SplitRecurring("Standup", 20200413, r)
+		}
+		if err != nil {
+			t.Errorf("%s: occurrence cancelled by a recurring anti task conflicts: %v", name, err)
+		}
+		if _, ok := s.AntiTask("Skip"); !ok {
+			t.Errorf("%s: recurring anti task removed", name)
+		}
+	}
+}
+
+func TestEditRecurringAntiInTransaction(t *testing.T) {
+	s := scheduleWith(t, standup)
+	skip := model.AntiTask{
+		Task:      model.Task{Name: "Skip", Type: model.CANCEL, Date: 20200408, StartTime: 9 * time.Hour, Duration: 30 * time.Minute},
+		EndDate:   20200429,
+		Frequency: 2,
+	}
+	if err := s.AddAnti(skip); err != nil {
+		t.Fatalf("Failed to add recurring anti task: %v", err)
+	}
+	err := s.Atomically(func(tx *model.Tx) error {
+		return tx.EditAntiTask("Skip", "Skip Standup", 20200408, 9*time.Hour, 30*time.Minute)
+	})
+	if err != nil {
+		t.Fatalf("Failed to edit recurring anti task in a transaction: %v", err)
+	}
+	if a, ok := s.AntiTask("Skip Standup"); !ok || a.Frequency != 2 || a.EndDate != 20200429 {
+		t.Errorf("Recurring anti task edited in a transaction became %+v", a)
+	}
+	if _, ok := tasksOn(t, s, 20200415)["Standup"]; ok {
+		t.Errorf("Standup on 20200415 no longer cancelled after the edit")
+	}
+}
+
+func TestRecurringAntiMonthly(t *testing.T) {
+	s := model.NewSchedule()
+	r := model.RecurringTask{
+		Task:      model.Task{Name: "Payday", Type: model.WORK, Date: 20200131, StartTime: 12 * time.Hour, Duration: 15 * time.Minute},
+		EndDate:   20201231,
+		Frequency: 1,
+		MonthDay:  31,
+	}
+	if err := s.AddRecurring(r); err != nil {
+		t.Fatalf("Failed to add recurring task: %v", err)
+	}
+	skip := model.AntiTask{
+		Task:      model.Task{Name: "Skip Payday", Type: model.CANCEL, Date: 20200131, StartTime: 12 * time.Hour, Duration: 15 * time.Minute},
+		EndDate:   20201231,
+		Frequency: 2,
+	}
+	if err := s.AddAnti(skip); err != nil {
+		t.Fatalf("Failed to add recurring anti task: %v", err)
+	}
+	// Months without a 31st have no occurrence to count
+	for date, want := range map[int]bool{20200131: false, 20200331: true, 20200531: false, 20200731: true, 20200831: false, 20201031: true, 20201231: false} {
+		if _, ok := tasksOn(t, s, date)["Payday"]; ok != want {
+			t.Errorf("Payday on %d: got %v, want %v", date, ok, want)
+		}
+	}
+}
+
+func TestBadRecurringAntis(t *testing.T) {
+	s := scheduleWith(t, standup)
+	task := model.Task{Name: "Skip", Type: model.CANCEL, Date: 20200408, StartTime: 9 * time.Hour, Duration: 30 * time.Minute}
+	moved := task
+	moved.StartTime = 10 * time.Hour
+	bad := map[string]model.AntiTask{
+		"not lined up":       {Task: moved, EndDate: 20200429, Frequency: 2},
+		"negative frequency": {Task: task, EndDate: 20200429, Frequency: -2},
+		"no end date":        {Task: task, Frequency: 2},
+		"end before start":   {Task: task, EndDate: 20200401, Frequency: 2},
+		"names a series":     {Task: task, EndDate: 20200429, Series: "Standup", Frequency: 2},
+	}
+	for name, a := range bad {
+		if err := s.AddAnti(a); err == nil {
+			t.Errorf("%s: added bad recurring anti task", name)
+		}
+	}
+}
+
+func TestRecurringAntiRoundTrip(t *testing.T) {
+	s := scheduleWith(t, standup)
+	if err := s.AddAnti(model.AntiTask{
+		Task:      model.Task{Name: "Skip Standup", Type: model.CANCEL, Date: 20200406, StartTime: 9 * time.Hour, Duration: 30 * time.Minute},
+		EndDate:   20200420,
+		Frequency: 3,
+	}); err != nil {
+		t.Fatalf("Failed to add recurring anti task: %v", err)
+	}
+	dir := t.TempDir()
+	for ext, write := range map[string]func(*model.Schedule, string) error{
+		"json": (*model.Schedule).WriteTasks,
+		"csv":  (*model.Schedule).WriteCSV,
+	} {
+		path := filepath.Join(dir, "skips."+ext)
+		if err := write(s, path); err != nil {
+			t.Fatalf("Failed to write %s: %v", ext, err)
+		}
+		loaded := model.NewSchedule()
+		var err error
+		if ext == "json" {
+			err = loaded.LoadFile(path)
+		} else {
+			err = loaded.LoadCSV(path)
+		}
+		if err != nil {
+			t.Fatalf("Failed to load %s: %v", ext, err)
+		}
+		got, want := loaded.AntiTasks(), s.AntiTasks()
+		if ext == "csv" {
+			got, want = portableAntiTasks(loaded, got), portableAntiTasks(s, want)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: anti tasks loaded as %v, want %v", ext, got, want)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+// Package tests contains unit tests
+// recurring_test.go contains tests for recurring tasks
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hlin91/CS3560_Scheduler_Backup/model"
+)
+
+// firstOverlapByExpansion finds the first overlapping subtask by comparing every pair of subtasks
+func firstOverlapByExpansion(r, task model.RecurringTask) (model.Task, bool) {
+	subtasks, _ := r.GetSubtasks()
+	others, _ := task.GetSubtasks()
+	for _, a := range subtasks {
+		for _, b := range others {
+			if a.Overlaps(b) {
+				return a, true
+			}
+		}
+	}
+	return model.Task{}, false
+}
+
+func TestFirstOverlapRecurring(t *testing.T) {
+	starts := []struct {
+		date      int
+		startTime time.Duration
+		duration  time.Duration
+	}{
+		{20200401, 9 * time.Hour, 2 * time.Hour},
+		{20200402, 10 * time.Hour, time.Hour},
+		{20200403, 22 * time.Hour, 3 * time.Hour},
+		{20200405, 30 * time.Minute, time.Hour},
+		{20200407, 8 * time.Hour, time.Hour},
+		{20200404, 10 * time.Hour, 0},
+		{20200406, 8 * time.Hour, 0},
+	}
+	for _, a := range starts {
+		for _, b := range starts {
+			for p := 1; p <= 7; p++ {
+				for q := 1; q <= 7; q++ {
+					r, err := model.NewRecurringTask("A", "Class", a.date, a.startTime, a.duration, 20200520, p)
+					if err != nil {
+						t.Fatalf("Failed to create task: %v", err)
+					}
+					task, err := model.NewRecurringTask("B", "Work", b.date, b.startTime, b.duration, 20200430, q)
+					if err != nil {
+						t.Fatalf("Failed to create task: %v", err)
+					}
+					want, wantOk := firstOverlapByExpansion(r, task)
+					got, ok := r.FirstOverlapRecurring(task)
+					if ok != wantOk || got != want {
+						t.Errorf("FirstOverlapRecurring(%+v, %+v) = %+v, %v, want %+v, %v", r, task, got, ok, want, wantOk)
+					}
+					if r.OverlapsRecurring(task) != task.OverlapsRecurring(r) {
+						t.Errorf("OverlapsRecurring is not symmetric for %+v and %+v", r, task)
+					}
+				}
+			}
+		}
+	}
+}
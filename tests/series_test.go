@@ -0,0 +1,141 @@
+// Package tests contains unit tests
+// series_test.go contains tests for recurring tasks ending after a count or never
+package tests
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hlin91/CS3560_Scheduler_Backup/model"
+)
+
+func TestCountLimitedSeries(t *testing.T) {
+	gym := model.Task{Name: "Gym", Type: model.EXERCISE, Date: 20200406, StartTime: 7 * time.Hour, Duration: time.Hour}
+	tests := []struct {
+		name string
+		r    model.RecurringTask
+		want []int
+	}{
+		{"daily", model.RecurringTask{Task: gym, Count: 3, Frequency: 2},
+			[]int{20200406, 20200408, 20200410}},
+		{"Mon/Wed/Fri from a Wednesday", model.RecurringTask{Task: model.Task{Name: "Gym", Type: model.EXERCISE, Date: 20200408, StartTime: 7 * time.Hour, Duration: time.Hour}, Count: 4, Frequency: 1, Weekdays: model.NewWeekdays(time.Monday, time.Wednesday, time.Friday)},
+			[]int{20200408, 20200410, 20200413, 20200415}},
+		{"day 31", model.RecurringTask{Task: model.Task{Name: "Rent", Type: model.WORK, Date: 20200131, StartTime: 9 * time.Hour, Duration: time.Hour}, Count: 3, Frequency: 1, MonthDay: 31},
+			[]int{20200131, 20200331, 20200531}},
+	}
+	for _, test := range tests {
+		s := model.NewSchedule()
+		if err := s.AddRecurring(test.r); err != nil {
+			t.Errorf("%s: failed to add recurring task: %v", test.name, err)
+			continue
+		}
+		r, _ := s.RecurringTask(test.r.Name)
+		if got := subtaskDates(t, r); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got subtasks on %v, want %v", test.name, got, test.want)
+		}
+	}
+	s := model.NewSchedule()
+	if err := s.AddRecurring(model.RecurringTask{Task: gym, EndDate: 20200430, Count: 3, Frequency: 1}); err == nil {
+		t.Errorf("Added recurring task with both an end date and a count")
+	}
+	if err := s.AddRecurring(model.RecurringTask{Task: gym, Count: 3, Frequency: 1}); err != nil {
+		t.Fatalf("Failed to add recurring task: %v", err)
+	}
+	if err := s.AddTransientTask("Dentist", model.APPOINTMENT, 20200408, 7*time.Hour, time.Hour); err == nil {
+		t.Errorf("Added task during the last subtask")
+	}
+	if err := s.AddTransientTask("Dentist", model.APPOINTMENT, 20200409, 7*time.Hour, time.Hour); err != nil {
+		t.Errorf("Failed to add task after the last subtask: %v", err)
+	}
+}
+
+func TestOpenEndedSeries(t *testing.T) {
+	s := model.NewSchedule()
+	if err := s.AddRecurring(model.RecurringTask{
+		Task:      model.Task{Name: "Sleep", Type: model.SLEEP, Date: 20200401, StartTime: 23 * time.Hour, Duration: 8 * time.Hour},
+		Frequency: 1,
+	}); err != nil {
+		t.Fatalf("Failed to add open-ended recurring task: %v", err)
+	}
+	if err := s.AddTransientTask("Red-eye", model.VISIT, 20750101, 2*time.Hour, time.Hour); err == nil {
+		t.Errorf("Added task during a subtask decades after the start")
+	}
+	day := time.Date(2120, time.June, 1, 0, 0, 0, 0, time.UTC)
+	if tasks, _ := s.GetTasksInRange(day, day.AddDate(0, 0, 1)); len(tasks) != 2 {
+		t.Errorf("Got %d subtasks on a day a century later, want 2", len(tasks))
+	}
+	// Views of every year stop at the horizon rather than running forever
+	if tasks, err := s.GetTasksByMonth(6); err != nil || len(tasks) != 31*model.OPEN_HORIZON_YEARS {
+		t.Errorf("Got %d tasks in June, want %d", len(tasks), 31*model.OPEN_HORIZON_YEARS)
+	}
+	if err := s.AddRecurringTask("Night Shift", model.WORK, 20300101, 22*time.Hour, 2*time.Hour, 0, 7); err == nil {
+		t.Errorf("Added open-ended recurring task overlapping another")
+	}
+	// A yearly rule and a monthly rule that first meet on the 5th Tuesday of September 2025
+	book := model.RecurringTask{
+		Task:      model.Task{Name: "Book Club", Type: model.STUDY, Date: 20200414, StartTime: 19 * time.Hour, Duration: 2 * time.Hour},
+		Frequency: 1,
+		MonthWeek: 5,
+		Weekdays:  model.NewWeekdays(time.Tuesday),
+	}
+	if err := s.AddRecurring(model.RecurringTask{
+		Task:      model.Task{Name: "Choir", Type: model.STUDY, Date: 20201006, StartTime: 20 * time.Hour, Duration: time.Hour},
+		Frequency: 52,
+		Weekdays:  model.NewWeekdays(time.Tuesday),
+	}); err != nil {
+		t.Fatalf("Failed to add open-ended weekly task: %v", err)
+	}
+	book.Date = 20200331
+	if err := s.AddRecurring(book); err == nil {
+		t.Errorf("Added open-ended monthly task meeting a weekly task")
+	}
+	book.Date, book.MonthWeek, book.StartTime = 20200414, 2, 17*time.Hour
+	if err := s.AddRecurring(book); err != nil {
+		t.Errorf("Failed to add open-ended monthly task: %v", err)
+	}
+}
+
+func TestSeriesEndsRoundTrip(t *testing.T) {
+	s := model.NewSchedule()
+	rules := []model.RecurringTask{
+		{Task: model.Task{Name: "Gym", Type: model.EXERCISE, Date: 20200406, StartTime: 7 * time.Hour, Duration: time.Hour}, Count: 10, Frequency: 1, Weekdays: model.NewWeekdays(time.Monday, time.Friday)},
+		{Task: model.Task{Name: "Sleep", Type: model.SLEEP, Date: 20200401, StartTime: 23 * time.Hour, Duration: 8 * time.Hour}, Frequency: 1},
+	}
+	for _, r := range rules {
+		if err := s.AddRecurring(r); err != nil {
+			t.Fatalf("Failed to add recurring task %q: %v", r.Name, err)
+		}
+	}
+	dir := t.TempDir()
+	formats := map[string]func(*model.Schedule, string) error{
+		"json": (*model.Schedule).WriteTasks,
+		"csv":  (*model.Schedule).WriteCSV,
+		"ics":  (*model.Schedule).WriteICal,
+	}
+	for ext, write := range formats {
+		path := filepath.Join(dir, "series."+ext)
+		if err := write(s, path); err != nil {
+			t.Fatalf("Failed to write %s: %v", ext, err)
+		}
+		loaded := model.NewSchedule()
+		var err error
+		switch ext {
+		case "json":
+			err = loaded.LoadFile(path)
+		case "csv":
+			err = loaded.LoadCSV(path)
+		case "ics":
+			err = loaded.LoadICal(path, nil)
+		}
+		if err != nil {
+			t.Fatalf("Failed to load %s: %v", ext, err)
+		}
+		for _, r := range rules {
+			if got, _ := loaded.RecurringTask(r.Name); withoutID(got) != r {
+				t.Errorf("%s: recurring task %q loaded as %+v, want %+v", ext, r.Name, got, r)
+			}
+		}
+	}
+}
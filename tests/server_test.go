@@ -0,0 +1,64 @@
+// Package tests contains unit tests
+// server_test.go contains tests for the HTTP API
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hlin91/CS3560_Scheduler_Backup/controller"
+	"github.com/hlin91/CS3560_Scheduler_Backup/model"
+)
+
+func TestServer(t *testing.T) {
+	s := model.NewSchedule()
+	srv := httptest.NewServer(controller.NewServer(s, ""))
+	defer srv.Close()
+	do := func(method, path, body string, want int) {
+		t.Helper()
+		req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s failed: %v", method, path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("%s %s: got status %d, want %d", method, path, resp.StatusCode, want)
+		}
+	}
+	set1, err := os.ReadFile("../data/Set1.json")
+	if err != nil {
+		t.Fatalf("Failed to read Set1: %v", err)
+	}
+	do("POST", "/import", string(set1), http.StatusOK)
+	do("POST", "/import", string(set1), http.StatusConflict)
+	do("POST", "/tasks/transient", `{"Name": "Watch a movie", "Type": "Movie", "Date": 20200429, "StartTime": 21.5, "Duration": 2}`,
+		http.StatusBadRequest)
+	do("POST", "/tasks/transient", `{"Name": "Watch a movie", "Type": "Visit", "Date": 20200430, "StartTime": 18.5, "Duration": 2}`,
+		http.StatusConflict)
+	do("POST", "/tasks/transient", `{"Name": "Watch a movie", "Type": "Visit", "Date": 20200430, "StartTime": 21, "Duration": 2}`,
+		http.StatusCreated)
+	do("POST", "/tasks/anti", `{"Name": "Skip-out", "Date": 20200430, "StartTime": 19.25, "Duration": 0.75}`, http.StatusBadRequest)
+	do("GET", "/tasks/Watch%20a%20movie", "", http.StatusOK)
+	do("PUT", "/tasks/Watch%20a%20movie", `{"Name": "Watch a movie", "Type": "Visit", "Date": 20200430, "StartTime": "19:00", "Duration": "2:00"}`,
+		http.StatusConflict)
+	do("DELETE", "/tasks/Skip%20For%20Visit", "", http.StatusConflict)
+	do("DELETE", "/tasks/Nothing", "", http.StatusNotFound)
+	do("PUT", "/tasks/CS3560-Th/overrides/20200430", `{"Date": 20200430, "StartTime": "21:30", "Duration": "1:15"}`, http.StatusConflict)
+	do("PUT", "/tasks/CS3560-Th/overrides/20200430", `{"Date": 20200501, "StartTime": "13:00", "Duration": "1:15"}`, http.StatusOK)
+	do("GET", "/tasks/CS3560-Th/overrides/20200430", "", http.StatusOK)
+	do("DELETE", "/tasks/CS3560-Th/overrides/20200430", "", http.StatusOK)
+	do("DELETE", "/tasks/CS3560-Th/overrides/20200430", "", http.StatusNotFound)
+	do("GET", "/schedule/day?month=4&day=28", "", http.StatusOK)
+	do("GET", "/schedule/week?month=4", "", http.StatusBadRequest)
+	do("GET", "/export", "", http.StatusOK)
+	if _, ok := s.TransientTask("Watch a movie"); !ok {
+		t.Errorf("Task created through the API is missing from the schedule")
+	}
+}
@@ -0,0 +1,102 @@
+// Package tests contains unit tests
+// split_test.go contains tests for changing recurring tasks from a date onward
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hlin91/CS3560_Scheduler_Backup/model"
+)
+
+func TestSplitRecurring(t *testing.T) {
+	s := model.NewSchedule()
+	if err := s.AddRecurringTask("CS3560-Tu", model.CLASS, 20200407, 19*time.Hour, 75*time.Minute, 20200505, 7); err != nil {
+		t.Fatalf("Failed to add recurring task: %v", err)
+	}
+	for _, date := range []int{20200414, 20200428} {
+		if err := s.AddAntiTask("Skip "+dateString(date), model.CANCEL, date, 19*time.Hour, 75*time.Minute); err != nil {
+			t.Fatalf("Failed to add anti task: %v", err)
+		}
+	}
+	if err := s.AddOverride(model.Override{Task: model.Task{Name: "CS3560-Tu", Date: 20200422, StartTime: 10 * time.Hour, Duration: 75 * time.Minute}, Original: 20200421}); err != nil {
+		t.Fatalf("Failed to add override: %v", err)
+	}
+	if err := s.AddTransientTask("Dinner", model.VISIT, 20200505, 21*time.Hour, time.Hour); err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	later := model.RecurringTask{
+		Task:      model.Task{Name: "CS3560-Tu (late)", Type: model.CLASS, Date: 20200421, StartTime: 20*time.Hour + 30*time.Minute, Duration: 75 * time.Minute},
+		EndDate:   20200505,
+		Frequency: 7,
+	}
+	if err := s.SplitRecurring("CS3560-Tu", 20200421, later); err == nil {
+		t.Errorf("Split into a task overlapping another")
+	}
+	if r, _ := s.RecurringTask("CS3560-Tu"); r.EndDate != 20200505 {
+		t.Errorf("Failed split changed the recurring task: %v", r)
+	}
+	earlier := later
+	earlier.Name, earlier.StartTime = "CS3560-Tu (early)", 18*time.Hour
+	bad := map[string]struct {
+		from int
+		r    model.RecurringTask
+	}{
+		"at the start":       {20200407, earlier},
+		"after the end":      {20200512, earlier},
+		"new task too early": {20200428, earlier},
+	}
+	for name, test := range bad {
+		if err := s.SplitRecurring("CS3560-Tu", test.from, test.r); err == nil {
+			t.Errorf("%s: split recurring task", name)
+		}
+	}
+	if err := s.SplitRecurring("CS3560-Tu", 20200421, earlier); err != nil {
+		t.Fatalf("Failed to split recurring task: %v", err)
+	}
+	if r, _ := s.RecurringTask("CS3560-Tu"); r.EndDate != 20200420 {
+		t.Errorf("Old task ends on %d, want 20200420", r.EndDate)
+	}
+	if _, ok := s.AntiTask("Skip 2020-04-14"); !ok {
+		t.Errorf("Anti task before the split was deleted")
+	}
+	if _, ok := s.AntiTask("Skip 2020-04-28"); ok {
+		t.Errorf("Anti task after the split that matches nothing was kept")
+	}
+	if _, ok := s.Override("CS3560-Tu (early)", 20200421); !ok {
+		t.Errorf("Override after the split did not move to the new task")
+	}
+	if tasks := tasksOn(t, s, 20200428); tasks["CS3560-Tu (early)"] != 18*time.Hour {
+		t.Errorf("Got %v after the split, want the new task at 18:00", tasks)
+	}
+	if _, err := s.Undo(); err != nil {
+		t.Fatalf("Failed to undo: %v", err)
+	}
+	if _, ok := s.AntiTask("Skip 2020-04-28"); !ok {
+		t.Errorf("Undo did not restore the anti task")
+	}
+	if _, ok := s.RecurringTask("CS3560-Tu (early)"); ok {
+		t.Errorf("Undo did not remove the new task")
+	}
+}
+
+func TestSplitCountLimited(t *testing.T) {
+	s := model.NewSchedule()
+	gym := model.RecurringTask{Task: model.Task{Name: "Gym", Type: model.EXERCISE, Date: 20200406, StartTime: 7 * time.Hour, Duration: time.Hour}, Count: 10, Frequency: 1}
+	if err := s.AddRecurring(gym); err != nil {
+		t.Fatalf("Failed to add recurring task: %v", err)
+	}
+	evening := gym
+	evening.Name, evening.Date, evening.StartTime, evening.Count = "Gym (evening)", 20200409, 18*time.Hour, 7
+	if err := s.SplitRecurring("Gym", 20200409, evening); err != nil {
+		t.Fatalf("Failed to split recurring task: %v", err)
+	}
+	if r, _ := s.RecurringTask("Gym"); r.Count != 3 || r.EndDate != 0 {
+		t.Errorf("Old task ends after %d subtasks on %d, want 3 and no end date", r.Count, r.EndDate)
+	}
+}
+
+// dateString formats a date as YYYY-MM-DD
+func dateString(date int) string {
+	return time.Date(date/10000, time.Month(date/100%100), date%100, 0, 0, 0, 0, time.UTC).Format("2006-01-02")
+}
@@ -0,0 +1,212 @@
+// Package tests contains unit tests
+// timezone_test.go contains tests for tasks in time zones
+package tests
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hlin91/CS3560_Scheduler_Backup/model"
+)
+
+const (
+	BERLIN      = "Europe/Berlin"
+	LOS_ANGELES = "America/Los_Angeles"
+	NEW_YORK    = "America/New_York"
+	TOKYO       = "Asia/Tokyo"
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("Time zone database is not available: %v", err)
+	}
+	return loc
+}
+
+func TestSubtasksAcrossDST(t *testing.T) {
+	la := mustLoadLocation(t, LOS_ANGELES)
+	// Daylight saving time begins in Los Angeles on 2020-03-08
+	r, err := model.NewRecurringTask("Evening Class", model.CLASS, 20200302, 19*time.Hour, time.Hour+15*time.Minute, 20200323, 7)
+	if err != nil {
+		t.Fatalf("Failed to create task: %v", err)
+	}
+	r.TimeZone = LOS_ANGELES
+	subtasks, err := r.GetSubtasks()
+	if err != nil {
+		t.Fatalf("Failed to get subtasks: %v", err)
+	}
+	if len(subtasks) != 4 {
+		t.Fatalf("Got %d subtasks, want 4", len(subtasks))
+	}
+	for _, sub := range subtasks {
+		start, _ := sub.GetStartDate()
+		if local := start.In(la); local.Hour() != 19 || local.Minute() != 0 {
+			t.Errorf("Subtask on %d starts at %v, want 19:00 local time", sub.Date, local)
+		}
+	}
+	before, _ := subtasks[0].GetStartDate()
+	after, _ := subtasks[1].GetStartDate()
+	if got := after.Sub(before); got != 7*24*time.Hour-time.Hour {
+		t.Errorf("Got %v between the subtasks either side of the change, want one hour less than a week", got)
+	}
+}
+
+func TestTimeZoneConflicts(t *testing.T) {
+	mustLoadLocation(t, LOS_ANGELES)
+	s := model.NewSchedule()
+	if err := s.AddTask(model.Task{Name: "Call", Type: model.APPOINTMENT, Date: 20200428, StartTime: 9 * time.Hour, Duration: time.Hour, TimeZone: LOS_ANGELES}); err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	// 12:00 in New York is 9:00 in Los Angeles
+	err := s.AddTask(model.Task{Name: "Lunch", Type: model.VISIT, Date: 20200428, StartTime: 12 * time.Hour, Duration: time.Hour, TimeZone: NEW_YORK})
+	if !errors.Is(err, model.ErrConflict) {
+		t.Errorf("Got error %v, want a conflict between the same times in different zones", err)
+	}
+	// 9:00 in UTC is 2:00 in Los Angeles
+	if err := s.AddTransientTask("Early", model.VISIT, 20200428, 9*time.Hour, time.Hour); err != nil {
+		t.Errorf("Failed to add task at the same clock time in another zone: %v", err)
+	}
+	if err := s.AddTask(model.Task{Name: "Bad", Type: model.VISIT, Date: 20200429, StartTime: 9 * time.Hour, Duration: time.Hour, TimeZone: "Mars/Olympus"}); !errors.Is(err, model.ErrInvalid) {
+		t.Errorf("Got error %v, want an invalid time zone", err)
+	}
+}
+
+func TestRecurringConflictsAcrossDST(t *testing.T) {
+	mustLoadLocation(t, BERLIN)
+	series := func(name, taskType string, date int, start, duration time.Duration, end int) model.RecurringTask {
+		return model.RecurringTask{Task: model.Task{Name: name, Type: taskType, Date: date, StartTime: start, Duration: duration, TimeZone: BERLIN}, EndDate: end, Frequency: 1}
+	}
+	// Clocks go forward at 02:00 on 2020-03-29, so Night ends at 05:00 that day
+	s := model.NewSchedule()
+	if err := s.AddRecurring(series("Night", model.WORK, 20200328, time.Hour, 3*time.Hour, 20200330)); err != nil {
+		t.Fatalf("Failed to add recurring task: %v", err)
+	}
+	if err := s.AddRecurring(series("Early", model.STUDY, 20200328, 4*time.Hour+30*time.Minute, time.Hour, 20200330)); !errors.Is(err, model.ErrConflict) {
+		t.Errorf("Got error %v, want a conflict on the day clocks go forward", err)
+	}
+	if err := s.AddTask(model.Task{Name: "Early", Type: model.VISIT, Date: 20200329, StartTime: 4*time.Hour + 30*time.Minute, Duration: time.Hour, TimeZone: BERLIN}); !errors.Is(err, model.ErrConflict) {
+		t.Errorf("Got error %v, want the same conflict for a transient task", err)
+	}
+	// Open-ended weekly series are on the same days again after a week, but only meet the change once a year
+	s = model.NewSchedule()
+	sundays := model.Weekdays(1 << time.Sunday)
+	night := series("Night", model.WORK, 20200301, time.Hour, 3*time.Hour, 0)
+	night.Weekdays = sundays
+	if err := s.AddRecurring(night); err != nil {
+		t.Fatalf("Failed to add recurring task: %v", err)
+	}
+	early := series("Early", model.STUDY, 20200301, 4*time.Hour+30*time.Minute, time.Hour, 0)
+	early.Weekdays = sundays
+	if err := s.AddRecurring(early); !errors.Is(err, model.ErrConflict) {
+		t.Errorf("Got error %v, want a conflict between open-ended series on the day clocks go forward", err)
+	}
+	// Clocks go back at 03:00 on 2020-10-25, so Night ends at 03:00 that day
+	s = model.NewSchedule()
+	if err := s.AddRecurring(series("Night", model.WORK, 20201024, time.Hour, 3*time.Hour, 20201026)); err != nil {
+		t.Fatalf("Failed to add recurring task: %v", err)
+	}
+	if err := s.AddRecurring(series("Early", model.STUDY, 20201025, 3*time.Hour+30*time.Minute, time.Hour, 20201025)); err != nil {
+		t.Errorf("Failed to add recurring task after the end of another on the day clocks go back: %v", err)
+	}
+}
+
+func TestAntiTaskInOtherZone(t *testing.T) {
+	la := mustLoadLocation(t, LOS_ANGELES)
+	s := model.NewSchedule()
+	class := model.RecurringTask{
+		Task:      model.Task{Name: "Evening Class", Type: model.CLASS, Date: 20200302, StartTime: 19 * time.Hour, Duration: time.Hour + 15*time.Minute, TimeZone: LOS_ANGELES},
+		EndDate:   20200323,
+		Frequency: 7,
+	}
+	if err := s.AddRecurring(class); err != nil {
+		t.Fatalf("Failed to add recurring task: %v", err)
+	}
+	// 19:00 on 2020-03-09 in Los Angeles is 02:00 on 2020-03-10 in UTC after daylight saving time begins
+	if err := s.AddAntiTask("Skip", model.CANCEL, 20200310, 2*time.Hour, time.Hour+15*time.Minute); err != nil {
+		t.Fatalf("Failed to cancel the subtask with a UTC anti task: %v", err)
+	}
+	if err := s.AddAntiTask("Wrong Hour", model.CANCEL, 20200317, 3*time.Hour, time.Hour+15*time.Minute); err == nil {
+		t.Errorf("Anti task an hour off the subtask after the change was added")
+	}
+	start := time.Date(2020, time.March, 1, 0, 0, 0, 0, la)
+	tasks, err := s.GetTasksInRange(start, start.AddDate(0, 1, 0))
+	if err != nil {
+		t.Fatalf("Failed to get tasks: %v", err)
+	}
+	if len(tasks) != 3 {
+		t.Errorf("Got %d subtasks, want 3 with one cancelled", len(tasks))
+	}
+	for _, task := range tasks {
+		if task.Date == 20200309 {
+			t.Errorf("Cancelled subtask %+v was returned", task)
+		}
+	}
+}
+
+func TestDefaultLocation(t *testing.T) {
+	la := mustLoadLocation(t, LOS_ANGELES)
+	s := model.NewSchedule()
+	if err := s.SetLocation(la); err != nil {
+		t.Fatalf("Failed to set location: %v", err)
+	}
+	if err := s.AddTransientTask("Dentist", model.APPOINTMENT, 20200428, 9*time.Hour, time.Hour); err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	if err := s.AddTask(model.Task{Name: "Flight", Type: model.VISIT, Date: 20200428, StartTime: 9 * time.Hour, Duration: time.Hour, TimeZone: "UTC"}); err != nil {
+		t.Fatalf("Failed to add task in UTC: %v", err)
+	}
+	if task, _ := s.TransientTask("Dentist"); task.TimeZone != LOS_ANGELES {
+		t.Errorf("Task was given time zone %q, want the default %q", task.TimeZone, LOS_ANGELES)
+	}
+	// Edits keep the time zone unless given another
+	if err := s.EditTransientTask("Dentist", "Dentist", model.APPOINTMENT, 20200428, 10*time.Hour, time.Hour); err != nil {
+		t.Fatalf("Failed to edit task: %v", err)
+	}
+	if task, _ := s.TransientTask("Dentist"); task.TimeZone != LOS_ANGELES {
+		t.Errorf("Edited task has time zone %q, want %q", task.TimeZone, LOS_ANGELES)
+	}
+	path := filepath.Join(t.TempDir(), "zones.json")
+	if err := s.WriteTasks(path); err != nil {
+		t.Fatalf("Failed to write tasks: %v", err)
+	}
+	loaded := model.NewSchedule()
+	if err := loaded.LoadFile(path); err != nil {
+		t.Fatalf("Failed to load tasks: %v", err)
+	}
+	for _, task := range s.TransientTasks() {
+		if got, _ := loaded.TransientTask(task.Name); got != task {
+			t.Errorf("Task %q changed after round trip: %+v", task.Name, got)
+		}
+	}
+	if err := s.SetLocation(time.FixedZone("Nowhere", 3600)); err == nil {
+		t.Errorf("Set a location without a time zone name")
+	}
+}
+
+func TestRenderInZone(t *testing.T) {
+	tokyo := mustLoadLocation(t, TOKYO)
+	s := model.NewSchedule()
+	if err := s.AddTask(model.Task{Name: "Call", Type: model.APPOINTMENT, Date: 20200428, StartTime: 19 * time.Hour, Duration: time.Hour, TimeZone: LOS_ANGELES}); err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	// 19:00 on 2020-04-28 in Los Angeles is 11:00 on 2020-04-29 in Tokyo
+	day := time.Date(2020, time.April, 29, 0, 0, 0, 0, tokyo)
+	tasks, err := s.GetTasksInRange(day, day.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatalf("Failed to get tasks: %v", err)
+	}
+	if len(tasks) != 1 {
+		t.Fatalf("Got %d tasks on the day in Tokyo, want 1", len(tasks))
+	}
+	shown := model.TasksIn(tasks, tokyo)[0]
+	if shown.Date != 20200429 || shown.StartTime != 11*time.Hour || shown.TimeZone != TOKYO {
+		t.Errorf("Task shown in Tokyo as %+v, want 11:00 on 2020-04-29", shown)
+	}
+	if !shown.Overlaps(tasks[0]) {
+		t.Errorf("Task shown in another zone is not at the same time")
+	}
+}
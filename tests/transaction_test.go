@@ -0,0 +1,90 @@
+// Package tests contains unit tests
+// transaction_test.go contains tests for all-or-nothing transactions on the schedule
+package tests
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hlin91/CS3560_Scheduler_Backup/model"
+)
+
+func TestAtomically(t *testing.T) {
+	s := model.NewSchedule()
+	if err := s.LoadFile("../data/Set1.json"); err != nil {
+		t.Fatalf("Failed to load Set1: %v", err)
+	}
+	err := s.Atomically(func(tx *model.Tx) error {
+		if err := tx.AddTransientTask("Dentist", "Appointment", 20200501, 9*time.Hour, time.Hour); err != nil {
+			return err
+		}
+		if err := tx.DeleteTask("CS3560-Tu"); err != nil {
+			return err
+		}
+		return tx.EditTransientTask("Intern Interview", "Interview", "Appointment", 20200429, 17*time.Hour, 2*time.Hour+30*time.Minute)
+	})
+	if err != nil {
+		t.Fatalf("Failed to commit transaction: %v", err)
+	}
+	if _, ok := s.TransientTask("Interview"); !ok {
+		t.Errorf("Committed transaction was not applied")
+	}
+	err = s.Atomically(func(tx *model.Tx) error {
+		if err := tx.DeleteTask("CS3560-Th"); err != nil {
+			return err
+		}
+		if err := tx.AddTransientTask("Lunch", "Visit", 20200501, 12*time.Hour, time.Hour); err != nil {
+			return err
+		}
+		// Conflicts with Dentist
+		return tx.AddTransientTask("Checkup", "Appointment", 20200501, 9*time.Hour+30*time.Minute, time.Hour)
+	})
+	if !errors.Is(err, model.ErrConflict) {
+		t.Errorf("Got error %v, want a scheduling conflict", err)
+	}
+	if _, ok := s.RecurringTask("CS3560-Th"); !ok {
+		t.Errorf("Rolled back transaction deleted a task")
+	}
+	if _, ok := s.TransientTask("Lunch"); ok {
+		t.Errorf("Rolled back transaction added a task")
+	}
+	func() {
+		defer func() { recover() }()
+		s.Atomically(func(tx *model.Tx) error {
+			tx.DeleteTask("Dentist")
+			panic("script failed")
+		})
+	}()
+	if _, ok := s.TransientTask("Dentist"); !ok {
+		t.Errorf("Transaction was not rolled back after a panic")
+	}
+}
+
+func TestBeginRollback(t *testing.T) {
+	s := model.NewSchedule()
+	s.AddRecurringTask("CS3560-Tu", "Class", 20200414, 19*time.Hour, time.Hour+15*time.Minute, 20200505, 7)
+	tx, err := s.Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	tx.AddAntiTask("Holiday", "Cancellation", 20200421, 19*time.Hour, time.Hour+15*time.Minute)
+	tx.AddTransientTask("Pooping", "Appointment", 20200421, 19*time.Hour, time.Hour)
+	tx.AddTransientTask("Errand", "Shopping", 20200415, 19*time.Hour, time.Hour)
+	// A failed edit inside the transaction is reverted without ending the transaction
+	if err := tx.EditRecurringTask("CS3560-Tu", "CS3560-Tu", "Class", 20200414, 19*time.Hour, time.Hour+15*time.Minute, 20200505, 1); err == nil {
+		t.Errorf("Edit should conflict with Errand")
+	}
+	if _, ok := tx.AntiTask("Holiday"); !ok {
+		t.Errorf("Failed edit reverted earlier changes in the transaction")
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("Failed to roll back: %v", err)
+	}
+	if r, _ := s.RecurringTask("CS3560-Tu"); len(s.AntiTasks()) != 0 || len(s.TransientTasks()) != 0 || r.Frequency != 7 {
+		t.Errorf("Rollback left the schedule modified")
+	}
+	if err := tx.Commit(); !errors.Is(err, model.ErrTxDone) {
+		t.Errorf("Committed a finished transaction")
+	}
+}
@@ -0,0 +1,116 @@
+// Package tests contains unit tests
+// types_test.go contains tests for registries of task types
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hlin91/CS3560_Scheduler_Backup/model"
+)
+
+func TestTypeRegistry(t *testing.T) {
+	reg, err := model.NewTypeRegistry([]model.TaskType{
+		{Name: "Experiment", Category: model.TRANSIENT, Aliases: []string{"Run", "Trial"}},
+		{Name: "Lab Meeting", Category: model.RECURRING, Aliases: []string{"Group Meeting"}},
+		{Name: "Calibration", Category: model.RECURRING},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create type registry: %v", err)
+	}
+	s := model.NewSchedule()
+	if err := s.SetTypes(reg); err != nil {
+		t.Fatalf("Failed to set types: %v", err)
+	}
+	if err := s.AddTransientTask("Titration", "trial", 20200421, 9*time.Hour, time.Hour); err != nil {
+		t.Fatalf("Failed to add task by a type alias: %v", err)
+	}
+	if task, _ := s.TransientTask("Titration"); task.Type != "Experiment" {
+		t.Errorf("Task added by an alias has type %q, want %q", task.Type, "Experiment")
+	}
+	if err := s.AddRecurringTask("Weekly", "LAB MEETING", 20200420, 14*time.Hour, time.Hour, 20200601, 7); err != nil {
+		t.Fatalf("Failed to add recurring task regardless of case: %v", err)
+	}
+	if err := s.AddRecurringTask("Checks", "Experiment", 20200420, 8*time.Hour, time.Hour, 20200601, 1); err == nil {
+		t.Errorf("Added recurring task with a transient type")
+	}
+	if err := s.AddTransientTask("Groceries", model.SHOPPING, 20200421, 17*time.Hour, time.Hour); err == nil {
+		t.Errorf("Added task with a type missing from the registry")
+	}
+	// Cancellation is always an anti type
+	if err := s.AddAntiTask("No Meeting", "cancellation", 20200427, 14*time.Hour, time.Hour); err != nil {
+		t.Errorf("Failed to add anti task: %v", err)
+	}
+	if err := s.EditTransientTask("Titration", "Titration", "Group Meeting", 20200421, 9*time.Hour, time.Hour); err == nil {
+		t.Errorf("Edited transient task to a recurring type")
+	}
+	if got, want := s.Types().Types(model.RECURRING), []model.TaskType{
+		{Name: "Lab Meeting", Category: model.RECURRING, Aliases: []string{"Group Meeting"}},
+		{Name: "Calibration", Category: model.RECURRING},
+	}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Got recurring types %v, want %v", got, want)
+	}
+}
+
+func TestBadTypeRegistries(t *testing.T) {
+	bad := map[string][]model.TaskType{
+		"no name":          {{Category: model.TRANSIENT}},
+		"bad category":     {{Name: "Lab", Category: "Sometimes"}},
+		"duplicate name":   {{Name: "Lab", Category: model.TRANSIENT}, {Name: "lab", Category: model.RECURRING}},
+		"alias of another": {{Name: "Lab", Category: model.TRANSIENT}, {Name: "Seminar", Category: model.RECURRING, Aliases: []string{"LAB"}}},
+		"recurring cancel": {{Name: model.CANCEL, Category: model.RECURRING}},
+		"blackout type":    {{Name: "blackout", Category: model.TRANSIENT}},
+		"empty alias":      {{Name: "Lab", Category: model.TRANSIENT, Aliases: []string{" "}}},
+	}
+	for name, types := range bad {
+		if _, err := model.NewTypeRegistry(types); err == nil {
+			t.Errorf("%s: created bad type registry", name)
+		}
+	}
+}
+
+func TestTypesLoadFile(t *testing.T) {
+	dir := t.TempDir()
+	config := filepath.Join(dir, "types.json")
+	content := `[{"Name": "Experiment", "Category": "Transient", "Aliases": ["Run", "Trial"]},
+		{"Name": "Lab Meeting", "Category": "Recurring", "Aliases": ["Group Meeting"]}]`
+	if err := os.WriteFile(config, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write types.json: %v", err)
+	}
+	types, err := model.ReadTypes(config)
+	if err != nil {
+		t.Fatalf("Failed to read types: %v", err)
+	}
+	path := filepath.Join(dir, "lab.json")
+	tasks := `[{"Name": "Titration", "Type": "run", "Date": 20200421, "StartTime": 9, "Duration": 1},
+		{"Name": "Weekly", "Type": "group meeting", "StartDate": 20200420, "StartTime": 14, "Duration": 1, "EndDate": 20200601, "Frequency": 7},
+		{"Name": "No Meeting", "Type": "Cancellation", "Date": 20200427, "StartTime": 14, "Duration": 1}]`
+	if err := os.WriteFile(path, []byte(tasks), 0644); err != nil {
+		t.Fatalf("Failed to write lab.json: %v", err)
+	}
+	if err := model.NewSchedule().LoadFile(path); err == nil {
+		t.Errorf("Loaded tasks with types missing from the built in types")
+	}
+	s := model.NewSchedule()
+	if err := s.SetTypes(types); err != nil {
+		t.Fatalf("Failed to set types: %v", err)
+	}
+	if err := s.LoadFile(path); err != nil {
+		t.Fatalf("Failed to load tasks with the types of the registry: %v", err)
+	}
+	if r, _ := s.RecurringTask("Weekly"); r.Type != "Lab Meeting" {
+		t.Errorf("Recurring task loaded with type %q, want %q", r.Type, "Lab Meeting")
+	}
+	if got := tasksOn(t, s, 20200427); len(got) != 0 {
+		t.Errorf("Got %v on a cancelled day, want nothing", got)
+	}
+	if err := os.WriteFile(config, []byte(`[{"Name": "Lab", "Category": "Anytime"}]`), 0644); err != nil {
+		t.Fatalf("Failed to write types.json: %v", err)
+	}
+	if _, err := model.ReadTypes(config); err == nil {
+		t.Errorf("Read types with a bad category")
+	}
+}